@@ -0,0 +1,92 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"html/template"
+	"net/http"
+	"os"
+	"runtime"
+	"runtime/debug"
+	"strings"
+	"time"
+)
+
+//go:embed web/about.html
+var aboutHTML string
+
+var aboutPage = template.Must(template.New("about").Parse(aboutHTML))
+
+func openAbout() {
+	openUI("about")
+}
+
+type aboutField struct {
+	Name, Value string
+}
+
+// aboutFields describes the build and setup, for bug reports. The commit
+// comes from the VCS stamp go build adds in a checkout, and so does the date
+// when build.ps1 did not set one.
+func aboutFields() []aboutField {
+	commit, built, modified := "unknown", buildDate, false
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, s := range info.Settings {
+			switch s.Key {
+			case "vcs.revision":
+				commit = s.Value
+			case "vcs.time":
+				if t, err := time.Parse(time.RFC3339, s.Value); err == nil && built == "" {
+					built = t.Local().Format("2006-01-02 15:04")
+				}
+			case "vcs.modified":
+				modified = s.Value == "true"
+			}
+		}
+	}
+	if modified {
+		commit += " (modified)"
+	}
+	if built == "" {
+		built = "unknown"
+	}
+
+	config := configFilePath()
+	if _, err := os.Stat(config); err != nil {
+		config += " (not found)"
+	}
+	fields := []aboutField{
+		{"Version", version},
+		{"Commit", commit},
+		{"Build date", built},
+		{"Go", runtime.Version() + " " + runtime.GOOS + "/" + runtime.GOARCH},
+		{"Config file", config},
+	}
+	if p := fileSetting("M_PROFILE"); p != "" {
+		fields = append(fields, aboutField{"Profile", p})
+	}
+	return fields
+}
+
+func handleAbout(w http.ResponseWriter, r *http.Request) {
+	v := struct {
+		Token   string
+		Fields  []aboutField
+		Message string
+		Error   bool
+	}{Token: uiServer.token, Fields: aboutFields()}
+
+	if r.Method == http.MethodPost {
+		var b strings.Builder
+		for _, f := range v.Fields {
+			fmt.Fprintf(&b, "%s: %s\r\n", f.Name, f.Value)
+		}
+		if err := setClipboardText(b.String()); err != nil {
+			v.Message, v.Error = "Copy failed: "+err.Error(), true
+		} else {
+			v.Message = "Copied to the clipboard."
+		}
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	aboutPage.Execute(w, v)
+}
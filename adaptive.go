@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+// adaptiveMode picks the collect and send intervals from the load of the
+// latest sample: adaptiveFast while CPU or GPU is at or above adaptiveHigh,
+// adaptiveSlow while both are below adaptiveLow, the configured intervals
+// otherwise. Leaving a mode takes a 10 point margin so the agent doesn't
+// flap around a threshold.
+type adaptiveMode struct {
+	mode string
+}
+
+const adaptiveMargin = 10
+
+func (a *adaptiveMode) intervals(m Metrics) (collect, send time.Duration) {
+	if !adaptive {
+		return collectInterval, sendInterval
+	}
+
+	load := max(m.CPU, m.GPU)
+	mode := "normal"
+	switch {
+	case load >= adaptiveHigh, a.mode == "fast" && load >= adaptiveHigh-adaptiveMargin:
+		mode = "fast"
+	case load < adaptiveLow, a.mode == "slow" && load < adaptiveLow+adaptiveMargin:
+		mode = "slow"
+	}
+	if mode != a.mode {
+		if a.mode != "" {
+			slog.Info("Switching sampling mode", "mode", mode, "load", load)
+		}
+		a.mode = mode
+	}
+
+	switch mode {
+	case "fast":
+		return adaptiveFast, adaptiveFast
+	case "slow":
+		return adaptiveSlow, max(adaptiveSlow, sendInterval)
+	}
+	return collectInterval, sendInterval
+}
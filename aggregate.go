@@ -0,0 +1,65 @@
+package main
+
+import "math"
+
+// Aggregate summarizes one series over the samples collected since the
+// previous report.
+type Aggregate struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Count int     `json:"count"`
+}
+
+// aggregateSamples is M_AGGREGATE; 0 sends only the latest sample.
+var aggregateSamples = getEnv("M_AGGREGATE", "1") != "0"
+
+type aggregateSum struct {
+	min, max, sum float64
+	count         int
+}
+
+// sampleAggregator accumulates every series between reports, so spikes
+// between two sends still show up in the next one. It is only used from
+// the collection loop.
+type sampleAggregator struct {
+	series map[string]*aggregateSum
+}
+
+func (a *sampleAggregator) Add(m Metrics) {
+	if a.series == nil {
+		a.series = map[string]*aggregateSum{}
+	}
+	visitSamples(m, func(name, _ string, v float64, labels ...string) {
+		if math.IsNaN(v) || math.IsInf(v, 0) {
+			return
+		}
+		key := seriesKey(name, labels)
+		s := a.series[key]
+		if s == nil {
+			s = &aggregateSum{min: v, max: v}
+			a.series[key] = s
+		}
+		s.min, s.max = min(s.min, v), max(s.max, v)
+		s.sum += v
+		s.count++
+	})
+}
+
+// Take returns the aggregates by series and starts over. A window of one
+// sample and a series that stayed the same add nothing to the sample
+// itself and are left out.
+func (a *sampleAggregator) Take() map[string]Aggregate {
+	var out map[string]Aggregate
+	for key, s := range a.series {
+		if s.count < 2 || s.min == s.max {
+			continue
+		}
+		if out == nil {
+			out = map[string]Aggregate{}
+		}
+		out[key] = Aggregate{Min: s.min, Max: s.max, Avg: s.sum / float64(s.count), Count: s.count}
+	}
+	a.series = nil
+	return out
+}
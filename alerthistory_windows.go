@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"sync"
+
+	"github.com/getlantern/systray"
+)
+
+const alertHistorySize = 20
+
+// alertMenu is the tray's Alerts submenu: the latest alert events, newest
+// first, so one whose toast went unnoticed can still be read. systray cannot
+// remove items, so the lines are created up front and hidden until used.
+var alertMenu struct {
+	sync.Mutex
+	root   *systray.MenuItem
+	clear  *systray.MenuItem
+	lines  []*systray.MenuItem
+	events []AlertEvent
+}
+
+func addAlertMenu() {
+	alertMenu.root = systray.AddMenuItem(tr("Alerts"), tr("Recent alert events"))
+	for range alertHistorySize {
+		item := alertMenu.root.AddSubMenuItem("", "")
+		item.Disable()
+		item.Hide()
+		alertMenu.lines = append(alertMenu.lines, item)
+	}
+	alertMenu.clear = alertMenu.root.AddSubMenuItem(tr("Clear"), tr("Forget the alert history"))
+	go func() {
+		for range alertMenu.clear.ClickedCh {
+			alertMenu.Lock()
+			alertMenu.events = nil
+			alertMenu.Unlock()
+			updateAlertMenu()
+		}
+	}()
+	updateAlertMenu()
+}
+
+// recordAlert adds ev to the history, dropping the oldest event once it
+// holds alertHistorySize.
+func recordAlert(ev AlertEvent) {
+	alertMenu.Lock()
+	alertMenu.events = append(alertMenu.events, ev)
+	if n := len(alertMenu.events); n > alertHistorySize {
+		alertMenu.events = alertMenu.events[n-alertHistorySize:]
+	}
+	alertMenu.Unlock()
+	updateAlertMenu()
+}
+
+func updateAlertMenu() {
+	if headless || alertMenu.root == nil {
+		return
+	}
+	alertMenu.Lock()
+	defer alertMenu.Unlock()
+
+	events := alertMenu.events
+	if len(events) == 0 {
+		alertMenu.root.SetTitle(tr("Alerts"))
+		alertMenu.clear.Disable()
+	} else {
+		alertMenu.root.SetTitle(tr("Alerts (%d)", len(events)))
+		alertMenu.clear.Enable()
+	}
+	for i, item := range alertMenu.lines {
+		if i >= len(events) {
+			item.Hide()
+			continue
+		}
+		item.SetTitle(alertLine(events[len(events)-1-i]))
+		item.Show()
+	}
+}
+
+// alertLine is one history line, e.g. "Oct 14 15:04 🔴 cpu_hot: CPU is at
+// 92 °C (limit 90 °C)".
+func alertLine(ev AlertEvent) string {
+	line := fmt.Sprintf("%s %s %s: %s", ev.Timestamp.Local().Format("Jan 2 15:04"), alertMark(ev), ev.Rule, ev.Message)
+	if r := []rune(line); len(r) > 100 {
+		line = string(r[:99]) + "…"
+	}
+	return line
+}
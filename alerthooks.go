@@ -0,0 +1,119 @@
+package main
+
+import (
+	"bytes"
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"net/url"
+)
+
+var (
+	alertWebhook       = getEnv("M_ALERT_WEBHOOK", "")
+	alertWebhookSecret = getEnv("M_ALERT_WEBHOOK_SECRET", "")
+	discordWebhook     = getEnv("M_DISCORD_WEBHOOK_URL", "")
+	slackWebhook       = getEnv("M_SLACK_WEBHOOK_URL", "")
+	telegramBotToken   = getEnv("M_TELEGRAM_BOT_TOKEN", "")
+	telegramChatID     = getEnv("M_TELEGRAM_CHAT_ID", "")
+)
+
+// alertChannel delivers alert events somewhere outside the agent's own
+// server.
+type alertChannel struct {
+	name string
+	send func(AlertEvent) error
+}
+
+var alertChannels = newAlertChannels()
+
+func newAlertChannels() []alertChannel {
+	var channels []alertChannel
+	if alertWebhook != "" {
+		channels = append(channels, alertChannel{"webhook", func(ev AlertEvent) error {
+			return postWebhook(alertWebhook, alertWebhookSecret, ev)
+		}})
+	}
+	if discordWebhook != "" {
+		channels = append(channels, alertChannel{"discord", func(ev AlertEvent) error {
+			return postWebhook(discordWebhook, "", map[string]string{"content": chatText(ev)})
+		}})
+	}
+	if slackWebhook != "" {
+		channels = append(channels, alertChannel{"slack", func(ev AlertEvent) error {
+			return postWebhook(slackWebhook, "", map[string]string{"text": chatText(ev)})
+		}})
+	}
+	switch {
+	case telegramBotToken != "" && telegramChatID != "":
+		endpoint := "https://api.telegram.org/bot" + telegramBotToken + "/sendMessage"
+		channels = append(channels, alertChannel{"telegram", func(ev AlertEvent) error {
+			return postWebhook(endpoint, "", map[string]string{"chat_id": telegramChatID, "text": chatText(ev)})
+		}})
+	case telegramBotToken != "" || telegramChatID != "":
+		slog.Warn("Telegram alerts need both M_TELEGRAM_BOT_TOKEN and M_TELEGRAM_CHAT_ID")
+	}
+	return channels
+}
+
+// chatText is the one-line message posted to chat channels.
+func chatText(ev AlertEvent) string {
+	return fmt.Sprintf("%s %s · %s: %s", alertMark(ev), ev.Hostname, ev.Rule, ev.Message)
+}
+
+func alertMark(ev AlertEvent) string {
+	if ev.State == "resolved" {
+		return "✅"
+	}
+	return "🔴"
+}
+
+// deliverAlert sends ev to every channel in the background.
+func deliverAlert(ev AlertEvent) {
+	for _, c := range alertChannels {
+		go func() {
+			if err := c.send(ev); err != nil {
+				slog.Warn("Alert delivery failed", "channel", c.name, "err", err)
+			}
+		}()
+	}
+}
+
+// postWebhook posts v as JSON. With a secret the body is signed like
+// GitHub's webhooks: X-Signature-256: sha256=<hex HMAC-SHA256(secret, body)>.
+func postWebhook(endpoint, secret string, v any) error {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-win-monitor/"+version)
+	if secret != "" {
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write(body)
+		req.Header.Set("X-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		// Chat webhook URLs carry their credentials; keep them out of logs.
+		if ue, ok := err.(*url.Error); ok {
+			err = ue.Err
+		}
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return &statusError{Code: resp.StatusCode}
+	}
+	return nil
+}
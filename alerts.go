@@ -0,0 +1,465 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"math"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// alertRule fires when a sample breaks Threshold for at least For, and
+// resolves once it is back past Threshold by Hysteresis, so a reading that
+// hovers around the limit does not flap. Its toasts are at least Cooldown
+// apart.
+type alertRule struct {
+	Name       string
+	Metric     string
+	Op         string
+	Threshold  float64
+	For        time.Duration
+	Hysteresis float64
+	Cooldown   time.Duration
+
+	// Kind marks the built-in rules, which word their messages themselves.
+	Kind string
+}
+
+func (r alertRule) breached(v float64) bool {
+	switch r.Op {
+	case ">":
+		return v > r.Threshold
+	case ">=":
+		return v >= r.Threshold
+	case "<":
+		return v < r.Threshold
+	default:
+		return v <= r.Threshold
+	}
+}
+
+func (r alertRule) recovered(v float64) bool {
+	if r.Op == ">" || r.Op == ">=" {
+		return v < r.Threshold-r.Hysteresis
+	}
+	return v > r.Threshold+r.Hysteresis
+}
+
+// matches reports whether the rule covers the series key of a sample
+// named name: either the whole family or that one series.
+func (r alertRule) matches(name, key string) bool {
+	return r.Metric == name || r.Metric == key
+}
+
+// AlertEvent is sent to the server's alert endpoint when a rule fires or
+// resolves.
+type AlertEvent struct {
+	Timestamp time.Time         `json:"timestamp"`
+	MachineID string            `json:"machineId"`
+	Hostname  string            `json:"hostname"`
+	Rule      string            `json:"rule"`
+	Series    string            `json:"series"`
+	Labels    map[string]string `json:"labels,omitempty"`
+	State     string            `json:"state"`
+	Value     float64           `json:"value"`
+	Threshold float64           `json:"threshold"`
+	Message   string            `json:"message"`
+
+	cooldown time.Duration
+}
+
+var alertOps = []string{">=", "<=", ">", "<"}
+
+var (
+	alerts        = newAlertEngine(configuredAlertRules())
+	alertToasts   = getEnv("M_ALERT_TOASTS", "1") == "1"
+	alertCooldown = time.Duration(getInt("M_ALERT_COOLDOWN_MIN", 10)) * time.Minute
+)
+
+// fileAlertRules holds the [[alert_rules]] tables of the config file.
+var fileAlertRules []map[string]any
+
+func configuredAlertRules() []alertRule {
+	rules := parseAlertRules(getEnv("M_ALERT_RULES", ""))
+	rules = append(rules, parseAlertTables(fileAlertRules)...)
+	rules = append(rules, diskFreeRules(getEnv("M_DISK_FREE_ALERT", ""))...)
+	rules = append(rules, thermalRules()...)
+	rules = append(rules, dataCapRules()...)
+
+	// The engine keeps its state by rule name.
+	seen := map[string]bool{}
+	unique := rules[:0]
+	for _, r := range rules {
+		if seen[r.Name] {
+			slog.Warn("Alert rule is defined more than once, keeping the first", "rule", r.Name)
+			continue
+		}
+		seen[r.Name] = true
+		unique = append(unique, r)
+	}
+	return unique
+}
+
+// parseAlertRules reads M_ALERT_RULES: rules separated by semicolons, each
+// "[name=]metric op threshold [for duration] [hysteresis n] [cooldown
+// duration]", e.g. "cpu_hot=cpu_usage_percent > 95 for 5m hysteresis 10". Metrics are the
+// sample names of the Prometheus and OTLP outputs, optionally narrowed to
+// one series such as disk_temperature_celsius{disk="C:"}.
+func parseAlertRules(s string) []alertRule {
+	var rules []alertRule
+	for _, spec := range strings.Split(s, ";") {
+		if spec = strings.TrimSpace(spec); spec == "" {
+			continue
+		}
+		r, err := parseAlertRule(spec)
+		if err != nil {
+			slog.Warn("Invalid alert rule", "rule", spec, "err", err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+func parseAlertRule(spec string) (alertRule, error) {
+	r := alertRule{Cooldown: alertCooldown}
+	if name, rest, ok := strings.Cut(spec, "="); ok && !strings.ContainsAny(name, "<>{") {
+		r.Name, spec = strings.TrimSpace(name), rest
+	}
+
+	var cond string
+	for _, op := range alertOps {
+		if metric, rest, ok := strings.Cut(spec, op); ok {
+			r.Metric, r.Op, cond = strings.TrimSpace(metric), op, rest
+			break
+		}
+	}
+	if r.Op == "" || r.Metric == "" {
+		return r, fmt.Errorf("expected metric, operator and threshold")
+	}
+
+	fields := strings.Fields(cond)
+	if len(fields) == 0 {
+		return r, fmt.Errorf("missing threshold")
+	}
+	var err error
+	if r.Threshold, err = strconv.ParseFloat(fields[0], 64); err != nil {
+		return r, fmt.Errorf("threshold %q is not a number", fields[0])
+	}
+	for i := 1; i < len(fields); i += 2 {
+		if i+1 >= len(fields) {
+			return r, fmt.Errorf("%s needs a value", fields[i])
+		}
+		switch v := fields[i+1]; fields[i] {
+		case "for":
+			if r.For, err = time.ParseDuration(v); err != nil || r.For < 0 {
+				return r, fmt.Errorf("invalid duration %q", v)
+			}
+		case "cooldown":
+			if r.Cooldown, err = time.ParseDuration(v); err != nil || r.Cooldown < 0 {
+				return r, fmt.Errorf("invalid cooldown %q", v)
+			}
+		case "hysteresis":
+			if r.Hysteresis, err = strconv.ParseFloat(v, 64); err != nil || r.Hysteresis < 0 {
+				return r, fmt.Errorf("invalid hysteresis %q", v)
+			}
+		default:
+			return r, fmt.Errorf("unknown option %q", fields[i])
+		}
+	}
+	if r.Name == "" {
+		r.Name = r.Metric + " " + r.Op + " " + fields[0]
+	}
+	return r, nil
+}
+
+// parseAlertTables reads the rules of the config file, one table each:
+//
+//	[[alert_rules]]
+//	name = "cpu_hot"
+//	metric = "cpu_usage_percent"
+//	op = ">"
+//	threshold = 95
+//	for = "5m"
+//	hysteresis = 10
+//
+// Invalid rules are skipped; each problem is logged and a toast says how
+// many rules did not load, so a typo does not silently disable an alert.
+func parseAlertTables(tables []map[string]any) []alertRule {
+	var rules []alertRule
+	invalid := 0
+	for i, t := range tables {
+		r, err := parseAlertTable(t)
+		if err != nil {
+			invalid++
+			name, _ := t["name"].(string)
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			slog.Warn("Invalid alert rule in the config file", "path", configFilePath(), "rule", name, "err", err)
+			continue
+		}
+		rules = append(rules, r)
+	}
+	if invalid > 0 {
+		showToast(tr("Invalid alert rules"), tr("%d alert rules in the config file were not loaded, see the log", invalid))
+	}
+	return rules
+}
+
+func parseAlertTable(t map[string]any) (alertRule, error) {
+	r := alertRule{Cooldown: alertCooldown}
+	var threshold any
+	for k, v := range t {
+		var err error
+		switch k {
+		case "name":
+			r.Name, err = tomlString(k, v)
+		case "metric":
+			r.Metric, err = tomlString(k, v)
+		case "op":
+			r.Op, err = tomlString(k, v)
+		case "threshold":
+			threshold = v
+			r.Threshold, err = tomlNumber(k, v)
+		case "for":
+			r.For, err = tomlDuration(k, v)
+		case "cooldown":
+			r.Cooldown, err = tomlDuration(k, v)
+		case "hysteresis":
+			r.Hysteresis, err = tomlNumber(k, v)
+			if err == nil && r.Hysteresis < 0 {
+				err = fmt.Errorf("hysteresis must not be negative")
+			}
+		default:
+			err = fmt.Errorf("unknown key %q", k)
+		}
+		if err != nil {
+			return r, err
+		}
+	}
+
+	switch {
+	case r.Metric == "":
+		return r, fmt.Errorf("missing metric")
+	case strings.ContainsAny(r.Metric, "<>= "):
+		return r, fmt.Errorf("metric %q should be a sample name such as cpu_usage_percent", r.Metric)
+	case !slices.Contains(alertOps, r.Op):
+		return r, fmt.Errorf("op %q should be one of %s", r.Op, strings.Join(alertOps, " "))
+	case threshold == nil:
+		return r, fmt.Errorf("missing threshold")
+	}
+	if r.Name == "" {
+		r.Name = r.Metric + " " + r.Op + " " + formatAlertValue(r.Threshold)
+	}
+	return r, nil
+}
+
+func tomlString(key string, v any) (string, error) {
+	s, ok := v.(string)
+	if !ok {
+		return "", fmt.Errorf("%s should be a string", key)
+	}
+	return strings.TrimSpace(s), nil
+}
+
+func tomlNumber(key string, v any) (float64, error) {
+	switch n := v.(type) {
+	case int64:
+		return float64(n), nil
+	case float64:
+		return n, nil
+	}
+	return 0, fmt.Errorf("%s should be a number", key)
+}
+
+func tomlDuration(key string, v any) (time.Duration, error) {
+	s, ok := v.(string)
+	if !ok {
+		return 0, fmt.Errorf("%s should be a duration such as \"5m\"", key)
+	}
+	d, err := time.ParseDuration(s)
+	if err != nil || d < 0 {
+		return 0, fmt.Errorf("%s %q is not a valid duration", key, s)
+	}
+	return d, nil
+}
+
+type alertState struct {
+	since  time.Time
+	firing bool
+}
+
+// alertEngine evaluates the rules against every sample. Each series of a
+// family is tracked on its own, so one hot disk does not hide another. It
+// is only used from the collection loop.
+type alertEngine struct {
+	rules  []alertRule
+	states map[string]*alertState
+}
+
+func newAlertEngine(rules []alertRule) *alertEngine {
+	return &alertEngine{rules: rules, states: map[string]*alertState{}}
+}
+
+// SetRules replaces the rules, keeping the state of those whose name is
+// unchanged, and of the script alerts, so a reload does not fire them
+// again.
+func (e *alertEngine) SetRules(rules []alertRule) {
+	names := map[string]bool{}
+	for _, r := range rules {
+		names[r.Name] = true
+	}
+	for k := range e.states {
+		if rule, series, _ := strings.Cut(k, "\x00"); !names[rule] && !strings.HasPrefix(series, "script:") {
+			delete(e.states, k)
+		}
+	}
+	e.rules = rules
+}
+
+// Evaluate returns the alerts that fired or resolved with m.
+func (e *alertEngine) Evaluate(m Metrics, now time.Time) []AlertEvent {
+	if len(e.rules) == 0 {
+		return nil
+	}
+	var events []AlertEvent
+	visitSamples(m, func(name, _ string, v float64, labels ...string) {
+		key := seriesKey(name, labels)
+		for _, r := range e.rules {
+			if r.matches(name, key) {
+				if ev, ok := e.check(r, key, labels, v, now); ok {
+					ev.MachineID, ev.Hostname = m.MachineID, m.Hostname
+					events = append(events, ev)
+				}
+			}
+		}
+	})
+	return events
+}
+
+// EvaluateConditions returns the script alerts that fired or resolved,
+// each handled like a rule firing at 1 on a 0/1 sample. The series names
+// the script.
+func (e *alertEngine) EvaluateConditions(m Metrics, conds []scriptCondition, now time.Time) []AlertEvent {
+	var events []AlertEvent
+	for _, c := range conds {
+		r := alertRule{Name: c.name, Kind: "script", Metric: "script", Op: ">=", Threshold: 1, Cooldown: alertCooldown}
+		if ev, ok := e.check(r, "script:"+c.script, nil, boolFloat(c.firing), now); ok {
+			ev.MachineID, ev.Hostname = m.MachineID, m.Hostname
+			if ev.State == "fired" && c.message != "" {
+				ev.Message = c.message
+			}
+			events = append(events, ev)
+		}
+	}
+	return events
+}
+
+func (e *alertEngine) check(r alertRule, series string, labels []string, v float64, now time.Time) (AlertEvent, bool) {
+	id := r.Name + "\x00" + series
+	s := e.states[id]
+	if s == nil {
+		s = &alertState{}
+		e.states[id] = s
+	}
+	ev := AlertEvent{Timestamp: now.UTC(), Rule: r.Name, Series: series, Value: v, Threshold: r.Threshold, cooldown: r.Cooldown}
+
+	if s.firing {
+		if !r.recovered(v) {
+			return ev, false
+		}
+		s.firing, s.since = false, time.Time{}
+		ev.State = "resolved"
+		describeAlert(r, &ev, labels)
+		return ev, true
+	}
+
+	if !r.breached(v) {
+		s.since = time.Time{}
+		return ev, false
+	}
+	if s.since.IsZero() {
+		s.since = now
+	}
+	if now.Sub(s.since) < r.For {
+		return ev, false
+	}
+	s.firing = true
+	ev.State = "fired"
+	describeAlert(r, &ev, labels)
+	return ev, true
+}
+
+func describeAlert(r alertRule, ev *AlertEvent, labels []string) {
+	if len(labels) > 0 {
+		ev.Labels = map[string]string{}
+		for i := 0; i+1 < len(labels); i += 2 {
+			ev.Labels[labels[i]] = labels[i+1]
+		}
+	}
+	switch {
+	case r.Kind == "disk-free-percent" || r.Kind == "disk-free-gb":
+		ev.Message = describeDiskFree(r, *ev)
+	case r.Kind == "temperature" || r.Kind == "throttling":
+		ev.Message = describeThermal(r, *ev)
+	case r.Kind == "data-cap":
+		ev.Message = describeDataCap(r, *ev)
+	case r.Kind == "script" && ev.State == "resolved":
+		ev.Message = ev.Rule + " is over"
+	case r.Kind == "script":
+		ev.Message = ev.Rule
+	case ev.State == "resolved":
+		ev.Message = fmt.Sprintf("%s back to %s", ev.Series, formatAlertValue(ev.Value))
+	default:
+		ev.Message = fmt.Sprintf("%s is %s (%s %s", ev.Series, formatAlertValue(ev.Value), r.Op, formatAlertValue(r.Threshold))
+		if r.For > 0 {
+			ev.Message += " for " + r.For.String()
+		}
+		ev.Message += ")"
+	}
+}
+
+func formatAlertValue(v float64) string {
+	return strconv.FormatFloat(math.Round(v*10)/10, 'f', -1, 64)
+}
+
+// lastAlertToast is when each rule last showed a toast.
+var lastAlertToast = struct {
+	sync.Mutex
+	at map[string]time.Time
+}{at: map[string]time.Time{}}
+
+// raiseAlert logs ev, shows a toast when it fired, and sends it to the
+// server and the alert channels in the background so a slow server does
+// not hold up the collection loop. Besides the loop, the health collector
+// raises its own alerts.
+func raiseAlert(ev AlertEvent) {
+	slog.Warn("Alert", "state", ev.State, "rule", ev.Rule, "series", ev.Series, "value", ev.Value, "message", ev.Message, "event", evtAlert)
+	recordAlert(ev)
+	if ev.State == "fired" {
+		noteAlertFired(ev.Timestamp)
+	}
+	if alertToasts && ev.State == "fired" && shouldToastAlert(ev) {
+		showToast(tr("Alert: %s", ev.Rule), ev.Message)
+	}
+	deliverAlert(ev)
+	go func() {
+		if err := publish("alert", ev); err != nil {
+			slog.Warn("Alert report failed", "err", err)
+		}
+	}()
+}
+
+func shouldToastAlert(ev AlertEvent) bool {
+	lastAlertToast.Lock()
+	defer lastAlertToast.Unlock()
+	if ev.Timestamp.Sub(lastAlertToast.at[ev.Rule]) < ev.cooldown {
+		return false
+	}
+	lastAlertToast.at[ev.Rule] = ev.Timestamp
+	return true
+}
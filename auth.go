@@ -0,0 +1,106 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"regexp"
+	"sync"
+)
+
+// nonces holds, per host, the last nonce the server offered, either in a
+// 401 challenge (WWW-Authenticate: HMAC nonce="...") or ahead of time
+// (Authentication-Info: nextnonce="..."). Each nonce is used once.
+var nonces sync.Map
+
+// hmacLocks holds a mutex per host. The server has one nonce outstanding at
+// a time, so in hmac mode a host's requests go one at a time; otherwise two
+// of them would keep spending each other's challenge.
+var hmacLocks sync.Map
+
+var (
+	challengeNonce = regexp.MustCompile(`(?i)^HMAC\b.*\bnonce="([^"]+)"`)
+	nextNonce      = regexp.MustCompile(`(?i)\bnextnonce="([^"]+)"`)
+)
+
+// authorize sets the Authorization header. With M_SESSION_TOKENS every
+// request but the token exchange itself carries a short-lived session token.
+// Otherwise, in the default hmac mode, the raw secret never leaves the
+// machine: the agent answers the server's nonce with HMAC-SHA256(secret,
+// nonce). Without a nonce at hand the request goes out unauthenticated and
+// the server's 401 supplies one.
+func authorize(req *http.Request, token string) error {
+	if token == "" {
+		return nil
+	}
+	if sessionTokens && !isTokenEndpoint(req.URL) {
+		t, err := sessionToken(req.URL, token)
+		if err != nil {
+			return fmt.Errorf("session token: %w", err)
+		}
+		req.Header.Set("Authorization", "Bearer "+t)
+		return nil
+	}
+	if authMode == "bearer" {
+		req.Header.Set("Authorization", "Bearer "+token)
+		return nil
+	}
+
+	v, ok := nonces.LoadAndDelete(req.URL.Host)
+	if !ok {
+		return nil
+	}
+	nonce := v.(string)
+	mac := hmac.New(sha256.New, []byte(token))
+	mac.Write([]byte(nonce))
+	req.Header.Set("Authorization", `HMAC nonce="`+nonce+`", signature="`+hex.EncodeToString(mac.Sum(nil))+`"`)
+	return nil
+}
+
+// lockAuth waits for the host's turn when the request is signed with a
+// nonce and returns the function that ends it.
+func lockAuth(endpoint, token string) func() {
+	u, err := url.Parse(endpoint)
+	if err != nil || token == "" || authMode == "bearer" || sessionTokens && !isTokenEndpoint(u) {
+		return func() {}
+	}
+	v, _ := hmacLocks.LoadOrStore(u.Host, new(sync.Mutex))
+	mu := v.(*sync.Mutex)
+	mu.Lock()
+	return mu.Unlock
+}
+
+func noteNonce(host string, h http.Header) {
+	if m := nextNonce.FindStringSubmatch(h.Get("Authentication-Info")); m != nil {
+		nonces.Store(host, m[1])
+	}
+	for _, c := range h.Values("WWW-Authenticate") {
+		if m := challengeNonce.FindStringSubmatch(c); m != nil {
+			nonces.Store(host, m[1])
+		}
+	}
+}
+
+// mustRetryAuth reports whether a 401 came with a fresh challenge that the
+// request can be repeated with.
+func mustRetryAuth(endpoint string, err error) bool {
+	var se *statusError
+	if !errors.As(err, &se) || se.Code != http.StatusUnauthorized {
+		return false
+	}
+	u, _ := url.Parse(endpoint)
+	if sessionTokens && !isTokenEndpoint(u) {
+		// The session token was revoked or expired early; fetch a new one.
+		dropSessionToken(u)
+		return true
+	}
+	if authMode == "bearer" {
+		return false
+	}
+	_, ok := nonces.Load(u.Host)
+	return ok
+}
@@ -0,0 +1,50 @@
+package main
+
+import (
+	"os"
+	"strings"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+const runKey = `Software\Microsoft\Windows\CurrentVersion\Run`
+
+// autostartEnabled reports whether the agent is registered to start at
+// login for the current user.
+func autostartEnabled() bool {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	_, _, err = k.GetStringValue(winServiceName)
+	return err == nil
+}
+
+// setAutostart adds or removes the Run entry. The entry starts this
+// executable with the arguments it was started with, so the login launch
+// matches the current one.
+func setAutostart(on bool) error {
+	k, err := registry.OpenKey(registry.CURRENT_USER, runKey, registry.SET_VALUE)
+	if err != nil {
+		return err
+	}
+	defer k.Close()
+	if !on {
+		if err := k.DeleteValue(winServiceName); err != nil && err != registry.ErrNotExist {
+			return err
+		}
+		return nil
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	args := []string{windows.EscapeArg(exe)}
+	for _, a := range os.Args[1:] {
+		args = append(args, windows.EscapeArg(a))
+	}
+	return k.SetStringValue(winServiceName, strings.Join(args, " "))
+}
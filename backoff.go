@@ -0,0 +1,28 @@
+package main
+
+import (
+	"math/rand/v2"
+	"time"
+)
+
+type backoff struct {
+	base    time.Duration
+	max     time.Duration
+	attempt int
+}
+
+// Next returns the delay before the next attempt: exponential growth capped at
+// max, with "equal jitter" so agents that failed together don't retry together.
+func (b *backoff) Next() time.Duration {
+	d := b.base << min(b.attempt, 16)
+	if d <= 0 || d > b.max {
+		d = b.max
+	}
+	b.attempt++
+	half := d / 2
+	return half + rand.N(half+1)
+}
+
+func (b *backoff) Reset() {
+	b.attempt = 0
+}
@@ -1,2 +1,6 @@
-go build -ldflags "-H=windowsgui" -o monitor.exe .
+$version = git describe --tags --always --dirty 2>$null
+if (-not $version) { $version = "dev" }
+$buildDate = Get-Date -Format "yyyy-MM-dd HH:mm"
+$updateKey = $env:UPDATE_PUBLIC_KEY
+go build -ldflags "-H=windowsgui -X main.version=$version -X 'main.buildDate=$buildDate' -X main.updatePublicKey=$updateKey" -o monitor.exe .
 if (Test-Path monitor.exe) { Write-Host "Build successful!" } else { Write-Host "Build failed!" }
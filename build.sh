@@ -0,0 +1,10 @@
+#!/bin/sh
+# Builds the headless agent for Linux and macOS; Windows builds use build.ps1.
+version=$(git describe --tags --always --dirty 2>/dev/null || echo dev)
+buildDate=$(date "+%Y-%m-%d %H:%M")
+for target in linux/amd64 linux/arm64 darwin/amd64 darwin/arm64; do
+	os=${target%/*}
+	arch=${target#*/}
+	CGO_ENABLED=0 GOOS=$os GOARCH=$arch go build -ldflags "-X main.version=$version -X 'main.buildDate=$buildDate' -X main.updatePublicKey=$UPDATE_PUBLIC_KEY" -o "monitor-$os-$arch" . || exit 1
+done
+echo "Build successful!"
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import (
+	"crypto/tls"
+	"errors"
+)
+
+func loadStoreCertificate(string) (tls.Certificate, error) {
+	return tls.Certificate{}, errors.New("the certificate store is only available on Windows, use M_TLS_CERT_FILE and M_TLS_KEY_FILE")
+}
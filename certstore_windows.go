@@ -0,0 +1,160 @@
+package main
+
+import (
+	"crypto"
+	"crypto/ecdsa"
+	"crypto/rsa"
+	"crypto/tls"
+	"crypto/x509"
+	"encoding/asn1"
+	"fmt"
+	"io"
+	"math/big"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	ncrypt             = windows.NewLazySystemDLL("ncrypt.dll")
+	procNCryptSignHash = ncrypt.NewProc("NCryptSignHash")
+)
+
+const (
+	bcryptPadPKCS1 = 0x2
+	bcryptPadPSS   = 0x8
+	ncryptSilent   = 0x40
+)
+
+// loadStoreCertificate finds a client certificate by subject in the current
+// user's, then the machine's, personal store. The private key stays in the
+// store (DPAPI / CNG) and is only used through NCryptSignHash.
+func loadStoreCertificate(subject string) (tls.Certificate, error) {
+	for _, location := range []uint32{windows.CERT_SYSTEM_STORE_CURRENT_USER, windows.CERT_SYSTEM_STORE_LOCAL_MACHINE} {
+		cert, err := findStoreCertificate(location, subject)
+		if err == nil {
+			return cert, nil
+		}
+		if err != windows.Errno(windows.CRYPT_E_NOT_FOUND) {
+			return tls.Certificate{}, err
+		}
+	}
+	return tls.Certificate{}, fmt.Errorf("no certificate matching %q in the personal store", subject)
+}
+
+func findStoreCertificate(location uint32, subject string) (tls.Certificate, error) {
+	store, err := windows.CertOpenStore(windows.CERT_STORE_PROV_SYSTEM, 0, 0,
+		location|windows.CERT_STORE_READONLY_FLAG,
+		uintptr(unsafe.Pointer(windows.StringToUTF16Ptr("MY"))))
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("open store: %w", err)
+	}
+	defer windows.CertCloseStore(store, 0)
+
+	ctx, err := windows.CertFindCertificateInStore(store,
+		windows.X509_ASN_ENCODING|windows.PKCS_7_ASN_ENCODING, 0,
+		windows.CERT_FIND_SUBJECT_STR, unsafe.Pointer(windows.StringToUTF16Ptr(subject)), nil)
+	if err != nil {
+		return tls.Certificate{}, err
+	}
+	defer windows.CertFreeCertificateContext(ctx)
+
+	der := append([]byte(nil), unsafe.Slice(ctx.EncodedCert, ctx.Length)...)
+	leaf, err := x509.ParseCertificate(der)
+	if err != nil {
+		return tls.Certificate{}, fmt.Errorf("parse certificate: %w", err)
+	}
+
+	// With CRYPT_ACQUIRE_CACHE_FLAG the key handle belongs to the
+	// certificate context, so it is kept alive with a duplicate.
+	keep := windows.CertDuplicateCertificateContext(ctx)
+	var key windows.Handle
+	var spec uint32
+	var mustFree bool
+	err = windows.CryptAcquireCertificatePrivateKey(keep,
+		windows.CRYPT_ACQUIRE_CACHE_FLAG|windows.CRYPT_ACQUIRE_ONLY_NCRYPT_KEY_FLAG|windows.CRYPT_ACQUIRE_SILENT_FLAG,
+		nil, &key, &spec, &mustFree)
+	if err != nil {
+		windows.CertFreeCertificateContext(keep)
+		return tls.Certificate{}, fmt.Errorf("acquire private key: %w", err)
+	}
+
+	return tls.Certificate{
+		Certificate: [][]byte{der},
+		PrivateKey:  &storeSigner{key: key, pub: leaf.PublicKey},
+		Leaf:        leaf,
+	}, nil
+}
+
+type storeSigner struct {
+	key windows.Handle
+	pub crypto.PublicKey
+}
+
+func (s *storeSigner) Public() crypto.PublicKey {
+	return s.pub
+}
+
+func (s *storeSigner) Sign(_ io.Reader, digest []byte, opts crypto.SignerOpts) ([]byte, error) {
+	var alg string
+	switch opts.HashFunc() {
+	case crypto.SHA1:
+		alg = "SHA1"
+	case crypto.SHA256:
+		alg = "SHA256"
+	case crypto.SHA384:
+		alg = "SHA384"
+	case crypto.SHA512:
+		alg = "SHA512"
+	default:
+		return nil, fmt.Errorf("unsupported hash %v", opts.HashFunc())
+	}
+	algPtr := windows.StringToUTF16Ptr(alg)
+
+	var padding unsafe.Pointer
+	var flags uint32
+	switch s.pub.(type) {
+	case *rsa.PublicKey:
+		if pss, ok := opts.(*rsa.PSSOptions); ok {
+			salt := pss.SaltLength
+			if salt == rsa.PSSSaltLengthEqualsHash || salt == rsa.PSSSaltLengthAuto {
+				salt = opts.HashFunc().Size()
+			}
+			padding = unsafe.Pointer(&struct {
+				alg  *uint16
+				salt uint32
+			}{algPtr, uint32(salt)})
+			flags = bcryptPadPSS
+		} else {
+			padding = unsafe.Pointer(&struct{ alg *uint16 }{algPtr})
+			flags = bcryptPadPKCS1
+		}
+	case *ecdsa.PublicKey:
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", s.pub)
+	}
+
+	var size uint32
+	if r, _, _ := procNCryptSignHash.Call(uintptr(s.key), uintptr(padding),
+		uintptr(unsafe.Pointer(&digest[0])), uintptr(len(digest)),
+		0, 0, uintptr(unsafe.Pointer(&size)), uintptr(flags|ncryptSilent)); r != 0 {
+		return nil, fmt.Errorf("NCryptSignHash: 0x%x", r)
+	}
+	sig := make([]byte, size)
+	if r, _, _ := procNCryptSignHash.Call(uintptr(s.key), uintptr(padding),
+		uintptr(unsafe.Pointer(&digest[0])), uintptr(len(digest)),
+		uintptr(unsafe.Pointer(&sig[0])), uintptr(size), uintptr(unsafe.Pointer(&size)), uintptr(flags|ncryptSilent)); r != 0 {
+		return nil, fmt.Errorf("NCryptSignHash: 0x%x", r)
+	}
+	sig = sig[:size]
+
+	if _, ok := s.pub.(*ecdsa.PublicKey); ok {
+		// CNG returns r||s; TLS wants the ASN.1 form.
+		half := len(sig) / 2
+		return asn1.Marshal(struct{ R, S *big.Int }{
+			new(big.Int).SetBytes(sig[:half]),
+			new(big.Int).SetBytes(sig[half:]),
+		})
+	}
+	return sig, nil
+}
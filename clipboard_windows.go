@@ -0,0 +1,92 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procOpenClipboard    = user32.NewProc("OpenClipboard")
+	procCloseClipboard   = user32.NewProc("CloseClipboard")
+	procEmptyClipboard   = user32.NewProc("EmptyClipboard")
+	procSetClipboardData = user32.NewProc("SetClipboardData")
+	procGlobalAlloc      = kernel32.NewProc("GlobalAlloc")
+	procGlobalLock       = kernel32.NewProc("GlobalLock")
+	procGlobalUnlock     = kernel32.NewProc("GlobalUnlock")
+	procGlobalFree       = kernel32.NewProc("GlobalFree")
+	procRtlMoveMemory    = kernel32.NewProc("RtlMoveMemory")
+)
+
+const (
+	cfUnicodeText = 13
+	gmemMoveable  = 0x0002
+)
+
+// copyMetrics puts a readable summary of the latest sample followed by its
+// full JSON on the clipboard, ready to paste into an issue or chat.
+func copyMetrics() {
+	m, ok := latestMetrics()
+	if !ok {
+		slog.Info("Copy metrics: nothing collected yet")
+		return
+	}
+	data, err := json.MarshalIndent(m, "", "  ")
+	if err != nil {
+		slog.Warn("Copy metrics failed", "err", err)
+		return
+	}
+	if err := setClipboardText(metricsSummary(m) + "\r\n" + string(data)); err != nil {
+		slog.Warn("Copy metrics failed", "err", err)
+	}
+}
+
+func metricsSummary(m Metrics) string {
+	var b strings.Builder
+	fmt.Fprintf(&b, "%s, go-win-monitor %s, %s\r\n", m.Hostname, version, m.Timestamp.Local().Format("2006-01-02 15:04:05"))
+	fmt.Fprintf(&b, "CPU: %.1f%%\r\n", m.CPU)
+	fmt.Fprintf(&b, "RAM: %.1f%% (%d MB / %d MB)\r\n", m.RAM, m.RAMUsedMB, m.RAMTotalMB)
+	if m.GPU >= 0 {
+		fmt.Fprintf(&b, "GPU: %.0f%%\r\n", m.GPU)
+	}
+	fmt.Fprintf(&b, "Network: down %.0f B/s, up %.0f B/s\r\n", m.NetRecvBps, m.NetSentBps)
+	fmt.Fprintf(&b, "Uptime: %s\r\n", formatUptime(m.UptimeSec))
+	return b.String()
+}
+
+func setClipboardText(s string) error {
+	text, err := windows.UTF16FromString(s)
+	if err != nil {
+		return err
+	}
+
+	if r, _, err := procOpenClipboard.Call(0); r == 0 {
+		return fmt.Errorf("OpenClipboard: %w", err)
+	}
+	defer procCloseClipboard.Call()
+	procEmptyClipboard.Call()
+
+	size := uintptr(len(text)) * 2
+	h, _, err := procGlobalAlloc.Call(gmemMoveable, size)
+	if h == 0 {
+		return fmt.Errorf("GlobalAlloc: %w", err)
+	}
+	p, _, err := procGlobalLock.Call(h)
+	if p == 0 {
+		procGlobalFree.Call(h)
+		return fmt.Errorf("GlobalLock: %w", err)
+	}
+	procRtlMoveMemory.Call(p, uintptr(unsafe.Pointer(&text[0])), size)
+	procGlobalUnlock.Call(h)
+
+	// On success the clipboard owns the memory.
+	if r, _, err := procSetClipboardData.Call(cfUnicodeText, h); r == 0 {
+		procGlobalFree.Call(h)
+		return fmt.Errorf("SetClipboardData: %w", err)
+	}
+	return nil
+}
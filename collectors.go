@@ -0,0 +1,434 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/host"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// Collector gathers one group of metrics, named as in M_COLLECTORS and the
+// server's subscription. Collect only reads; the fields it returns are
+// merged into the payload afterwards, in registry order. A collector with
+// nothing to report, e.g. turned off by its own setting, returns nil.
+type Collector interface {
+	Name() string
+	Collect(ctx context.Context) (fields, error)
+}
+
+// fields merges a collector's readings into the payload.
+type fields func(*Metrics)
+
+type collectorFunc struct {
+	name    string
+	collect func(context.Context) (fields, error)
+}
+
+func (c collectorFunc) Name() string { return c.name }
+
+func (c collectorFunc) Collect(ctx context.Context) (fields, error) { return c.collect(ctx) }
+
+// collectorRegistry holds every collector, the built-in ones followed by
+// the exec plugins. The order is the merge order: gpu and sensors add
+// their fans to the readings of fans, and sensors fills in the CPU
+// temperature cpu could not read.
+var collectorRegistry = append([]Collector{
+	collectorFunc{"cpu", collectCPU},
+	collectorFunc{"memory", collectMemory},
+	collectorFunc{"pagefile", collectPagefile},
+	collectorFunc{"system", collectSystem},
+	collectorFunc{"wifi", collectWiFi},
+	collectorFunc{"watch", collectWatched},
+	collectorFunc{"services", collectServices},
+	collectorFunc{"docker", collectDocker},
+	collectorFunc{"hyperv", collectHyperV},
+	collectorFunc{"network", collectNetwork},
+	collectorFunc{"session", collectSession},
+	collectorFunc{"users", collectUsers},
+	collectorFunc{"foreground", collectForeground},
+	collectorFunc{"public-ip", collectPublicIP},
+	collectorFunc{"clock", collectClock},
+	collectorFunc{"etw-network", collectTopNetwork},
+	collectorFunc{"pings", collectPings},
+	collectorFunc{"fans", collectFans},
+	collectorFunc{"disk-temps", collectDiskTemps},
+	collectorFunc{"volumes", collectVolumes},
+	collectorFunc{"gpu", collectGPU},
+	collectorFunc{"gpu-processes", collectGPUProcesses},
+	collectorFunc{"sensors", collectSensors},
+	collectorFunc{"counters", collectCounters},
+	collectorFunc{"wmi", collectWMI},
+	collectorFunc{"agent", collectAgent},
+}, pluginCollectors()...)
+
+// collectorNames lists the groups of metrics a server can subscribe to.
+var collectorNames = registeredCollectorNames()
+
+// collectorTimeout is how long collectMetrics waits for a collector, set
+// with M_COLLECTOR_TIMEOUT. A collector can ask for its own by implementing
+// Timeout, as the exec plugins do with M_PLUGIN_TIMEOUT.
+var collectorTimeout = getInterval("M_COLLECTOR_TIMEOUT", 5*time.Second)
+
+type timedCollector interface {
+	Timeout() time.Duration
+}
+
+func timeoutOf(c Collector) time.Duration {
+	if t, ok := c.(timedCollector); ok {
+		return t.Timeout()
+	}
+	return collectorTimeout
+}
+
+// collectorState follows a collector across ticks. busy is set while a call
+// is in flight, which may outlive the tick that started it when the source
+// ignores its context; slow is only touched by collectMetrics.
+var errStillRunning = errors.New("still running from an earlier collect")
+
+type collectorState struct {
+	busy atomic.Bool
+	slow bool
+}
+
+var collectorStates = newCollectorStates()
+
+func newCollectorStates() map[string]*collectorState {
+	states := make(map[string]*collectorState, len(collectorRegistry))
+	for _, c := range collectorRegistry {
+		states[c.Name()] = &collectorState{}
+	}
+	return states
+}
+
+func registeredCollectorNames() []string {
+	names := make([]string, len(collectorRegistry))
+	for i, c := range collectorRegistry {
+		names[i] = c.Name()
+	}
+	return names
+}
+
+// collectMetrics runs the wanted collectors in parallel and merges what
+// they return, in registry order, on the caller's goroutine. A collector
+// that fails or misses its timeout leaves its fields at their defaults for
+// this tick and is listed in Errors; one still running from an earlier tick
+// is not started again until it returns.
+func collectMetrics(ctx context.Context) Metrics {
+	m := Metrics{Timestamp: time.Now().UTC(), MachineID: machineID(), Hostname: hostname(), Tags: currentTags(), GPU: -1, GPUEncoder: -1, GPUDecoder: -1, GPUCoreMHz: -1, GPUMemMHz: -1, GPUTempC: -1, CPUTempC: -1, WiFiSignal: -1}
+	failed := func(name string, err error) {
+		if m.Errors == nil {
+			m.Errors = map[string]string{}
+		}
+		m.Errors[name] = err.Error()
+	}
+	type result struct {
+		f   fields
+		err error
+	}
+	type pending struct {
+		c        Collector
+		state    *collectorState
+		deadline time.Time
+		result   chan result
+	}
+	var started []pending
+	for _, c := range collectorRegistry {
+		if !wants(c.Name()) {
+			continue
+		}
+		state := collectorStates[c.Name()]
+		if !state.busy.CompareAndSwap(false, true) {
+			slog.Debug("Collector still running, skipped", "collector", c.Name())
+			failed(c.Name(), errStillRunning)
+			continue
+		}
+		timeout := timeoutOf(c)
+		p := pending{c: c, state: state, deadline: time.Now().Add(timeout), result: make(chan result, 1)}
+		go func() {
+			defer state.busy.Store(false)
+			ctx, cancel := context.WithTimeout(ctx, timeout)
+			defer cancel()
+			var r result
+			if err := runCollector(c.Name(), func() { r.f, r.err = c.Collect(ctx) }); err != nil {
+				r.err = err
+			}
+			if r.err != nil {
+				slog.Debug("Collector failed", "collector", c.Name(), "err", r.err)
+			}
+			p.result <- r
+		}()
+		started = append(started, p)
+	}
+
+	for _, p := range started {
+		timer := time.NewTimer(time.Until(p.deadline))
+		select {
+		case r := <-p.result:
+			if p.state.slow {
+				p.state.slow = false
+				slog.Info("Collector back in time", "collector", p.c.Name())
+			}
+			if r.err != nil {
+				failed(p.c.Name(), r.err)
+			}
+			if r.f != nil {
+				if err := runCollector(p.c.Name(), func() { r.f(&m) }); err != nil {
+					failed(p.c.Name(), err)
+				}
+			}
+		case <-timer.C:
+			if !p.state.slow {
+				p.state.slow = true
+				slog.Warn("Collector timed out, its metrics are left out", "collector", p.c.Name(), "timeout", timeoutOf(p.c))
+			}
+			failed(p.c.Name(), fmt.Errorf("timed out after %s", timeoutOf(p.c)))
+		case <-ctx.Done():
+		}
+		timer.Stop()
+	}
+	return m
+}
+
+func collectCPU(ctx context.Context) (fields, error) {
+	percent, err := cpu.PercentWithContext(ctx, 0, false)
+	if err != nil || len(percent) == 0 {
+		return nil, err
+	}
+	cur, base, _ := getCPUFrequency()
+	temp := getCPUTemperature()
+	return func(m *Metrics) {
+		m.CPU = percent[0]
+		m.CPUFreqMHz = cur
+		m.CPUBaseFreqMHz = base
+		m.CPUTempC = temp
+		m.CPUThrottled = cpuThrottled(*m)
+	}, nil
+}
+
+func collectMemory(ctx context.Context) (fields, error) {
+	vm, err := mem.VirtualMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *Metrics) {
+		m.RAM = vm.UsedPercent
+		m.RAMUsedMB = vm.Used / 1024 / 1024
+		m.RAMTotalMB = vm.Total / 1024 / 1024
+		m.RAMAvailableMB = vm.Available / 1024 / 1024
+	}, nil
+}
+
+func collectPagefile(ctx context.Context) (fields, error) {
+	swap, err := mem.SwapMemoryWithContext(ctx)
+	if err != nil {
+		return nil, err
+	}
+	var used, total uint64
+	pagefiles, err := mem.SwapDevicesWithContext(ctx)
+	for _, p := range pagefiles {
+		used += p.UsedBytes
+		total += p.UsedBytes + p.FreeBytes
+	}
+	return func(m *Metrics) {
+		m.CommitUsedMB = swap.Used / 1024 / 1024
+		m.CommitLimitMB = swap.Total / 1024 / 1024
+		m.PagefileUsedMB = used / 1024 / 1024
+		m.PagefileTotalMB = total / 1024 / 1024
+		if total > 0 {
+			m.Pagefile = float64(used) / float64(total) * 100
+		}
+	}, err
+}
+
+func collectSystem(ctx context.Context) (fields, error) {
+	var s Metrics
+	getSystemCounters(&s)
+	bootTime, err := host.BootTimeWithContext(ctx)
+	pending := isRebootPending()
+	return func(m *Metrics) {
+		m.Processes, m.Threads, m.Handles = s.Processes, s.Threads, s.Handles
+		m.RAMCachedMB, m.PagedPoolMB, m.NonPagedPoolMB = s.RAMCachedMB, s.PagedPoolMB, s.NonPagedPoolMB
+		if err == nil {
+			m.BootTime = bootTime
+			m.UptimeSec = uint64(time.Now().Unix()) - bootTime
+		}
+		m.PendingReboot = pending
+	}, err
+}
+
+func collectWiFi(context.Context) (fields, error) {
+	ssid, quality, ok := getWiFi()
+	if !ok {
+		return nil, nil
+	}
+	return func(m *Metrics) { m.WiFiSSID, m.WiFiSignal = ssid, quality }, nil
+}
+
+func collectWatched(context.Context) (fields, error) {
+	if len(watch) == 0 {
+		return nil, nil
+	}
+	watched, err := getWatchedProcesses(watch)
+	if err != nil {
+		return nil, err
+	}
+	return func(m *Metrics) { m.Watched = watched }, nil
+}
+
+func collectServices(context.Context) (fields, error) {
+	if len(services) == 0 {
+		return nil, nil
+	}
+	statuses := getServiceStatuses(services)
+	return func(m *Metrics) { m.Services = statuses }, nil
+}
+
+func collectDocker(context.Context) (fields, error) {
+	if !docker.Load() {
+		return nil, nil
+	}
+	containers, ok := getContainerStats()
+	if !ok {
+		return nil, nil
+	}
+	return func(m *Metrics) { m.Containers = containers }, nil
+}
+
+func collectHyperV(context.Context) (fields, error) {
+	if !hyperV.Load() {
+		return nil, nil
+	}
+	vms, ok := getHyperVStats()
+	if !ok {
+		return nil, nil
+	}
+	return func(m *Metrics) { m.VMs = vms }, nil
+}
+
+func collectNetwork(context.Context) (fields, error) {
+	tcp, tcpOK := getTCPStats()
+	counters := getInterfaceCounters()
+	interfaces := getInterfaceErrors(counters)
+	var usage *DataUsage
+	if dataUsageEnabled {
+		dataUsage.Add(counters)
+		usage = dataUsage.Usage()
+	}
+	sent, recv := getNetworkThroughput()
+	return func(m *Metrics) {
+		if tcpOK {
+			m.TCP = &tcp
+		}
+		m.Interfaces = interfaces
+		m.DataUsage = usage
+		m.NetSentBps, m.NetRecvBps = sent, recv
+	}, nil
+}
+
+func collectSession(context.Context) (fields, error) {
+	idle, idleOK := getIdleSeconds()
+	locked := isSessionLocked()
+	return func(m *Metrics) {
+		if idleOK {
+			m.IdleSec = idle
+		}
+		m.SessionLocked = locked
+	}, nil
+}
+
+func collectForeground(context.Context) (fields, error) {
+	if !reportForeground.Load() {
+		return nil, nil
+	}
+	app, ok := getForegroundApp()
+	if !ok {
+		return nil, nil
+	}
+	return func(m *Metrics) { m.ForegroundApp = app }, nil
+}
+
+func collectPublicIP(context.Context) (fields, error) {
+	if ipLookup == "" {
+		return nil, nil
+	}
+	ip := getPublicIP(ipLookup)
+	return func(m *Metrics) { m.PublicIP = ip }, nil
+}
+
+func collectTopNetwork(context.Context) (fields, error) {
+	if !etwNet {
+		return nil, nil
+	}
+	top := getTopNetworkProcesses()
+	return func(m *Metrics) { m.TopNetwork = top }, nil
+}
+
+func collectPings(context.Context) (fields, error) {
+	if len(pings) == 0 {
+		return nil, nil
+	}
+	results := pingHosts(pings)
+	return func(m *Metrics) { m.Pings = results }, nil
+}
+
+func collectFans(context.Context) (fields, error) {
+	fans := getFans()
+	return func(m *Metrics) { m.Fans = fans }, nil
+}
+
+func collectDiskTemps(context.Context) (fields, error) {
+	temps := getDiskTemperatures()
+	return func(m *Metrics) { m.DiskTemps = temps }, nil
+}
+
+func collectVolumes(context.Context) (fields, error) {
+	volumes := getVolumes()
+	return func(m *Metrics) { m.Volumes = volumes }, nil
+}
+
+func collectGPU(ctx context.Context) (fields, error) {
+	gpu, ok, err := getNvidiaGPU(ctx)
+	if errors.Is(err, errNoNvidia) {
+		return nil, nil
+	}
+	if !ok {
+		return nil, err
+	}
+	return func(m *Metrics) {
+		m.GPU = gpu.Utilization
+		m.GPUEncoder = gpu.Encoder
+		m.GPUDecoder = gpu.Decoder
+		m.GPUCoreMHz = gpu.CoreClock
+		m.GPUMemMHz = gpu.MemClock
+		m.GPUTempC = gpu.TempC
+		m.GPUThrottled = gpuThrottled(gpu.Utilization, gpu.CoreClock, gpu.MaxClock)
+		if gpu.FanSpeed >= 0 && wants("fans") {
+			m.Fans = append(m.Fans, FanReading{Name: "GPU", RPM: -1, Percent: gpu.FanSpeed})
+		}
+	}, nil
+}
+
+func collectGPUProcesses(ctx context.Context) (fields, error) {
+	if !gpuProcesses.Load() {
+		return nil, nil
+	}
+	procs, err := getGPUProcesses(ctx)
+	if errors.Is(err, errNoNvidia) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return func(m *Metrics) { m.GPUProcesses = procs }, nil
+}
+
+func collectAgent(context.Context) (fields, error) {
+	s := getAgentStats()
+	return func(m *Metrics) { m.Agent = &s }, nil
+}
@@ -0,0 +1,125 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"slices"
+	"time"
+)
+
+// Command is an instruction from the server, delivered in the reply to a
+// metrics report: {"commands": [{"id": "...", "type": "snapshot"}, ...]}.
+type Command struct {
+	ID                 string `json:"id"`
+	Type               string `json:"type"`
+	SendIntervalSec    int    `json:"sendIntervalSec,omitempty"`
+	CollectIntervalSec int    `json:"collectIntervalSec,omitempty"`
+	Collector          string `json:"collector,omitempty"`
+	Process            string `json:"process,omitempty"`
+	Profile            string `json:"profile,omitempty"`
+	Level              string `json:"level,omitempty"`
+}
+
+var commands = make(chan Command, 16)
+
+// reconnectPending tells the collection loop to drop its reporters' backoff
+// before the next report.
+var reconnectPending bool
+
+func queueCommands(reply []byte) {
+	if len(reply) == 0 {
+		return
+	}
+	var r struct {
+		Commands []Command `json:"commands"`
+	}
+	if err := json.Unmarshal(reply, &r); err != nil {
+		return
+	}
+	for _, c := range r.Commands {
+		select {
+		case commands <- c:
+		default:
+			slog.Warn("Command queue full, dropping command", "type", c.Type, "id", c.ID)
+		}
+	}
+}
+
+// runCommand applies c on the collection loop's goroutine, which picks up
+// interval changes after the current tick. It reports whether a report
+// should be sent right away.
+func runCommand(c Command) bool {
+	slog.Info("Command", "id", c.ID, "type", c.Type)
+
+	switch c.Type {
+	case "snapshot":
+		return true
+	case "reconnect":
+		resetTransport()
+		reconnectPending = true
+		return true
+	case "suspend":
+		resetTransport()
+		setStatus("Suspended")
+	case "inventory":
+		go sendInventory()
+	case "speedtest":
+		go runSpeedTest(c.ID)
+	case "setInterval":
+		if c.CollectIntervalSec > 0 {
+			collectInterval = min(max(time.Duration(c.CollectIntervalSec)*time.Second, minInterval), maxInterval)
+		}
+		if c.SendIntervalSec > 0 {
+			sendInterval = min(max(time.Duration(c.SendIntervalSec)*time.Second, minInterval), maxInterval)
+		}
+		sendInterval = max(sendInterval, collectInterval)
+		backoffMax = max(backoffMax, sendInterval)
+		slog.Info("Intervals changed", "collect", collectInterval, "send", sendInterval)
+		updateIntervalMenu()
+	case "pickInterval":
+		pickInterval(time.Duration(c.SendIntervalSec) * time.Second)
+	case "enable", "disable":
+		setCollector(c.Collector, c.Type == "enable")
+	case "kill", "restart":
+		go controlProcess(c)
+	case "reloadConfig":
+		reloadConfig()
+	case "newSecret":
+		applySecret()
+		return true
+	case "switchProfile":
+		switchProfile(c.Profile)
+	case "setLogLevel":
+		if setLogLevel(c.Level) {
+			slog.Info("Log level changed", "level", logLevel.Level())
+		}
+	default:
+		slog.Warn("Unknown command", "type", c.Type)
+	}
+	return false
+}
+
+// setCollector turns a collector on or off until the next start. The
+// off-by-default ones flip their own switch; the rest are overridden in
+// wants.
+func setCollector(name string, on bool) {
+	switch name {
+	case "docker":
+		docker.Store(on)
+	case "hyperv":
+		hyperV.Store(on)
+	case "gpu-processes":
+		gpuProcesses.Store(on)
+	case "foreground":
+		reportForeground.Store(on)
+		checkForegroundMenu(on)
+	default:
+		if !slices.Contains(collectorNames, name) {
+			slog.Warn("Unknown collector", "collector", name)
+			return
+		}
+	}
+	collectorOverrides.Lock()
+	defer collectorOverrides.Unlock()
+	collectorOverrides.on[name] = on
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/BurntSushi/toml"
+)
+
+// configAliases maps the friendlier config file keys to their variables.
+var configAliases = map[string]string{
+	"url":         "M_API_URL",
+	"secret":      "M_AGENT_SECRET",
+	"secret_file": "M_AGENT_SECRET_FILE",
+}
+
+// mapSettings are the variables holding key=value lists, written as tables
+// in the config file.
+var mapSettings = map[string]bool{"M_TAGS": true, "M_HEADERS": true, "M_OTLP_HEADERS": true, "M_PLUGINS": true, "M_COUNTERS": true, "M_DATA_CAPS": true}
+
+// fileConfig holds the settings from the config file keyed by variable
+// name, e.g. interval = "10s" or [mqtt] url = "..." for M_MQTT_URL.
+// Environment variables take precedence.
+//
+// profiles holds its [profiles.<name>] tables, flattened like the top level.
+// The active one overlays the top-level settings, so a profile only needs the
+// keys that differ, typically url, secret and tls.
+//
+// reloadConfig replaces both, and tags and collectors with them, while the
+// tray and the settings page read them, so they're only touched under
+// configMu.
+var (
+	configMu             sync.RWMutex
+	fileConfig, profiles = loadConfigFile(configFilePath())
+)
+
+func configFilePath() string {
+	if p := flagSettings["M_CONFIG"]; p != "" {
+		return p
+	}
+	if p := os.Getenv("M_CONFIG"); p != "" {
+		return p
+	}
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(dir, "go-win-monitor", "config.toml")
+}
+
+// updateConfigFile lets edit change the config file's keys and writes it
+// back, keeping every key edit leaves alone. Nothing is written when edit
+// fails.
+func updateConfigFile(edit func(cfg map[string]any) error) error {
+	path := configFilePath()
+	if path == "" {
+		return fmt.Errorf("no config directory")
+	}
+	cfg := map[string]any{}
+	if _, err := toml.DecodeFile(path, &cfg); err != nil && !os.IsNotExist(err) {
+		return fmt.Errorf("read %s: %w", path, err)
+	}
+	if err := edit(cfg); err != nil {
+		return err
+	}
+
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return err
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := toml.NewEncoder(f).Encode(cfg); err != nil {
+		f.Close()
+		return fmt.Errorf("write %s: %w", path, err)
+	}
+	return f.Close()
+}
+
+func loadConfigFile(path string) (map[string]string, map[string]map[string]string) {
+	if path == "" {
+		return nil, nil
+	}
+	var raw map[string]any
+	if _, err := toml.DecodeFile(path, &raw); err != nil {
+		if !os.IsNotExist(err) {
+			slog.Error("Config file unreadable", "path", path, "err", err)
+		}
+		return nil, nil
+	}
+
+	var profs map[string]map[string]string
+	if tables, ok := raw["profiles"].(map[string]any); ok {
+		delete(raw, "profiles")
+		profs = map[string]map[string]string{}
+		for name, t := range tables {
+			if t, ok := t.(map[string]any); ok {
+				profs[name] = map[string]string{}
+				flattenConfig(profs[name], "", t)
+			}
+		}
+	}
+
+	// [[alert_rules]] tables are parsed with the other rules; a plain
+	// alert_rules string is still M_ALERT_RULES.
+	fileAlertRules = nil
+	if tables, ok := raw["alert_rules"].([]map[string]any); ok {
+		delete(raw, "alert_rules")
+		fileAlertRules = tables
+	}
+	fileWMIQueries = nil
+	if tables, ok := raw["wmi_queries"].([]map[string]any); ok {
+		delete(raw, "wmi_queries")
+		fileWMIQueries = tables
+	}
+
+	settings := map[string]string{}
+	flattenConfig(settings, "", raw)
+	overlayProfile(settings, profs)
+	return settings, profs
+}
+
+func flattenConfig(settings map[string]string, prefix string, table map[string]any) {
+	for k, v := range table {
+		key := prefix + strings.ToUpper(strings.ReplaceAll(k, "-", "_"))
+		name := "M_" + key
+		if alias, ok := configAliases[k]; ok && prefix == "" {
+			name = alias
+		}
+
+		switch v := v.(type) {
+		case map[string]any:
+			if mapSettings[name] {
+				settings[name] = joinPairs(v)
+			} else {
+				flattenConfig(settings, key+"_", v)
+			}
+		case []any:
+			items := make([]string, len(v))
+			for i, item := range v {
+				items[i] = fmt.Sprint(item)
+			}
+			settings[name] = strings.Join(items, ",")
+		case bool:
+			settings[name] = "0"
+			if v {
+				settings[name] = "1"
+			}
+		default:
+			settings[name] = fmt.Sprint(v)
+		}
+	}
+}
+
+func joinPairs(m map[string]any) string {
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+fmt.Sprint(v))
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// lookupSetting returns a setting from the command line, the environment or
+// the config file, in that order.
+func lookupSetting(key string) string {
+	if v := flagSettings[key]; v != "" {
+		return v
+	}
+	if v := os.Getenv(key); v != "" {
+		return v
+	}
+	return fileSetting(key)
+}
+
+// fileSetting returns a setting from the config file alone.
+func fileSetting(key string) string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return fileConfig[key]
+}
+
+// currentTags returns the tags sent with every report.
+func currentTags() map[string]string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return tags
+}
+
+func loadSecret() string {
+	if s := getEnv("M_AGENT_SECRET", ""); s != "" {
+		return s
+	}
+	if path := getEnv("M_AGENT_SECRET_FILE", ""); path != "" {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			slog.Error("Secret file unreadable", "err", err)
+			return ""
+		}
+		return strings.TrimSpace(string(data))
+	}
+	s, err := readStoredSecret()
+	if err != nil {
+		slog.Warn("Stored secret unreadable", "err", err)
+	}
+	return s
+}
+
+const configPollInterval = 5 * time.Second
+
+// watchConfig asks the collection loop to reload the config file whenever
+// its modification time changes.
+func watchConfig(path string) {
+	var last time.Time
+	if info, err := os.Stat(path); err == nil {
+		last = info.ModTime()
+	}
+	for range time.Tick(configPollInterval) {
+		info, err := os.Stat(path)
+		if err != nil || info.ModTime().Equal(last) {
+			continue
+		}
+		last = info.ModTime()
+		queueReload()
+	}
+}
+
+func queueReload() {
+	queueLocal("reloadConfig")
+}
+
+// reloadConfig re-reads the config file and applies the settings that can
+// change without a restart: intervals, collectors, tags, alert rules and the
+// log level. It runs on the collection loop's goroutine, like every command.
+func reloadConfig() {
+	file, profs := loadConfigFile(configFilePath())
+	configMu.Lock()
+	fileConfig, profiles = file, profs
+	configMu.Unlock()
+
+	interval = getInterval("M_INTERVAL", 30*time.Second)
+	collectInterval = getInterval("M_COLLECT_INTERVAL", interval)
+	sendInterval = max(getInterval("M_SEND_INTERVAL", interval), collectInterval)
+	backoffMax = max(getInterval("M_BACKOFF_MAX", 5*time.Minute), sendInterval)
+
+	newCollectors, newTags := parseList(getEnv("M_COLLECTORS", "")), parseTags(getEnv("M_TAGS", ""))
+	configMu.Lock()
+	collectors, tags = newCollectors, newTags
+	configMu.Unlock()
+	collectorTimeout = getInterval("M_COLLECTOR_TIMEOUT", 5*time.Second)
+	setLogLevel(getEnv("M_LOG_LEVEL", "info"))
+	alerts.SetRules(configuredAlertRules())
+	dataUsage.configure(getInt("M_BILLING_DAY", 1), parseDataCaps(getEnv("M_DATA_CAPS", "")))
+	scripts = loadScripts(scriptDir())
+	setWMIQueries(parseWMITables(fileWMIQueries))
+	loadOptInCollectors()
+
+	slog.Info("Config reloaded", "collect", collectInterval, "send", sendInterval)
+	updateIntervalMenu()
+}
@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+// attachConsole is a Windows concern: a GUI-subsystem build has no console
+// of its own, while here stderr is always the terminal or the journal.
+func attachConsole() {}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+var procAttachConsole = kernel32.NewProc("AttachConsole")
+
+const attachParentProcess = 0xFFFFFFFF // ATTACH_PARENT_PROCESS, (DWORD)-1
+
+// attachConsole sends stdout and stderr to the console of the prompt that
+// started the agent. The executable is built as a GUI program, so with
+// --no-tray its log would otherwise go nowhere; output that is already
+// redirected, as under a scheduled task or a CI runner, is left alone.
+func attachConsole() {
+	if h, err := windows.GetStdHandle(windows.STD_ERROR_HANDLE); err == nil && h != 0 && h != windows.InvalidHandle {
+		return
+	}
+	if r, _, _ := procAttachConsole.Call(attachParentProcess); r == 0 {
+		return
+	}
+	f, err := os.OpenFile("CONOUT$", os.O_WRONLY, 0)
+	if err != nil {
+		return
+	}
+	os.Stdout, os.Stderr = f, f
+}
@@ -0,0 +1,21 @@
+package main
+
+import "context"
+
+// M_COUNTERS lists Windows performance counters as name=path pairs, written
+// as a [counters] table in the config file, e.g.
+// disk_queue = '\PhysicalDisk(_Total)\Avg. Disk Queue Length'. Paths are
+// the English ones typeperf and Performance Monitor show, whatever the
+// display language; localized paths work too.
+var perfCounterPaths = parseTags(getEnv("M_COUNTERS", ""))
+
+func collectCounters(context.Context) (fields, error) {
+	if len(perfCounterPaths) == 0 {
+		return nil, nil
+	}
+	values, err := readPerfCounters()
+	if len(values) == 0 {
+		return nil, err
+	}
+	return func(m *Metrics) { m.Counters = values }, err
+}
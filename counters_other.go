@@ -0,0 +1,10 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// readPerfCounters reads PDH counters, which only Windows has.
+func readPerfCounters() (map[string]float64, error) {
+	return nil, errors.New("performance counters are only available on Windows")
+}
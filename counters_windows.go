@@ -0,0 +1,100 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	pdh                             = windows.NewLazySystemDLL("pdh.dll")
+	procPdhOpenQuery                = pdh.NewProc("PdhOpenQueryW")
+	procPdhAddEnglishCounter        = pdh.NewProc("PdhAddEnglishCounterW")
+	procPdhAddCounter               = pdh.NewProc("PdhAddCounterW")
+	procPdhCollectQueryData         = pdh.NewProc("PdhCollectQueryData")
+	procPdhGetFormattedCounterValue = pdh.NewProc("PdhGetFormattedCounterValue")
+)
+
+const (
+	pdhFmtDouble   = 0x00000200
+	pdhFmtNoCap100 = 0x00008000
+
+	pdhCStatusNewData = 1
+)
+
+// pdhFmtCounterValue is PDH_FMT_COUNTERVALUE read as a double; the union
+// is 8-byte aligned on both architectures.
+type pdhFmtCounterValue struct {
+	CStatus uint32
+	_       uint32
+	Value   float64
+}
+
+// perfCounters is one PDH query holding every M_COUNTERS entry, opened on
+// the first collect. Rate counters such as % Processor Time are computed
+// between two collects, so they report from the second tick on.
+var perfCounters struct {
+	sync.Mutex
+	query    uintptr
+	counters map[string]uintptr
+}
+
+func readPerfCounters() (map[string]float64, error) {
+	perfCounters.Lock()
+	defer perfCounters.Unlock()
+	if perfCounters.counters == nil {
+		if err := openPerfCounters(); err != nil {
+			return nil, err
+		}
+	}
+	if len(perfCounters.counters) == 0 {
+		return nil, nil
+	}
+	if r, _, _ := procPdhCollectQueryData.Call(perfCounters.query); r != 0 {
+		return nil, fmt.Errorf("PdhCollectQueryData: status 0x%08X", r)
+	}
+	values := make(map[string]float64, len(perfCounters.counters))
+	for name, c := range perfCounters.counters {
+		var v pdhFmtCounterValue
+		r, _, _ := procPdhGetFormattedCounterValue.Call(c, pdhFmtDouble|pdhFmtNoCap100, 0, uintptr(unsafe.Pointer(&v)))
+		if r == 0 && v.CStatus <= pdhCStatusNewData {
+			values[name] = v.Value
+		}
+	}
+	return values, nil
+}
+
+// openPerfCounters adds each path, in English first and then as a localized
+// path. One that exists on neither is logged and left out until a restart.
+func openPerfCounters() error {
+	var query uintptr
+	if r, _, _ := procPdhOpenQuery.Call(0, 0, uintptr(unsafe.Pointer(&query))); r != 0 {
+		return fmt.Errorf("PdhOpenQuery: status 0x%08X", r)
+	}
+	counters := map[string]uintptr{}
+	for _, name := range slices.Sorted(maps.Keys(perfCounterPaths)) {
+		path, err := windows.UTF16PtrFromString(perfCounterPaths[name])
+		if err != nil {
+			continue
+		}
+		var c uintptr
+		r, _, _ := procPdhAddEnglishCounter.Call(query, uintptr(unsafe.Pointer(path)), 0, uintptr(unsafe.Pointer(&c)))
+		if r != 0 {
+			r, _, _ = procPdhAddCounter.Call(query, uintptr(unsafe.Pointer(path)), 0, uintptr(unsafe.Pointer(&c)))
+		}
+		if r != 0 {
+			slog.Warn("Performance counter not found", "counter", name, "path", perfCounterPaths[name], "status", fmt.Sprintf("0x%08X", r))
+			continue
+		}
+		counters[name] = c
+	}
+	// The first collect gives rate counters their starting point.
+	procPdhCollectQueryData.Call(query)
+	perfCounters.query, perfCounters.counters = query, counters
+	return nil
+}
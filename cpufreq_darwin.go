@@ -0,0 +1,7 @@
+package main
+
+// getCPUFrequency is not available on macOS, which does not expose the
+// current clock without root.
+func getCPUFrequency() (uint32, uint32, bool) {
+	return 0, 0, false
+}
@@ -0,0 +1,23 @@
+package main
+
+import "path/filepath"
+
+// getCPUFrequency averages the current clock of every core from cpufreq.
+// The base clock is only known with drivers that expose base_frequency,
+// such as intel_pstate; it is 0 otherwise, which also keeps the throttling
+// heuristic off.
+func getCPUFrequency() (uint32, uint32, bool) {
+	files, _ := filepath.Glob("/sys/devices/system/cpu/cpu[0-9]*/cpufreq/scaling_cur_freq")
+	var sum, n int64
+	for _, f := range files {
+		if khz := readSysfsInt(f); khz > 0 {
+			sum += khz
+			n++
+		}
+	}
+	if n == 0 {
+		return 0, 0, false
+	}
+	base := max(readSysfsInt("/sys/devices/system/cpu/cpu0/cpufreq/base_frequency"), 0)
+	return uint32(sum / n / 1000), uint32(base / 1000), true
+}
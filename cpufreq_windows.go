@@ -0,0 +1,23 @@
+package main
+
+import "github.com/yusufpapurcu/wmi"
+
+type processorInformation struct {
+	ProcessorFrequency          uint32
+	PercentProcessorPerformance uint32
+}
+
+func getCPUFrequency() (uint32, uint32, bool) {
+	var dst []processorInformation
+	err := wmi.Query(
+		"SELECT ProcessorFrequency, PercentProcessorPerformance FROM Win32_PerfFormattedData_Counters_ProcessorInformation WHERE Name = '_Total'",
+		&dst,
+	)
+	if err != nil || len(dst) == 0 || dst[0].ProcessorFrequency == 0 {
+		return 0, 0, false
+	}
+
+	base := dst[0].ProcessorFrequency
+	current := uint32(uint64(base) * uint64(dst[0].PercentProcessorPerformance) / 100)
+	return current, base, true
+}
@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"os"
+)
+
+// readStoredSecret has no Credential Manager to read from; the secret comes
+// from M_AGENT_SECRET or M_AGENT_SECRET_FILE.
+func readStoredSecret() (string, error) {
+	return "", nil
+}
+
+func writeStoredSecret(string) error {
+	return errors.New("there is no credential store on this platform, use M_AGENT_SECRET_FILE")
+}
+
+func runSetSecret() {
+	fmt.Fprintln(os.Stderr, "--set-secret needs Windows Credential Manager; use M_AGENT_SECRET_FILE instead")
+	os.Exit(1)
+}
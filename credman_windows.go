@@ -0,0 +1,103 @@
+package main
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"strings"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	procCredReadW   = advapi32.NewProc("CredReadW")
+	procCredWriteW  = advapi32.NewProc("CredWriteW")
+	procCredDeleteW = advapi32.NewProc("CredDeleteW")
+	procCredFree    = advapi32.NewProc("CredFree")
+)
+
+const (
+	credTypeGeneric         = 1
+	credPersistLocalMachine = 2
+
+	// credentialTarget is the name shown in Credential Manager under
+	// Windows Credentials > Generic Credentials.
+	credentialTarget = "go-win-monitor"
+)
+
+type credential struct {
+	Flags              uint32
+	Type               uint32
+	TargetName         *uint16
+	Comment            *uint16
+	LastWritten        windows.Filetime
+	CredentialBlobSize uint32
+	CredentialBlob     *byte
+	Persist            uint32
+	AttributeCount     uint32
+	Attributes         uintptr
+	TargetAlias        *uint16
+	UserName           *uint16
+}
+
+// readStoredSecret returns the secret saved with --set-secret, if any. The
+// blob is protected by Credential Manager with the user's DPAPI keys.
+func readStoredSecret() (string, error) {
+	target, _ := windows.UTF16PtrFromString(credentialTarget)
+	var c *credential
+	r, _, err := procCredReadW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0, uintptr(unsafe.Pointer(&c)))
+	if r == 0 {
+		if err == windows.ERROR_NOT_FOUND {
+			return "", nil
+		}
+		return "", fmt.Errorf("CredReadW: %w", err)
+	}
+	defer procCredFree.Call(uintptr(unsafe.Pointer(c)))
+
+	return string(unsafe.Slice(c.CredentialBlob, c.CredentialBlobSize)), nil
+}
+
+func writeStoredSecret(secret string) error {
+	target, _ := windows.UTF16PtrFromString(credentialTarget)
+	if secret == "" {
+		r, _, err := procCredDeleteW.Call(uintptr(unsafe.Pointer(target)), credTypeGeneric, 0)
+		if r == 0 && err != windows.ERROR_NOT_FOUND {
+			return fmt.Errorf("CredDeleteW: %w", err)
+		}
+		return nil
+	}
+
+	blob := []byte(secret)
+	user, _ := windows.UTF16PtrFromString("agent")
+	c := credential{
+		Type:               credTypeGeneric,
+		TargetName:         target,
+		CredentialBlobSize: uint32(len(blob)),
+		CredentialBlob:     &blob[0],
+		Persist:            credPersistLocalMachine,
+		UserName:           user,
+	}
+	r, _, err := procCredWriteW.Call(uintptr(unsafe.Pointer(&c)), 0)
+	if r == 0 {
+		return fmt.Errorf("CredWriteW: %w", err)
+	}
+	return nil
+}
+
+// runSetSecret implements --set-secret: the first line on stdin replaces the
+// stored secret, an empty line removes it.
+func runSetSecret() {
+	fmt.Fprint(os.Stderr, "Agent secret (empty to remove): ")
+	line, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil && line == "" {
+		fmt.Fprintf(os.Stderr, "read secret: %v\n", err)
+		os.Exit(1)
+	}
+	if err := writeStoredSecret(strings.TrimSpace(line)); err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	fmt.Fprintln(os.Stderr, "Saved to Windows Credential Manager as "+credentialTarget)
+	os.Exit(0)
+}
@@ -0,0 +1,96 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/gorilla/websocket"
+)
+
+//go:embed web/index.html
+var dashboardHTML []byte
+
+// liveFeed fans each new sample out to the dashboard's WebSocket clients.
+// A client that falls behind misses samples rather than blocking the loop.
+var liveFeed struct {
+	sync.Mutex
+	clients map[chan Metrics]struct{}
+}
+
+func broadcastMetrics(m Metrics) {
+	liveFeed.Lock()
+	defer liveFeed.Unlock()
+	for ch := range liveFeed.clients {
+		select {
+		case ch <- m:
+		default:
+		}
+	}
+}
+
+func subscribeMetrics() (chan Metrics, func()) {
+	ch := make(chan Metrics, 8)
+	liveFeed.Lock()
+	if liveFeed.clients == nil {
+		liveFeed.clients = map[chan Metrics]struct{}{}
+	}
+	liveFeed.clients[ch] = struct{}{}
+	liveFeed.Unlock()
+
+	return ch, func() {
+		liveFeed.Lock()
+		delete(liveFeed.clients, ch)
+		liveFeed.Unlock()
+	}
+}
+
+var upgrader = websocket.Upgrader{}
+
+func handleDashboard(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(dashboardHTML)
+}
+
+func handleLiveFeed(w http.ResponseWriter, r *http.Request) {
+	conn, err := upgrader.Upgrade(w, r, nil)
+	if err != nil {
+		return
+	}
+	defer conn.Close()
+
+	ch, unsubscribe := subscribeMetrics()
+	defer unsubscribe()
+
+	// Reads only serve to notice the browser going away.
+	closed := make(chan struct{})
+	go func() {
+		defer close(closed)
+		for {
+			if _, _, err := conn.NextReader(); err != nil {
+				return
+			}
+		}
+	}()
+
+	for {
+		select {
+		case m := <-ch:
+			conn.SetWriteDeadline(time.Now().Add(10 * time.Second))
+			if err := conn.WriteJSON(m); err != nil {
+				return
+			}
+		case <-agentCtx.Done():
+			msg := websocket.FormatCloseMessage(websocket.CloseGoingAway, "agent stopping")
+			conn.WriteControl(websocket.CloseMessage, msg, time.Now().Add(time.Second))
+			return
+		case <-closed:
+			return
+		}
+	}
+}
+
+func openDashboard() {
+	openBrowser("http://" + localAPIAddr + "/")
+}
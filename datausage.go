@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+// Data usage adds up the bytes each interface moves over a billing period,
+// kept in data-usage.json in the data directory so restarts do not lose
+// it. M_BILLING_DAY is the day of the month the period starts (1-28).
+// M_DATA_CAPS, a [data_caps] table in the config file, gives interfaces a
+// cap in GB, e.g. Cellular = 20, or * = 50 for every other one; the
+// data-cap alert fires once an interface has used M_DATA_CAP_ALERT percent
+// of it.
+var (
+	dataUsageEnabled = getEnv("M_DATA_USAGE", "1") == "1"
+	dataUsage        = newDataUsageTracker(filepath.Join(defaultDataDir(), "data-usage.json"))
+)
+
+const dataUsageSaveEvery = time.Minute
+
+// InterfaceUsage is one interface's traffic since the period started.
+// CapBytes is only set for an interface with a cap, and CapPercent is how
+// much of it is used.
+type InterfaceUsage struct {
+	Name       string  `json:"name"`
+	SentBytes  uint64  `json:"sentBytes"`
+	RecvBytes  uint64  `json:"recvBytes"`
+	CapBytes   uint64  `json:"capBytes,omitempty"`
+	CapPercent float64 `json:"capPercent,omitempty"`
+}
+
+// DataUsage is the running total of the current billing period.
+type DataUsage struct {
+	PeriodStart string           `json:"periodStart"`
+	Interfaces  []InterfaceUsage `json:"interfaces"`
+}
+
+type interfaceTotals struct {
+	Sent uint64 `json:"sent"`
+	Recv uint64 `json:"recv"`
+}
+
+// dataUsageTracker is fed by the network collector. The config reload
+// sets its billing day and caps from the collection loop, hence the lock.
+type dataUsageTracker struct {
+	mu         sync.Mutex
+	path       string
+	billingDay int
+	caps       map[string]float64 // GB by interface name, "*" for the rest
+	period     time.Time
+	totals     map[string]*interfaceTotals
+	last       map[string]net.IOCountersStat
+	saved      time.Time
+}
+
+// dataUsageState is what data-usage.json holds.
+type dataUsageState struct {
+	PeriodStart string                      `json:"periodStart"`
+	Interfaces  map[string]*interfaceTotals `json:"interfaces"`
+}
+
+func newDataUsageTracker(path string) *dataUsageTracker {
+	t := &dataUsageTracker{path: path, totals: map[string]*interfaceTotals{}, last: map[string]net.IOCountersStat{}}
+	t.configure(getInt("M_BILLING_DAY", 1), parseDataCaps(getEnv("M_DATA_CAPS", "")))
+
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return t
+	}
+	var s dataUsageState
+	if err := json.Unmarshal(data, &s); err != nil {
+		slog.Warn("Data usage file unreadable, starting over", "path", path, "err", err)
+		return t
+	}
+	if p, err := time.ParseInLocation(time.DateOnly, s.PeriodStart, time.Local); err == nil && p.Equal(t.period) {
+		for name, v := range s.Interfaces {
+			if v != nil {
+				t.totals[name] = v
+			}
+		}
+	}
+	return t
+}
+
+func parseDataCaps(s string) map[string]float64 {
+	caps := map[string]float64{}
+	for name, v := range parseTags(s) {
+		gbs, err := strconv.ParseFloat(v, 64)
+		if err != nil || gbs <= 0 {
+			slog.Warn("Invalid M_DATA_CAPS entry, expected a number of GB", "interface", name, "cap", v)
+			continue
+		}
+		caps[name] = gbs
+	}
+	return caps
+}
+
+// configure sets the billing day and caps, starting a new period when the
+// day moved it.
+func (t *dataUsageTracker) configure(billingDay int, caps map[string]float64) {
+	if billingDay < 1 || billingDay > 28 {
+		slog.Warn("M_BILLING_DAY must be between 1 and 28, using 1", "day", billingDay)
+		billingDay = 1
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.billingDay, t.caps = billingDay, caps
+	t.rollPeriod(time.Now())
+}
+
+// rollPeriod resets the totals once now is past the current period.
+func (t *dataUsageTracker) rollPeriod(now time.Time) {
+	p := billingPeriodStart(now, t.billingDay)
+	if p.Equal(t.period) {
+		return
+	}
+	if !t.period.IsZero() {
+		slog.Info("Data usage period started", "from", p.Format(time.DateOnly))
+		clear(t.totals)
+	}
+	t.period = p
+}
+
+// billingPeriodStart is the last midnight on the billing day at or before
+// now.
+func billingPeriodStart(now time.Time, day int) time.Time {
+	y, m, d := now.Date()
+	if d < day {
+		m--
+	}
+	return time.Date(y, m, day, 0, 0, 0, 0, now.Location())
+}
+
+// Add counts the bytes moved since the previous counters. The first call
+// after a start only records them, so what moved while the agent was not
+// running is not counted.
+func (t *dataUsageTracker) Add(counters []net.IOCountersStat) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	now := time.Now()
+	t.rollPeriod(now)
+	for _, c := range counters {
+		prev, ok := t.last[c.Name]
+		t.last[c.Name] = c
+		if !ok || c.BytesRecv+c.BytesSent == 0 {
+			continue
+		}
+		tot := t.totals[c.Name]
+		if tot == nil {
+			tot = &interfaceTotals{}
+			t.totals[c.Name] = tot
+		}
+		tot.Sent += delta(c.BytesSent, prev.BytesSent)
+		tot.Recv += delta(c.BytesRecv, prev.BytesRecv)
+	}
+	if now.Sub(t.saved) >= dataUsageSaveEvery {
+		t.saved = now
+		if err := t.save(); err != nil {
+			slog.Warn("Save data usage failed", "err", err)
+		}
+	}
+}
+
+// Save writes the totals out, for shutdown.
+func (t *dataUsageTracker) Save() error {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.save()
+}
+
+func (t *dataUsageTracker) save() error {
+	data, err := json.Marshal(dataUsageState{PeriodStart: t.period.Format(time.DateOnly), Interfaces: t.totals})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(t.path), 0o700); err != nil {
+		return err
+	}
+	tmp := t.path + ".tmp"
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write data usage: %w", err)
+	}
+	return os.Rename(tmp, t.path)
+}
+
+// Usage returns the totals sorted by interface name.
+func (t *dataUsageTracker) Usage() *DataUsage {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	if len(t.totals) == 0 {
+		return nil
+	}
+	u := &DataUsage{PeriodStart: t.period.Format(time.DateOnly)}
+	for name, tot := range t.totals {
+		iu := InterfaceUsage{Name: name, SentBytes: tot.Sent, RecvBytes: tot.Recv}
+		capGB, ok := t.caps[name]
+		if !ok {
+			capGB, ok = t.caps["*"]
+		}
+		if ok {
+			iu.CapBytes = uint64(capGB * gb)
+			iu.CapPercent = float64(tot.Sent+tot.Recv) / float64(iu.CapBytes) * 100
+		}
+		u.Interfaces = append(u.Interfaces, iu)
+	}
+	sort.Slice(u.Interfaces, func(i, j int) bool { return u.Interfaces[i].Name < u.Interfaces[j].Name })
+	return u
+}
+
+// dataCapRules builds the data-cap rule when any interface has a cap.
+func dataCapRules() []alertRule {
+	if len(parseTags(getEnv("M_DATA_CAPS", ""))) == 0 {
+		return nil
+	}
+	return []alertRule{{
+		Name: "data-cap", Kind: "data-cap", Metric: "data_usage_cap_percent", Op: ">=",
+		Threshold: float64(getInt("M_DATA_CAP_ALERT", 90)), Hysteresis: 1, Cooldown: 24 * time.Hour,
+	}}
+}
+
+// describeDataCap words data-cap alerts around the interface.
+func describeDataCap(r alertRule, ev AlertEvent) string {
+	iface := ev.Labels["interface"]
+	if ev.State == "resolved" {
+		return fmt.Sprintf("%s is back under %s%% of its data cap", iface, formatAlertValue(r.Threshold))
+	}
+	return fmt.Sprintf("%s has used %s%% of its data cap this period", iface, formatAlertValue(ev.Value))
+}
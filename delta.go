@@ -0,0 +1,88 @@
+package main
+
+import (
+	"encoding/json"
+	"math"
+	"reflect"
+	"time"
+)
+
+// alwaysSent are the fields every delta keeps, so a message stands on its own.
+var alwaysSent = []string{"timestamp", "machineId", "hostname", "tags", "runId", "seq"}
+
+// deltaEncoder trims a report down to the fields that changed since the
+// last one the server acknowledged. Numbers count as changed when they move
+// by more than deltaThreshold percent; everything else on any change.
+type deltaEncoder struct {
+	base     map[string]any
+	lastFull time.Time
+}
+
+var deltas deltaEncoder
+
+func toFields(m Metrics) map[string]any {
+	b, _ := json.Marshal(m)
+	var f map[string]any
+	json.Unmarshal(b, &f)
+	return f
+}
+
+// Encode returns the payload for m and whether it is a full snapshot.
+func (d *deltaEncoder) Encode(m Metrics) (map[string]any, bool) {
+	cur := toFields(m)
+	if d.base == nil || time.Since(d.lastFull) >= deltaFullEvery {
+		cur["delta"] = false
+		return cur, true
+	}
+
+	out := map[string]any{"delta": true}
+	for _, k := range alwaysSent {
+		if v, ok := cur[k]; ok {
+			out[k] = v
+		}
+	}
+	for k, v := range cur {
+		if changed(d.base[k], v) {
+			out[k] = v
+		}
+	}
+	// Fields that disappeared (e.g. an omitempty list going empty) are sent
+	// as null so the server can clear them.
+	for k := range d.base {
+		if _, ok := cur[k]; !ok {
+			out[k] = nil
+		}
+	}
+	return out, false
+}
+
+// Commit records m as what the server now has. Only fields that were part
+// of the sent payload move the base, so slow drifts still cross the
+// threshold eventually.
+func (d *deltaEncoder) Commit(m Metrics, sent map[string]any, full bool) {
+	cur := toFields(m)
+	if full || d.base == nil {
+		d.base = cur
+		d.lastFull = time.Now()
+		return
+	}
+	for k := range sent {
+		if v, ok := cur[k]; ok {
+			d.base[k] = v
+		} else {
+			delete(d.base, k)
+		}
+	}
+}
+
+func changed(old, cur any) bool {
+	a, okA := old.(float64)
+	b, okB := cur.(float64)
+	if okA && okB {
+		if a == b {
+			return false
+		}
+		return math.Abs(b-a) > deltaThreshold/100*math.Max(math.Abs(a), 1)
+	}
+	return !reflect.DeepEqual(old, cur)
+}
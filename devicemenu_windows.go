@@ -0,0 +1,94 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/getlantern/systray"
+)
+
+// deviceMenu is a tray submenu with one disabled line per device, e.g.
+// Fans ▸ CPU, GPU. Devices that disappear are hidden until they return.
+type deviceMenu struct {
+	root  *systray.MenuItem
+	items map[string]*systray.MenuItem
+}
+
+type deviceLine struct {
+	name, title string
+}
+
+func newDeviceMenu(title string) *deviceMenu {
+	root := systray.AddMenuItem(title, "")
+	root.Hide()
+	return &deviceMenu{root: root, items: map[string]*systray.MenuItem{}}
+}
+
+func (d *deviceMenu) Update(lines []deviceLine) {
+	if len(lines) == 0 {
+		d.root.Hide()
+		return
+	}
+	d.root.Show()
+
+	seen := map[string]bool{}
+	for _, l := range lines {
+		item, ok := d.items[l.name]
+		if !ok {
+			item = d.root.AddSubMenuItem(l.title, "")
+			item.Disable()
+			d.items[l.name] = item
+		}
+		item.SetTitle(l.title)
+		item.Show()
+		seen[l.name] = true
+	}
+	for name, item := range d.items {
+		if !seen[name] {
+			item.Hide()
+		}
+	}
+}
+
+func fanLines(fans []FanReading) []deviceLine {
+	lines := make([]deviceLine, len(fans))
+	for i, f := range fans {
+		title := fmt.Sprintf("%s: %.0f%%", f.Name, f.Percent)
+		if f.RPM >= 0 {
+			title = fmt.Sprintf("%s: %.0f RPM", f.Name, f.RPM)
+		}
+		lines[i] = deviceLine{f.Name, title}
+	}
+	return lines
+}
+
+func diskTempLines(temps []DiskTemperature) []deviceLine {
+	lines := make([]deviceLine, len(temps))
+	for i, t := range temps {
+		lines[i] = deviceLine{t.Name, fmt.Sprintf("%s: %d °C", t.Name, t.TempC)}
+	}
+	return lines
+}
+
+func interfaceLines(ifaces []InterfaceErrors) []deviceLine {
+	lines := make([]deviceLine, len(ifaces))
+	for i, n := range ifaces {
+		lines[i] = deviceLine{n.Name, tr("%s: %d errors, %d drops", n.Name, n.ErrIn+n.ErrOut, n.DropIn+n.DropOut)}
+	}
+	return lines
+}
+
+func containerLines(containers []ContainerStats) []deviceLine {
+	lines := make([]deviceLine, len(containers))
+	for i, c := range containers {
+		lines[i] = deviceLine{c.Name, fmt.Sprintf("%s: %.1f%% CPU, %d MB", c.Name, c.CPU, c.MemMB)}
+	}
+	return lines
+}
+
+func vmLines(vms []VMStats) []deviceLine {
+	lines := make([]deviceLine, len(vms))
+	for i, v := range vms {
+		lines[i] = deviceLine{v.Name, fmt.Sprintf("%s: %d%% CPU, %d MB", v.Name, v.CPU, v.MemMB)}
+	}
+	return lines
+}
@@ -0,0 +1,140 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+	"time"
+)
+
+const dockerPipe = `\\.\pipe\docker_engine`
+
+type ContainerStats struct {
+	Name   string  `json:"name"`
+	Image  string  `json:"image"`
+	CPU    float64 `json:"cpu"`
+	MemMB  uint64  `json:"memMb"`
+	MemMax uint64  `json:"memLimitMb"`
+}
+
+type dockerContainer struct {
+	ID    string   `json:"Id"`
+	Names []string `json:"Names"`
+	Image string   `json:"Image"`
+}
+
+type dockerCPUStats struct {
+	CPUUsage struct {
+		TotalUsage  uint64   `json:"total_usage"`
+		PercpuUsage []uint64 `json:"percpu_usage"`
+	} `json:"cpu_usage"`
+	SystemUsage uint64 `json:"system_cpu_usage"`
+	OnlineCPUs  uint64 `json:"online_cpus"`
+}
+
+type dockerStats struct {
+	CPUStats    dockerCPUStats `json:"cpu_stats"`
+	PreCPUStats dockerCPUStats `json:"precpu_stats"`
+	MemoryStats struct {
+		Usage uint64            `json:"usage"`
+		Limit uint64            `json:"limit"`
+		Stats map[string]uint64 `json:"stats"`
+	} `json:"memory_stats"`
+}
+
+// pipeConn adapts the Docker Desktop named pipe to net.Conn. Deadlines are not
+// supported; the HTTP client timeout bounds each request instead.
+type pipeConn struct {
+	*os.File
+}
+
+func (pipeConn) LocalAddr() net.Addr                { return pipeAddr{} }
+func (pipeConn) RemoteAddr() net.Addr               { return pipeAddr{} }
+func (pipeConn) SetDeadline(t time.Time) error      { return nil }
+func (pipeConn) SetReadDeadline(t time.Time) error  { return nil }
+func (pipeConn) SetWriteDeadline(t time.Time) error { return nil }
+
+type pipeAddr struct{}
+
+func (pipeAddr) Network() string { return "pipe" }
+func (pipeAddr) String() string  { return dockerPipe }
+
+var dockerClient = &http.Client{
+	Timeout: 10 * time.Second,
+	Transport: &http.Transport{
+		DialContext: func(ctx context.Context, network, addr string) (net.Conn, error) {
+			f, err := os.OpenFile(dockerPipe, os.O_RDWR, 0)
+			if err != nil {
+				return nil, err
+			}
+			return pipeConn{f}, nil
+		},
+		DisableKeepAlives: true,
+	},
+}
+
+func dockerGet(path string, v any) error {
+	resp, err := dockerClient.Get("http://docker" + path)
+	if err != nil {
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{Code: resp.StatusCode}
+	}
+	return json.NewDecoder(resp.Body).Decode(v)
+}
+
+func getContainerStats() ([]ContainerStats, bool) {
+	var containers []dockerContainer
+	if err := dockerGet("/containers/json", &containers); err != nil {
+		return nil, false
+	}
+
+	results := make([]ContainerStats, len(containers))
+	var wg sync.WaitGroup
+	for i, c := range containers {
+		wg.Add(1)
+		go func(i int, c dockerContainer) {
+			defer wg.Done()
+			s := ContainerStats{Image: c.Image}
+			if len(c.Names) > 0 {
+				s.Name = strings.TrimPrefix(c.Names[0], "/")
+			}
+
+			var st dockerStats
+			if err := dockerGet("/containers/"+c.ID+"/stats?stream=false", &st); err == nil {
+				s.CPU = containerCPUPercent(st)
+				used := st.MemoryStats.Usage - min(st.MemoryStats.Usage, st.MemoryStats.Stats["inactive_file"])
+				s.MemMB = used / 1024 / 1024
+				s.MemMax = st.MemoryStats.Limit / 1024 / 1024
+			}
+			results[i] = s
+		}(i, c)
+	}
+	wg.Wait()
+
+	return results, true
+}
+
+// containerCPUPercent is computed like docker stats does: the share of the
+// host's CPU time, scaled by its CPUs, so a container busy on two cores
+// reads 200. Older engines only report the per-CPU usage.
+func containerCPUPercent(st dockerStats) float64 {
+	cpuDelta := float64(st.CPUStats.CPUUsage.TotalUsage) - float64(st.PreCPUStats.CPUUsage.TotalUsage)
+	sysDelta := float64(st.CPUStats.SystemUsage) - float64(st.PreCPUStats.SystemUsage)
+	if cpuDelta <= 0 || sysDelta <= 0 {
+		return 0
+	}
+	cpus := float64(st.CPUStats.OnlineCPUs)
+	if cpus == 0 {
+		cpus = float64(max(len(st.CPUStats.CPUUsage.PercpuUsage), 1))
+	}
+	return cpuDelta / sysDelta * cpus * 100
+}
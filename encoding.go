@@ -0,0 +1,57 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+
+	"github.com/vmihailenco/msgpack/v5"
+)
+
+const msgpackType = "application/msgpack"
+
+// msgpackHosts holds the hosts whose responses advertised MessagePack in
+// Accept-Post. Usually the hello response is the first one.
+var msgpackHosts sync.Map
+
+// msgpackDeclined holds the hosts whose hello response did not select
+// MessagePack; their Accept-Post is not taken as an offer.
+var msgpackDeclined sync.Map
+
+func noteAcceptPost(host, accept string) {
+	if payloadEncoding == "msgpack" && strings.Contains(accept, msgpackType) {
+		if _, declined := msgpackDeclined.Load(host); declined {
+			return
+		}
+		if _, seen := msgpackHosts.LoadOrStore(host, true); !seen {
+			slog.Info("Server accepts MessagePack, using it for metrics", "host", host)
+		}
+	}
+}
+
+// postMetrics sends m as MessagePack when the server advertised it, and as
+// JSON otherwise or if the server rejects the MessagePack body.
+func postMetrics(endpoint, token string, m Metrics) ([]byte, error) {
+	u, _ := url.Parse(endpoint)
+	if _, ok := msgpackHosts.Load(u.Host); !ok {
+		return postJSON(endpoint, token, m)
+	}
+
+	var buf bytes.Buffer
+	enc := msgpack.NewEncoder(&buf)
+	enc.SetCustomStructTag("json")
+	if err := enc.Encode(m); err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	resp, err := postBody(endpoint, token, msgpackType, buf.Bytes())
+	if isUnsupportedMedia(err) {
+		slog.Warn("Server rejected MessagePack, falling back to JSON", "host", u.Host)
+		msgpackHosts.Delete(u.Host)
+		return postJSON(endpoint, token, m)
+	}
+	return resp, err
+}
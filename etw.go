@@ -0,0 +1,8 @@
+package main
+
+type ProcessNetwork struct {
+	Name    string  `json:"name"`
+	PID     uint32  `json:"pid"`
+	SentBps float64 `json:"sentBps"`
+	RecvBps float64 `json:"recvBps"`
+}
@@ -0,0 +1,16 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+// Per-process network tracing uses the kernel's ETW network provider, which
+// has no portable counterpart.
+
+func startNetworkTrace() error {
+	return errors.New("ETW is only available on Windows")
+}
+
+func stopNetworkTrace() {}
+
+func getTopNetworkProcesses() []ProcessNetwork { return nil }
@@ -0,0 +1,271 @@
+package main
+
+import (
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type wnodeHeader struct {
+	BufferSize        uint32
+	ProviderId        uint32
+	HistoricalContext uint64
+	TimeStamp         int64
+	Guid              windows.GUID
+	ClientContext     uint32
+	Flags             uint32
+}
+
+type eventTraceProperties struct {
+	Wnode               wnodeHeader
+	BufferSize          uint32
+	MinimumBuffers      uint32
+	MaximumBuffers      uint32
+	MaximumFileSize     uint32
+	LogFileMode         uint32
+	FlushTimer          uint32
+	EnableFlags         uint32
+	AgeLimit            int32
+	NumberOfBuffers     uint32
+	FreeBuffers         uint32
+	EventsLost          uint32
+	BuffersWritten      uint32
+	LogBuffersLost      uint32
+	RealTimeBuffersLost uint32
+	LoggerThreadId      windows.Handle
+	LogFileNameOffset   uint32
+	LoggerNameOffset    uint32
+}
+
+type eventTraceLogfile struct {
+	LogFileName         *uint16
+	LoggerName          *uint16
+	CurrentTime         int64
+	BuffersRead         uint32
+	ProcessTraceMode    uint32
+	CurrentEvent        [88]byte
+	LogfileHeader       [280]byte
+	BufferCallback      uintptr
+	BufferSize          uint32
+	Filled              uint32
+	EventsLost          uint32
+	EventRecordCallback uintptr
+	IsKernelTrace       uint32
+	Context             uintptr
+}
+
+type eventRecord struct {
+	Size              uint16
+	HeaderType        uint16
+	Flags             uint16
+	EventProperty     uint16
+	ThreadId          uint32
+	ProcessId         uint32
+	TimeStamp         int64
+	ProviderId        windows.GUID
+	Id                uint16
+	Version           uint8
+	Channel           uint8
+	Level             uint8
+	Opcode            uint8
+	Task              uint16
+	Keyword           uint64
+	ProcessorTime     uint64
+	ActivityId        windows.GUID
+	BufferContext     uint32
+	ExtendedDataCount uint16
+	UserDataLength    uint16
+	ExtendedData      uintptr
+	UserData          unsafe.Pointer
+	UserContext       uintptr
+}
+
+const (
+	etwSessionName = "go-win-monitor-net"
+
+	wnodeFlagTracedGuid          = 0x00020000
+	eventTraceRealTimeMode       = 0x00000100
+	eventTraceControlStop        = 1
+	eventControlCodeEnable       = 1
+	traceLevelInformation        = 4
+	processTraceModeRealTime     = 0x00000100
+	processTraceModeEventRecord  = 0x10000000
+	invalidProcessTraceHandle    = ^uint64(0)
+	kernelNetworkKeywordIPv4IPv6 = 0x10 | 0x20
+	topNetworkProcesses          = 5
+)
+
+var kernelNetworkProvider = windows.GUID{
+	Data1: 0x7DD42A49, Data2: 0x5329, Data3: 0x4832,
+	Data4: [8]byte{0x8D, 0xFD, 0x43, 0xD9, 0x79, 0x15, 0x3A, 0x88},
+}
+
+var (
+	advapi32           = windows.NewLazySystemDLL("advapi32.dll")
+	procStartTraceW    = advapi32.NewProc("StartTraceW")
+	procControlTraceW  = advapi32.NewProc("ControlTraceW")
+	procEnableTraceEx2 = advapi32.NewProc("EnableTraceEx2")
+	procOpenTraceW     = advapi32.NewProc("OpenTraceW")
+	procProcessTrace   = advapi32.NewProc("ProcessTrace")
+	procCloseTrace     = advapi32.NewProc("CloseTrace")
+)
+
+type netCounter struct {
+	sent uint64
+	recv uint64
+}
+
+var netTrace struct {
+	sync.Mutex
+	running bool
+	since   time.Time
+	byPID   map[uint32]*netCounter
+}
+
+func newTraceProperties() *eventTraceProperties {
+	// The session name is copied into the space after the struct.
+	buf := make([]byte, unsafe.Sizeof(eventTraceProperties{})+1024)
+	props := (*eventTraceProperties)(unsafe.Pointer(&buf[0]))
+	props.Wnode.BufferSize = uint32(len(buf))
+	props.Wnode.Flags = wnodeFlagTracedGuid
+	props.Wnode.ClientContext = 1
+	props.LogFileMode = eventTraceRealTimeMode
+	props.LoggerNameOffset = uint32(unsafe.Sizeof(eventTraceProperties{}))
+	return props
+}
+
+func startNetworkTrace() error {
+	if unsafe.Sizeof(eventTraceLogfile{}) != 448 || unsafe.Sizeof(eventRecord{}) != 112 {
+		return fmt.Errorf("ETW is only supported on 64-bit builds")
+	}
+
+	name, _ := windows.UTF16PtrFromString(etwSessionName)
+	stopNetworkTrace()
+
+	props := newTraceProperties()
+	var session uint64
+	if r, _, _ := procStartTraceW.Call(uintptr(unsafe.Pointer(&session)), uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(props))); r != 0 {
+		return fmt.Errorf("start trace: %w", windows.Errno(r))
+	}
+
+	r, _, _ := procEnableTraceEx2.Call(
+		uintptr(session),
+		uintptr(unsafe.Pointer(&kernelNetworkProvider)),
+		eventControlCodeEnable,
+		traceLevelInformation,
+		kernelNetworkKeywordIPv4IPv6,
+		0,
+		0,
+		0,
+	)
+	if r != 0 {
+		stopNetworkTrace()
+		return fmt.Errorf("enable provider: %w", windows.Errno(r))
+	}
+
+	logfile := eventTraceLogfile{
+		LoggerName:          name,
+		ProcessTraceMode:    processTraceModeRealTime | processTraceModeEventRecord,
+		EventRecordCallback: windows.NewCallback(onNetworkEvent),
+	}
+	handle, _, _ := procOpenTraceW.Call(uintptr(unsafe.Pointer(&logfile)))
+	if uint64(handle) == invalidProcessTraceHandle {
+		stopNetworkTrace()
+		return fmt.Errorf("open trace failed")
+	}
+
+	netTrace.Lock()
+	netTrace.running = true
+	netTrace.since = time.Now()
+	netTrace.byPID = map[uint32]*netCounter{}
+	netTrace.Unlock()
+
+	go func() {
+		h := uint64(handle)
+		procProcessTrace.Call(uintptr(unsafe.Pointer(&h)), 1, 0, 0)
+		procCloseTrace.Call(handle)
+		netTrace.Lock()
+		netTrace.running = false
+		netTrace.Unlock()
+	}()
+	return nil
+}
+
+func stopNetworkTrace() {
+	name, _ := windows.UTF16PtrFromString(etwSessionName)
+	props := newTraceProperties()
+	procControlTraceW.Call(0, uintptr(unsafe.Pointer(name)), uintptr(unsafe.Pointer(props)), eventTraceControlStop)
+}
+
+// onNetworkEvent handles TCP/UDP send and receive events, whose payload starts
+// with the owning PID and the transfer size (both UInt32).
+func onNetworkEvent(rec *eventRecord) uintptr {
+	var sent bool
+	switch rec.Id {
+	case 10, 26, 42, 58:
+		sent = true
+	case 11, 27, 43, 59:
+	default:
+		return 0
+	}
+	if rec.UserDataLength < 8 || rec.UserData == nil {
+		return 0
+	}
+
+	data := unsafe.Slice((*byte)(rec.UserData), 8)
+	pid := uint32(data[0]) | uint32(data[1])<<8 | uint32(data[2])<<16 | uint32(data[3])<<24
+	size := uint64(data[4]) | uint64(data[5])<<8 | uint64(data[6])<<16 | uint64(data[7])<<24
+
+	netTrace.Lock()
+	c, ok := netTrace.byPID[pid]
+	if !ok {
+		c = &netCounter{}
+		netTrace.byPID[pid] = c
+	}
+	if sent {
+		c.sent += size
+	} else {
+		c.recv += size
+	}
+	netTrace.Unlock()
+	return 0
+}
+
+func getTopNetworkProcesses() []ProcessNetwork {
+	netTrace.Lock()
+	if !netTrace.running {
+		netTrace.Unlock()
+		return nil
+	}
+	counters := netTrace.byPID
+	elapsed := time.Since(netTrace.since).Seconds()
+	netTrace.byPID = map[uint32]*netCounter{}
+	netTrace.since = time.Now()
+	netTrace.Unlock()
+
+	if elapsed <= 0 || len(counters) == 0 {
+		return nil
+	}
+
+	names, _ := listProcesses()
+	top := make([]ProcessNetwork, 0, len(counters))
+	for pid, c := range counters {
+		top = append(top, ProcessNetwork{
+			Name:    names[pid],
+			PID:     pid,
+			SentBps: float64(c.sent) / elapsed,
+			RecvBps: float64(c.recv) / elapsed,
+		})
+	}
+	sort.Slice(top, func(i, j int) bool {
+		return top[i].SentBps+top[i].RecvBps > top[j].SentBps+top[j].RecvBps
+	})
+	if len(top) > topNetworkProcesses {
+		top = top[:topNetworkProcesses]
+	}
+	return top
+}
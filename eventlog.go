@@ -0,0 +1,15 @@
+package main
+
+type EventLogReport struct {
+	Events []EventLogEntry `json:"events"`
+}
+
+type EventLogEntry struct {
+	Channel  string `json:"channel"`
+	Source   string `json:"source"`
+	EventID  int    `json:"eventId"`
+	Level    string `json:"level"`
+	Time     string `json:"time"`
+	RecordID uint64 `json:"recordId"`
+	Message  string `json:"message"`
+}
@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "log/slog"
+
+// runEventLogCollector reads Windows event log channels; the journal and
+// the unified log are not read.
+func runEventLogCollector([]string) {
+	slog.Info("Event log collection is only available on Windows")
+}
@@ -0,0 +1,210 @@
+package main
+
+import (
+	"encoding/xml"
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type eventXML struct {
+	System struct {
+		Provider struct {
+			Name string `xml:"Name,attr"`
+		} `xml:"Provider"`
+		EventID     int `xml:"EventID"`
+		Level       int `xml:"Level"`
+		TimeCreated struct {
+			SystemTime string `xml:"SystemTime,attr"`
+		} `xml:"TimeCreated"`
+		EventRecordID uint64 `xml:"EventRecordID"`
+		Channel       string `xml:"Channel"`
+	} `xml:"System"`
+}
+
+const (
+	evtQueryChannelPath      = 0x1
+	evtQueryReverseDirection = 0x200
+	evtRenderEventXml        = 1
+	evtFormatMessageEvent    = 1
+	eventBatchSize           = 50
+)
+
+var (
+	wevtapi                      = windows.NewLazySystemDLL("wevtapi.dll")
+	procEvtQuery                 = wevtapi.NewProc("EvtQuery")
+	procEvtNext                  = wevtapi.NewProc("EvtNext")
+	procEvtRender                = wevtapi.NewProc("EvtRender")
+	procEvtClose                 = wevtapi.NewProc("EvtClose")
+	procEvtOpenPublisherMetadata = wevtapi.NewProc("EvtOpenPublisherMetadata")
+	procEvtFormatMessage         = wevtapi.NewProc("EvtFormatMessage")
+)
+
+// runEventLogCollector reports the errors logged after it started. A
+// channel is only polled once its newest record is known, retried every
+// minute, so a failed first query does not replay the channel's history.
+func runEventLogCollector(channels []string) {
+	last := map[string]uint64{}
+	seed := func(ch string) bool {
+		events, err := queryEvents(ch, "*", evtQueryReverseDirection, 1)
+		if err != nil {
+			slog.Warn("Event log query failed", "channel", ch, "err", err)
+			return false
+		}
+		last[ch] = 0
+		if len(events) > 0 {
+			last[ch] = events[0].RecordID
+		}
+		return true
+	}
+	for _, ch := range channels {
+		seed(ch)
+	}
+
+	for {
+		time.Sleep(time.Minute)
+		// Events are picked up from where the last query stopped once
+		// low-resource mode, a pause or quiet hours end, since publish would
+		// drop them meanwhile.
+		if lowResource.Load() || paused.Load() || inQuietHours(time.Now()) {
+			continue
+		}
+
+		var report EventLogReport
+		next := map[string]uint64{}
+		for _, ch := range channels {
+			if _, ok := last[ch]; !ok {
+				seed(ch)
+				continue
+			}
+			query := fmt.Sprintf("*[System[(Level=1 or Level=2) and (EventRecordID > %d)]]", last[ch])
+			events, err := queryEvents(ch, query, 0, eventBatchSize)
+			if err != nil {
+				continue
+			}
+			for _, e := range events {
+				next[ch] = max(next[ch], e.RecordID)
+			}
+			report.Events = append(report.Events, events...)
+		}
+
+		if len(report.Events) == 0 {
+			continue
+		}
+		// The record IDs only move on once the events went out, so a failed
+		// report is sent again with the next poll.
+		if err := publish("events", report); err != nil {
+			slog.Warn("Event log report failed", "err", err)
+			continue
+		}
+		for ch, id := range next {
+			last[ch] = max(last[ch], id)
+		}
+	}
+}
+
+func queryEvents(channel, query string, flags uint32, limit int) ([]EventLogEntry, error) {
+	if err := procEvtQuery.Find(); err != nil {
+		return nil, err
+	}
+
+	chPtr, _ := windows.UTF16PtrFromString(channel)
+	qPtr, _ := windows.UTF16PtrFromString(query)
+	results, _, err := procEvtQuery.Call(0, uintptr(unsafe.Pointer(chPtr)), uintptr(unsafe.Pointer(qPtr)), uintptr(evtQueryChannelPath|flags))
+	if results == 0 {
+		return nil, err
+	}
+	defer procEvtClose.Call(results)
+
+	var entries []EventLogEntry
+	handles := make([]uintptr, limit)
+	for len(entries) < limit {
+		var returned uint32
+		ok, _, _ := procEvtNext.Call(results, uintptr(len(handles)), uintptr(unsafe.Pointer(&handles[0])), 1000, 0, uintptr(unsafe.Pointer(&returned)))
+		if ok == 0 || returned == 0 {
+			break
+		}
+		for _, h := range handles[:returned] {
+			if e, ok := renderEvent(h); ok && len(entries) < limit {
+				entries = append(entries, e)
+			}
+			procEvtClose.Call(h)
+		}
+	}
+	return entries, nil
+}
+
+func renderEvent(h uintptr) (EventLogEntry, bool) {
+	raw, ok := evtRenderXML(h)
+	if !ok {
+		return EventLogEntry{}, false
+	}
+
+	var x eventXML
+	if err := xml.Unmarshal([]byte(raw), &x); err != nil {
+		return EventLogEntry{}, false
+	}
+
+	return EventLogEntry{
+		Channel:  x.System.Channel,
+		Source:   x.System.Provider.Name,
+		EventID:  x.System.EventID,
+		Level:    eventLevelName(x.System.Level),
+		Time:     x.System.TimeCreated.SystemTime,
+		RecordID: x.System.EventRecordID,
+		Message:  strings.TrimSpace(evtFormatMessage(x.System.Provider.Name, h)),
+	}, true
+}
+
+func evtRenderXML(h uintptr) (string, bool) {
+	var used, props uint32
+	procEvtRender.Call(0, h, evtRenderEventXml, 0, 0, uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&props)))
+	if used == 0 {
+		return "", false
+	}
+
+	buf := make([]uint16, used/2+1)
+	ok, _, _ := procEvtRender.Call(0, h, evtRenderEventXml, uintptr(len(buf)*2), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)), uintptr(unsafe.Pointer(&props)))
+	if ok == 0 {
+		return "", false
+	}
+	return windows.UTF16ToString(buf), true
+}
+
+func evtFormatMessage(provider string, h uintptr) string {
+	pPtr, _ := windows.UTF16PtrFromString(provider)
+	meta, _, _ := procEvtOpenPublisherMetadata.Call(0, uintptr(unsafe.Pointer(pPtr)), 0, 0, 0)
+	if meta == 0 {
+		return ""
+	}
+	defer procEvtClose.Call(meta)
+
+	var used uint32
+	procEvtFormatMessage.Call(meta, h, 0, 0, 0, evtFormatMessageEvent, 0, 0, uintptr(unsafe.Pointer(&used)))
+	if used == 0 {
+		return ""
+	}
+
+	buf := make([]uint16, used)
+	ok, _, _ := procEvtFormatMessage.Call(meta, h, 0, 0, 0, evtFormatMessageEvent, uintptr(len(buf)), uintptr(unsafe.Pointer(&buf[0])), uintptr(unsafe.Pointer(&used)))
+	if ok == 0 {
+		return ""
+	}
+	return windows.UTF16ToString(buf)
+}
+
+func eventLevelName(level int) string {
+	switch level {
+	case 1:
+		return "Critical"
+	case 2:
+		return "Error"
+	case 3:
+		return "Warning"
+	}
+	return "Information"
+}
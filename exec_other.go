@@ -0,0 +1,21 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"runtime"
+)
+
+func hideWindow(*exec.Cmd) {}
+
+func openBrowser(url string) {
+	opener := "xdg-open"
+	if runtime.GOOS == "darwin" {
+		opener = "open"
+	}
+	if err := exec.Command(opener, url).Start(); err != nil {
+		slog.Warn("Open browser failed", "url", url, "err", err)
+	}
+}
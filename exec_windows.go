@@ -0,0 +1,21 @@
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"syscall"
+
+	"golang.org/x/sys/windows"
+)
+
+// hideWindow keeps a console program started from the tray from flashing a
+// console window.
+func hideWindow(cmd *exec.Cmd) {
+	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+}
+
+func openBrowser(url string) {
+	if err := windows.ShellExecute(0, windows.StringToUTF16Ptr("open"), windows.StringToUTF16Ptr(url), nil, nil, windows.SW_SHOWNORMAL); err != nil {
+		slog.Warn("Open browser failed", "url", url, "err", err)
+	}
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"encoding/csv"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// exportKeep is how many rotated files are kept next to the active one.
+const exportKeep = 5
+
+var exportEnabled atomic.Bool
+
+var csvHeader = []string{
+	"timestamp", "cpu", "ram", "ramUsedMb", "ramTotalMb", "gpu", "gpuEncoder", "gpuDecoder",
+	"pagefile", "netSentBps", "netRecvBps", "uptimeSec",
+}
+
+// fileExporter appends every sample to a CSV or JSONL file, chosen by the
+// file extension, rotating it once it grows past maxBytes.
+type fileExporter struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+}
+
+var exporter *fileExporter
+
+func newFileExporter(path string, maxBytes int64) (*fileExporter, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create export dir: %w", err)
+	}
+	return &fileExporter{path: path, maxBytes: maxBytes}, nil
+}
+
+func (e *fileExporter) jsonl() bool {
+	ext := strings.ToLower(filepath.Ext(e.path))
+	return ext == ".jsonl" || ext == ".json"
+}
+
+func (e *fileExporter) Write(m Metrics) error {
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if info, err := os.Stat(e.path); err == nil && info.Size() >= e.maxBytes {
+		e.rotate()
+	}
+
+	f, err := os.OpenFile(e.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open export: %w", err)
+	}
+	defer f.Close()
+
+	if e.jsonl() {
+		line, err := json.Marshal(m)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		_, err = f.Write(append(line, '\n'))
+		return err
+	}
+
+	w := csv.NewWriter(f)
+	if info, err := f.Stat(); err == nil && info.Size() == 0 {
+		w.Write(csvHeader)
+	}
+	w.Write(csvRow(m))
+	w.Flush()
+	return w.Error()
+}
+
+// rotate shifts metrics.csv to metrics.1.csv, metrics.1.csv to
+// metrics.2.csv and so on, dropping the oldest.
+func (e *fileExporter) rotate() {
+	ext := filepath.Ext(e.path)
+	base := strings.TrimSuffix(e.path, ext)
+	name := func(i int) string { return fmt.Sprintf("%s.%d%s", base, i, ext) }
+
+	os.Remove(name(exportKeep))
+	for i := exportKeep - 1; i >= 1; i-- {
+		os.Rename(name(i), name(i+1))
+	}
+	os.Rename(e.path, name(1))
+}
+
+func csvRow(m Metrics) []string {
+	f := func(v float64) string { return strconv.FormatFloat(v, 'f', 2, 64) }
+	u := func(v uint64) string { return strconv.FormatUint(v, 10) }
+	return []string{
+		m.Timestamp.Format(time.RFC3339), f(m.CPU), f(m.RAM), u(m.RAMUsedMB), u(m.RAMTotalMB),
+		f(m.GPU), f(m.GPUEncoder), f(m.GPUDecoder), f(m.Pagefile), f(m.NetSentBps), f(m.NetRecvBps),
+		u(m.UptimeSec),
+	}
+}
@@ -0,0 +1,7 @@
+package main
+
+type FanReading struct {
+	Name    string  `json:"name"`
+	RPM     float64 `json:"rpm"`
+	Percent float64 `json:"percent"`
+}
@@ -0,0 +1,7 @@
+package main
+
+// getFans returns nothing on macOS, whose fans are only readable through
+// the SMC.
+func getFans() []FanReading {
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"path/filepath"
+	"strings"
+)
+
+// getFans reads the fan speeds the hwmon drivers expose, e.g.
+// /sys/class/hwmon/hwmon2/fan1_input, labelled with the driver name and the
+// fan's label when it has one.
+func getFans() []FanReading {
+	inputs, _ := filepath.Glob("/sys/class/hwmon/hwmon*/fan*_input")
+	var fans []FanReading
+	for _, in := range inputs {
+		rpm := readSysfsInt(in)
+		if rpm <= 0 {
+			continue
+		}
+		dir := filepath.Dir(in)
+		name := readSysfs(filepath.Join(dir, "name"))
+		label := readSysfs(strings.TrimSuffix(in, "_input") + "_label")
+		if label == "" {
+			label = strings.TrimSuffix(filepath.Base(in), "_input")
+		}
+		fans = append(fans, FanReading{Name: strings.TrimSpace(name + " " + label), RPM: float64(rpm), Percent: -1})
+	}
+	return fans
+}
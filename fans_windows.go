@@ -0,0 +1,9 @@
+package main
+
+// getFans has nothing to read on Windows: Win32_Fan only has DesiredSpeed,
+// the speed a fan is set to rather than turning at, and most boards leave it
+// empty anyway. Real speeds come from LibreHardwareMonitor through the
+// sensors collector, and from the GPU.
+func getFans() []FanReading {
+	return nil
+}
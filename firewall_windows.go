@@ -0,0 +1,46 @@
+package main
+
+import (
+	"log/slog"
+	"runtime"
+
+	ole "github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+// getFirewallStatus asks HNetCfg.FwPolicy2 for the effective state of each
+// profile, group policy included, which needs no elevation.
+func getFirewallStatus() []FirewallProfile {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil {
+		if oleErr, ok := err.(*ole.OleError); !ok || oleErr.Code() != 1 { // S_FALSE: already initialized
+			slog.Debug("Firewall status not read", "err", err)
+			return nil
+		}
+	}
+	defer ole.CoUninitialize()
+
+	policy, err := createDispatch("HNetCfg.FwPolicy2")
+	if err != nil {
+		slog.Debug("Firewall status not read", "err", err)
+		return nil
+	}
+	defer policy.Release()
+
+	var profiles []FirewallProfile
+	for _, p := range []struct {
+		name string
+		typ  int32 // NET_FW_PROFILE_TYPE2
+	}{{"domain", 1}, {"private", 2}, {"public", 4}} {
+		v, err := oleutil.GetProperty(policy, "FirewallEnabled", p.typ)
+		if err != nil {
+			slog.Debug("Firewall profile not read", "profile", p.name, "err", err)
+			continue
+		}
+		enabled, _ := v.Value().(bool)
+		profiles = append(profiles, FirewallProfile{Profile: p.name, Enabled: enabled})
+	}
+	return profiles
+}
@@ -0,0 +1,57 @@
+package main
+
+import (
+	"flag"
+	"os"
+	"testing"
+)
+
+// flagSettings holds the command-line flags keyed by the variable they
+// override. Flags take precedence over the environment and the config file.
+var flagSettings = parseFlags(commandLine())
+
+// commandLine leaves out the arguments of a test binary, which are its own.
+func commandLine() []string {
+	if testing.Testing() {
+		return nil
+	}
+	return os.Args[1:]
+}
+
+func parseFlags(args []string) map[string]string {
+	fs := flag.NewFlagSet("go-win-monitor", flag.ExitOnError)
+	names := map[string]string{
+		"config":      "M_CONFIG",
+		"url":         "M_API_URL",
+		"secret-file": "M_AGENT_SECRET_FILE",
+		"interval":    "M_INTERVAL",
+		"log-level":   "M_LOG_LEVEL",
+	}
+	fs.String("config", "", "config file (default %APPDATA%\\go-win-monitor\\config.toml)")
+	fs.String("url", "", "API endpoint, overrides M_API_URL")
+	fs.String("secret-file", "", "file holding the agent secret")
+	fs.String("interval", "", "collect and send interval, e.g. 10s")
+	fs.String("log-level", "", "debug, info, warn, error or off")
+	noTray := fs.Bool("no-tray", false, "run without the tray icon")
+	fs.BoolVar(&setSecretMode, "set-secret", false, "read the agent secret from stdin and store it in Credential Manager")
+	fs.Parse(args)
+	if fs.Arg(0) == "service" {
+		serviceCommand = fs.Args()[1:]
+	}
+
+	settings := map[string]string{}
+	fs.Visit(func(f *flag.Flag) {
+		if key, ok := names[f.Name]; ok {
+			settings[key] = f.Value.String()
+		}
+	})
+	if *noTray {
+		settings["M_NO_TRAY"] = "1"
+	}
+	return settings
+}
+
+var setSecretMode bool
+
+// serviceCommand holds the arguments after "service", e.g. ["install"].
+var serviceCommand []string
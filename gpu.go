@@ -0,0 +1,132 @@
+package main
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strconv"
+	"strings"
+)
+
+type nvidiaStats struct {
+	Utilization float64
+	Encoder     float64
+	Decoder     float64
+	FanSpeed    float64
+	CoreClock   float64
+	MemClock    float64
+	MaxClock    float64
+	TempC       float64
+}
+
+// errNoNvidia means nvidia-smi is not installed, as on any machine without
+// an NVIDIA card; it is not a failure.
+var errNoNvidia = errors.New("nvidia-smi not found")
+
+// getNvidiaGPU returns false without an error when the card does not report
+// its utilization.
+func getNvidiaGPU(ctx context.Context) (nvidiaStats, bool, error) {
+	values, err := queryNvidia(ctx, "utilization.gpu", "utilization.encoder", "utilization.decoder", "fan.speed", "clocks.gr", "clocks.mem", "clocks.max.gr", "temperature.gpu")
+	if err != nil || values[0] < 0 {
+		return nvidiaStats{}, false, err
+	}
+
+	return nvidiaStats{
+		Utilization: values[0],
+		Encoder:     values[1],
+		Decoder:     values[2],
+		FanSpeed:    values[3],
+		CoreClock:   values[4],
+		MemClock:    values[5],
+		MaxClock:    values[6],
+		TempC:       values[7],
+	}, true, nil
+}
+
+// queryNvidia returns the requested fields for the first GPU. Fields the card
+// doesn't support ("[N/A]", "[Not Supported]") are reported as -1.
+func queryNvidia(ctx context.Context, fields ...string) ([]float64, error) {
+	cmd := exec.CommandContext(ctx,
+		"nvidia-smi",
+		"--query-gpu="+strings.Join(fields, ","),
+		"--format=csv,noheader,nounits",
+	)
+	hideWindow(cmd)
+
+	var out bytes.Buffer
+	cmd.Stdout = &out
+
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, errNoNvidia
+	}
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+
+	line, _, _ := strings.Cut(strings.TrimSpace(out.String()), "\n")
+	parts := strings.Split(line, ",")
+	if len(parts) != len(fields) {
+		return nil, fmt.Errorf("nvidia-smi printed %q, expected %d fields", line, len(fields))
+	}
+
+	values := make([]float64, len(parts))
+	for i, p := range parts {
+		v, err := strconv.ParseFloat(strings.TrimSpace(p), 64)
+		if err != nil {
+			v = -1
+		}
+		values[i] = v
+	}
+
+	return values, nil
+}
+
+// GPUProcess is a process holding memory on the NVIDIA card. VRAMMB is -1
+// when the driver does not account it per process, as for graphics
+// processes under WDDM.
+type GPUProcess struct {
+	PID    int32   `json:"pid"`
+	Name   string  `json:"name"`
+	VRAMMB float64 `json:"vramMb"`
+}
+
+// getGPUProcesses lists the processes nvidia-smi reports as compute apps,
+// heaviest first.
+func getGPUProcesses(ctx context.Context) ([]GPUProcess, error) {
+	cmd := exec.CommandContext(ctx, "nvidia-smi", "--query-compute-apps=pid,process_name,used_memory", "--format=csv,noheader,nounits")
+	hideWindow(cmd)
+	var out bytes.Buffer
+	cmd.Stdout = &out
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, errNoNvidia
+	}
+	if err != nil {
+		return nil, fmt.Errorf("nvidia-smi: %w", err)
+	}
+
+	var procs []GPUProcess
+	for _, line := range strings.Split(strings.TrimSpace(out.String()), "\n") {
+		parts := strings.Split(line, ",")
+		if len(parts) != 3 {
+			continue
+		}
+		pid, err := strconv.ParseInt(strings.TrimSpace(parts[0]), 10, 32)
+		if err != nil {
+			continue
+		}
+		p := GPUProcess{PID: int32(pid), Name: filepath.Base(strings.TrimSpace(parts[1])), VRAMMB: -1}
+		if v, err := strconv.ParseFloat(strings.TrimSpace(parts[2]), 64); err == nil {
+			p.VRAMMB = v
+		}
+		procs = append(procs, p)
+	}
+	slices.SortFunc(procs, func(a, b GPUProcess) int { return cmp.Compare(b.VRAMMB, a.VRAMMB) })
+	return procs, nil
+}
@@ -0,0 +1,44 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+//go:embed web/graphs.html
+var graphsHTML []byte
+
+func openGraphs() {
+	openUI("graphs")
+}
+
+func handleGraphs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(graphsHTML)
+}
+
+// graphPoint is the slice of a sample the sparklines need.
+type graphPoint struct {
+	T    int64   `json:"t"`
+	CPU  float64 `json:"cpu"`
+	RAM  float64 `json:"ram"`
+	GPU  float64 `json:"gpu"`
+	Up   float64 `json:"up"`
+	Down float64 `json:"down"`
+}
+
+func handleGraphData(w http.ResponseWriter, r *http.Request) {
+	minutes, err := strconv.Atoi(r.URL.Query().Get("minutes"))
+	if err != nil || minutes <= 0 {
+		minutes = 10
+	}
+	samples := history.Since(time.Now().Add(-time.Duration(min(minutes, 60)) * time.Minute))
+
+	points := make([]graphPoint, len(samples))
+	for i, m := range samples {
+		points[i] = graphPoint{m.Timestamp.UnixMilli(), m.CPU, m.RAM, m.GPU, m.NetSentBps, m.NetRecvBps}
+	}
+	writeJSON(w, points)
+}
@@ -0,0 +1,202 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"strings"
+	"sync"
+	"time"
+
+	"go-win-monitor/monitorpb"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/grpc/encoding/gzip"
+	"google.golang.org/grpc/metadata"
+)
+
+// grpcClient sends everything over a single Monitor.Stream session, opened
+// lazily and reopened on the next message after any error. Messages are sent
+// one at a time and each waits for its ack.
+type grpcClient struct {
+	mu     sync.Mutex
+	addr   string
+	conn   *grpc.ClientConn
+	stream grpc.BidiStreamingClient[monitorpb.AgentMessage, monitorpb.ServerMessage]
+	cancel context.CancelFunc
+	seq    uint64
+}
+
+func newGRPCClient(addr string, useTLS bool) (*grpcClient, error) {
+	creds := insecure.NewCredentials()
+	if useTLS {
+		creds = credentials.NewTLS(tlsConfig.Load().Clone())
+	}
+	conn, err := grpc.NewClient(addr,
+		grpc.WithTransportCredentials(creds),
+		grpc.WithUserAgent("go-win-monitor/"+version),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("grpc client: %w", err)
+	}
+	return &grpcClient{addr: addr, conn: conn}, nil
+}
+
+func (c *grpcClient) Publish(kind string, v any) error {
+	msg := &monitorpb.AgentMessage{}
+	if m, ok := v.(Metrics); ok {
+		msg.Body = &monitorpb.AgentMessage_Metrics{Metrics: metricsToProto(m)}
+	} else {
+		payload, err := json.Marshal(v)
+		if err != nil {
+			return fmt.Errorf("marshal: %w", err)
+		}
+		msg.Body = &monitorpb.AgentMessage_Event{Event: &monitorpb.Event{Kind: kind, Json: payload}}
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if c.stream == nil {
+		if err := c.open(); err != nil {
+			c.close()
+			return err
+		}
+	}
+	if err := c.call(msg); err != nil {
+		c.close()
+		return err
+	}
+	return nil
+}
+
+func (c *grpcClient) open() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	c.cancel = cancel
+	for k, v := range extraHeaders {
+		ctx = metadata.AppendToOutgoingContext(ctx, strings.ToLower(k), v)
+	}
+
+	var opts []grpc.CallOption
+	if compressBodies {
+		opts = append(opts, grpc.UseCompressor(gzip.Name))
+	}
+	stream, err := monitorpb.NewMonitorClient(c.conn).Stream(ctx, opts...)
+	if err != nil {
+		return fmt.Errorf("open stream: %w", err)
+	}
+	c.stream = stream
+
+	host, _ := os.Hostname()
+	return c.call(&monitorpb.AgentMessage{Body: &monitorpb.AgentMessage_Auth{Auth: &monitorpb.Auth{
+		Secret:       secret,
+		AgentVersion: version,
+		Hostname:     host,
+	}}})
+}
+
+// call sends msg and waits for the matching ack. A server that stops
+// answering has the stream cancelled after requestTimeout.
+func (c *grpcClient) call(msg *monitorpb.AgentMessage) error {
+	c.seq++
+	msg.Seq = c.seq
+
+	timer := time.AfterFunc(requestTimeout, c.cancel)
+	defer timer.Stop()
+
+	if err := c.stream.Send(msg); err != nil {
+		return fmt.Errorf("send: %w", err)
+	}
+	resp, err := c.stream.Recv()
+	if err != nil {
+		return fmt.Errorf("receive: %w", err)
+	}
+
+	switch body := resp.Body.(type) {
+	case *monitorpb.ServerMessage_Ack:
+		if body.Ack.Seq != msg.Seq {
+			return fmt.Errorf("ack for %d, expected %d", body.Ack.Seq, msg.Seq)
+		}
+		return nil
+	case *monitorpb.ServerMessage_Error:
+		return &statusError{Code: int(body.Error.Code)}
+	}
+	return fmt.Errorf("unexpected server message")
+}
+
+func (c *grpcClient) Send(kind string, v any) ([]byte, error) {
+	return nil, c.Publish(kind, v)
+}
+
+// Reset drops the stream so the next publish opens a new one.
+func (c *grpcClient) Reset() {
+	c.mu.Lock()
+	c.close()
+	c.mu.Unlock()
+}
+
+// Close ends the stream and the connection; the client is not used again.
+func (c *grpcClient) Close() {
+	c.mu.Lock()
+	c.close()
+	c.conn.Close()
+	c.mu.Unlock()
+}
+
+func (c *grpcClient) Status() string { return "grpc://" + c.addr }
+
+func (c *grpcClient) close() {
+	if c.stream != nil {
+		c.stream.CloseSend()
+		c.stream = nil
+	}
+	if c.cancel != nil {
+		c.cancel()
+		c.cancel = nil
+	}
+}
+
+func metricsToProto(m Metrics) *monitorpb.Metrics {
+	pm := &monitorpb.Metrics{
+		TimestampUnixMs: m.Timestamp.UnixMilli(),
+		MachineId:       m.MachineID,
+		Hostname:        m.Hostname,
+		Tags:            m.Tags,
+		RunId:           m.RunID,
+		Seq:             m.Seq,
+		Cpu:             m.CPU,
+		CpuFreqMhz:      m.CPUFreqMHz,
+		Ram:             m.RAM,
+		RamUsedMb:       m.RAMUsedMB,
+		RamTotalMb:      m.RAMTotalMB,
+		Pagefile:        m.Pagefile,
+		Gpu:             m.GPU,
+		GpuEncoder:      m.GPUEncoder,
+		GpuDecoder:      m.GPUDecoder,
+		UptimeSec:       m.UptimeSec,
+		PendingReboot:   m.PendingReboot,
+		Processes:       m.Processes,
+		Threads:         m.Threads,
+		Handles:         m.Handles,
+		WifiSsid:        m.WiFiSSID,
+		PublicIp:        m.PublicIP,
+		ForegroundApp:   m.ForegroundApp,
+		IdleSec:         m.IdleSec,
+		SessionLocked:   m.SessionLocked,
+	}
+
+	visitSamples(m, func(name, help string, value float64, labels ...string) {
+		s := &monitorpb.Sample{Name: name, Value: value}
+		if len(labels) > 0 {
+			s.Labels = make(map[string]string, len(labels)/2)
+			for i := 0; i+1 < len(labels); i += 2 {
+				s.Labels[labels[i]] = labels[i+1]
+			}
+		}
+		pm.Samples = append(pm.Samples, s)
+	})
+	return pm
+}
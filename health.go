@@ -0,0 +1,90 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"time"
+)
+
+type HealthReport struct {
+	Antivirus []AntivirusStatus `json:"antivirus"`
+	BitLocker []BitLockerVolume `json:"bitlocker,omitempty"`
+	Firewall  []FirewallProfile `json:"firewall,omitempty"`
+}
+
+// AntivirusStatus is one product registered with the Security Center.
+// SignatureAgeHours is only known for Microsoft Defender, -1 otherwise.
+type AntivirusStatus struct {
+	Name               string  `json:"name"`
+	RealTimeProtection bool    `json:"realTimeProtection"`
+	SignaturesUpToDate bool    `json:"signaturesUpToDate"`
+	SignatureAgeHours  float64 `json:"signatureAgeHours"`
+}
+
+// BitLockerVolume is one volume as BitLocker sees it. Protection is "on",
+// "off" or "unknown" (locked); Status is decrypted, encrypted, encrypting,
+// decrypting, encryptionPaused or decryptionPaused, or unknown with
+// EncryptedPercent at -1 when the conversion status could not be read.
+type BitLockerVolume struct {
+	Volume           string  `json:"volume"`
+	Protection       string  `json:"protection"`
+	Status           string  `json:"status"`
+	EncryptedPercent float64 `json:"encryptedPercent"`
+}
+
+// FirewallProfile is the effective state of one Windows Firewall profile:
+// domain, private or public.
+type FirewallProfile struct {
+	Profile string `json:"profile"`
+	Enabled bool   `json:"enabled"`
+}
+
+// firewallOff is whether the last health report found every firewall
+// profile off. It is only used from the health collector.
+var firewallOff bool
+
+func runHealthCollector() {
+	for {
+		report := collectHealth()
+		if err := publish("health", report); err != nil {
+			slog.Warn("Health report failed", "err", err)
+		}
+		time.Sleep(30 * time.Minute)
+	}
+}
+
+func collectHealth() HealthReport {
+	var report HealthReport
+	report.Antivirus = getAntivirusStatus()
+	for _, av := range report.Antivirus {
+		if !av.RealTimeProtection {
+			slog.Warn("Real-time protection disabled", "antivirus", av.Name)
+		}
+	}
+	report.BitLocker = getBitLockerStatus()
+	report.Firewall = getFirewallStatus()
+	checkFirewall(report.Firewall)
+	return report
+}
+
+// checkFirewall raises the firewall-off alert when every profile is off
+// and resolves it once one is back on.
+func checkFirewall(profiles []FirewallProfile) {
+	if len(profiles) == 0 {
+		return
+	}
+	off := !slices.ContainsFunc(profiles, func(p FirewallProfile) bool { return p.Enabled })
+	if off == firewallOff {
+		return
+	}
+	firewallOff = off
+	ev := AlertEvent{
+		Timestamp: time.Now().UTC(), MachineID: machineID(), Hostname: hostname(),
+		Rule: "firewall-off", Series: "firewall", State: "resolved",
+		Message: "Windows Firewall is back on", cooldown: alertCooldown,
+	}
+	if off {
+		ev.State, ev.Message = "fired", "Windows Firewall is off for every profile"
+	}
+	raiseAlert(ev)
+}
@@ -0,0 +1,15 @@
+//go:build !windows
+
+package main
+
+// getAntivirusStatus reads Windows Security Center, which has no
+// counterpart elsewhere; the health report goes out with no products.
+func getAntivirusStatus() []AntivirusStatus { return nil }
+
+// getBitLockerStatus has nothing to read off Windows; FileVault and LUKS
+// are not reported.
+func getBitLockerStatus() []BitLockerVolume { return nil }
+
+// getFirewallStatus reports no profiles, so the firewall-off alert never
+// fires off Windows.
+func getFirewallStatus() []FirewallProfile { return nil }
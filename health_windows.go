@@ -0,0 +1,49 @@
+package main
+
+import (
+	"strings"
+	"time"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+type antiVirusProduct struct {
+	DisplayName  string
+	ProductState uint32
+}
+
+type mpComputerStatus struct {
+	AntivirusSignatureLastUpdated time.Time
+}
+
+// getAntivirusStatus decodes SecurityCenter2's productState: bit 12 is set while
+// real-time protection is on and bit 4 is set when signatures are out of date.
+// SecurityCenter2's Timestamp is when the product last reported its state, not
+// when its signatures were updated, so the signature age comes from Defender's
+// own status and is only known for Defender.
+func getAntivirusStatus() []AntivirusStatus {
+	var products []antiVirusProduct
+	if err := wmi.QueryNamespace("SELECT DisplayName, ProductState FROM AntiVirusProduct", &products, `root\SecurityCenter2`); err != nil {
+		return nil
+	}
+	defenderAge := -1.0
+	var status []mpComputerStatus
+	if err := wmi.QueryNamespace("SELECT AntivirusSignatureLastUpdated FROM MSFT_MpComputerStatus", &status, `root\Microsoft\Windows\Defender`); err == nil && len(status) > 0 && !status[0].AntivirusSignatureLastUpdated.IsZero() {
+		defenderAge = time.Since(status[0].AntivirusSignatureLastUpdated).Hours()
+	}
+
+	var result []AntivirusStatus
+	for _, p := range products {
+		s := AntivirusStatus{
+			Name:               p.DisplayName,
+			RealTimeProtection: p.ProductState&0x1000 != 0,
+			SignaturesUpToDate: p.ProductState&0x10 == 0,
+			SignatureAgeHours:  -1,
+		}
+		if strings.Contains(p.DisplayName, "Defender") {
+			s.SignatureAgeHours = defenderAge
+		}
+		result = append(result, s)
+	}
+	return result
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+	"time"
+)
+
+type Heartbeat struct {
+	Timestamp     time.Time `json:"timestamp"`
+	MachineID     string    `json:"machineId"`
+	RunID         string    `json:"runId"`
+	UptimeSec     uint64    `json:"agentUptimeSec"`
+	SessionLocked bool      `json:"sessionLocked"`
+	Reason        string    `json:"reason"`
+}
+
+// runHeartbeat sends a small liveness message every heartbeatInterval,
+// independent of the metrics ticker, and right away when the session is
+// locked or unlocked.
+func runHeartbeat() {
+	start := time.Now()
+	locked := isSessionLocked()
+	last := time.Time{}
+
+	for ; ; time.Sleep(2 * time.Second) {
+		reason := ""
+		if now := isSessionLocked(); now != locked {
+			locked = now
+			reason = "unlock"
+			if locked {
+				reason = "lock"
+			}
+		} else if time.Since(last) >= heartbeatInterval {
+			reason = "interval"
+		}
+		if reason == "" {
+			continue
+		}
+
+		last = time.Now()
+		err := publish("heartbeat", Heartbeat{
+			Timestamp:     last.UTC(),
+			MachineID:     machineID(),
+			RunID:         runID,
+			UptimeSec:     uint64(time.Since(start) / time.Second),
+			SessionLocked: locked,
+			Reason:        reason,
+		})
+		if err != nil {
+			slog.Warn("Heartbeat failed", "err", err)
+		}
+	}
+}
@@ -0,0 +1,227 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"net/http"
+	"net/url"
+	"runtime"
+	"slices"
+	"time"
+)
+
+// protocolVersion is bumped whenever the messages change incompatibly.
+// Agents from before the handshake implicitly speak version 1.
+const protocolVersion = 2
+
+type Hello struct {
+	MachineID       string   `json:"machineId"`
+	Hostname        string   `json:"hostname"`
+	ProtocolVersion int      `json:"protocolVersion"`
+	AgentVersion    string   `json:"agentVersion"`
+	OS              string   `json:"os"`
+	OSVersion       string   `json:"osVersion"`
+	OSBuild         string   `json:"osBuild"`
+	Arch            string   `json:"arch"`
+	Features        []string `json:"features"`
+	Collectors      []string `json:"collectors"`
+	SendIntervalSec int      `json:"sendIntervalSec"`
+}
+
+// HelloResponse is the server's optional reply. Features, when present,
+// selects which of the negotiable features the agent may use, and Metrics
+// which of the collectors it runs; the intervals can also be overridden.
+type HelloResponse struct {
+	ProtocolVersion    int      `json:"protocolVersion"`
+	Features           []string `json:"features"`
+	Metrics            []string `json:"metrics"`
+	SendIntervalSec    int      `json:"sendIntervalSec"`
+	CollectIntervalSec int      `json:"collectIntervalSec"`
+}
+
+func enabledFeatures() []string {
+	var f []string
+	add := func(name string, on bool) {
+		if on {
+			f = append(f, name)
+		}
+	}
+	add("smart", true)
+	add("updates", true)
+	add("health", true)
+	add("inventory", true)
+	add("metadata", true)
+	add("summary", dailySummary)
+	add("events", len(eventLogs) > 0)
+	add("pings", len(pings) > 0)
+	add("watch", len(watch) > 0)
+	add("services", len(services) > 0)
+	add("docker", docker.Load())
+	add("hyperv", hyperV.Load())
+	add("gpu-processes", gpuProcesses.Load())
+	add("etw-network", etwNet)
+	add("foreground", reportForeground.Load())
+	add("public-ip", ipLookup != "")
+	add("gzip", compressBodies)
+	add("msgpack", payloadEncoding == "msgpack")
+	add("session-tokens", sessionTokens)
+	return f
+}
+
+func newHello() Hello {
+	win := getWindowsVersion()
+	return Hello{
+		MachineID:       machineID(),
+		Hostname:        hostname(),
+		ProtocolVersion: protocolVersion,
+		AgentVersion:    version,
+		OS:              win.Product,
+		OSVersion:       win.DisplayVersion,
+		OSBuild:         win.Build,
+		Arch:            runtime.GOARCH,
+		Features:        enabledFeatures(),
+		Collectors:      availableCollectors(),
+		SendIntervalSec: int(sendInterval / time.Second),
+	}
+}
+
+// sendHello greets the primary server and every extra API. Each one's
+// reply only negotiates the features used with it; the primary server's
+// also sets the collectors and intervals.
+func sendHello() error {
+	resetMetadata()
+	hello := newHello()
+	for _, t := range apiTargets {
+		go func() {
+			data, err := postJSON(t.endpoint("hello"), t.secret, hello)
+			if err != nil {
+				slog.Warn("Publish failed", "kind", "hello", "to", t.base, "err", err)
+				return
+			}
+			if resp, ok := parseHelloResponse(data); ok {
+				negotiate(t.endpoint("hello"), resp.Features)
+			}
+		}()
+	}
+	data, err := request("hello", hello)
+	if err != nil {
+		return err
+	}
+	if resp, ok := parseHelloResponse(data); ok {
+		applyHelloResponse(resp)
+	}
+	return nil
+}
+
+func parseHelloResponse(data []byte) (HelloResponse, bool) {
+	var resp HelloResponse
+	if len(data) == 0 {
+		return resp, false
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		slog.Warn("Ignoring hello response", "err", err)
+		return resp, false
+	}
+	return resp, true
+}
+
+// helloRetry sends the hello again from the collection loop until the
+// server answers it: after one failed, and whenever the primary output
+// reconnects, since the server may have come back with other settings. It
+// is only used on the loop's goroutine.
+type helloRetry struct {
+	pending bool
+	at      time.Time
+	bo      backoff
+}
+
+var hellos = helloRetry{bo: backoff{base: 10 * time.Second, max: 5 * time.Minute}}
+
+// Schedule has the next tick send the hello.
+func (h *helloRetry) Schedule() {
+	h.pending, h.at = true, time.Time{}
+	h.bo.Reset()
+}
+
+func (h *helloRetry) Failed(err error) {
+	d := h.bo.Next()
+	h.pending, h.at = true, time.Now().Add(d)
+	slog.Warn("Hello failed", "err", err, "retryIn", d.Round(time.Second))
+}
+
+// Run sends a pending hello once it is due. It returns false when the
+// server refused the agent version.
+func (h *helloRetry) Run() bool {
+	if !h.pending || time.Now().Before(h.at) {
+		return true
+	}
+	err := sendHello()
+	switch {
+	case err == nil:
+		h.pending = false
+		h.bo.Reset()
+	case isUpgradeRequired(err):
+		h.pending = false
+		slog.Error("Server refused the agent version", "version", version)
+		return false
+	default:
+		h.Failed(err)
+	}
+	return true
+}
+
+func isUpgradeRequired(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.Code == http.StatusUpgradeRequired
+}
+
+// applyHelloResponse runs on the collection loop's goroutine, or on the one
+// that starts it before it does. The send interval is left to the loop as a
+// setInterval command, like the server's other interval changes.
+func applyHelloResponse(resp HelloResponse) {
+	if resp.ProtocolVersion != 0 && resp.ProtocolVersion != protocolVersion {
+		slog.Warn("Protocol version mismatch", "server", resp.ProtocolVersion, "agent", protocolVersion)
+	}
+
+	negotiate(apiURL, resp.Features)
+
+	if resp.Metrics != nil {
+		applySubscription(resp.Metrics, resp.CollectIntervalSec)
+	}
+
+	if resp.SendIntervalSec > 0 {
+		select {
+		case commands <- Command{ID: "local", Type: "setInterval", SendIntervalSec: resp.SendIntervalSec}:
+		default:
+			slog.Warn("Command queue full, ignoring the server's send interval")
+		}
+	}
+}
+
+// negotiate records which of gzip and MessagePack the server at endpoint
+// selected. It is kept by host, next to what the responses advertise, so
+// the primary server's answer does not change what the extra APIs get. A
+// reply without features selects nothing.
+func negotiate(endpoint string, features []string) {
+	u, err := url.Parse(endpoint)
+	if features == nil || err != nil {
+		return
+	}
+	if compressBodies {
+		if slices.Contains(features, "gzip") {
+			noGzip.Delete(u.Host)
+		} else if _, seen := noGzip.LoadOrStore(u.Host, true); !seen {
+			slog.Info("Server did not select gzip, sending uncompressed", "host", u.Host)
+		}
+	}
+	if payloadEncoding == "msgpack" {
+		if slices.Contains(features, "msgpack") {
+			msgpackDeclined.Delete(u.Host)
+			msgpackHosts.Store(u.Host, true)
+		} else {
+			msgpackDeclined.Store(u.Host, true)
+			msgpackHosts.Delete(u.Host)
+		}
+	}
+}
@@ -0,0 +1,97 @@
+package main
+
+import (
+	"strings"
+	"sync"
+	"time"
+)
+
+// sampleHistory keeps the most recent samples in a fixed-size ring.
+type sampleHistory struct {
+	mu      sync.RWMutex
+	samples []Metrics
+	next    int
+	full    bool
+}
+
+var history = newSampleHistory(historySize)
+
+func newSampleHistory(size int) *sampleHistory {
+	return &sampleHistory{samples: make([]Metrics, max(size, 1))}
+}
+
+func (h *sampleHistory) Add(m Metrics) {
+	h.mu.Lock()
+	h.samples[h.next] = m
+	h.next = (h.next + 1) % len(h.samples)
+	if h.next == 0 {
+		h.full = true
+	}
+	h.mu.Unlock()
+}
+
+// Since returns the samples taken at or after t, oldest first.
+func (h *sampleHistory) Since(t time.Time) []Metrics {
+	h.mu.RLock()
+	defer h.mu.RUnlock()
+
+	var ordered []Metrics
+	if h.full {
+		ordered = append(ordered, h.samples[h.next:]...)
+	}
+	ordered = append(ordered, h.samples[:h.next]...)
+
+	out := []Metrics{}
+	for _, m := range ordered {
+		if !m.Timestamp.Before(t) {
+			out = append(out, m)
+		}
+	}
+	return out
+}
+
+type metricStats struct {
+	Min   float64 `json:"min"`
+	Max   float64 `json:"max"`
+	Avg   float64 `json:"avg"`
+	Count int     `json:"count"`
+}
+
+// Stats summarizes every series over the last window, keyed like the
+// Prometheus output without the prefix, e.g. cpu_usage_percent or
+// tcp_connections{state="established"}.
+func (h *sampleHistory) Stats(window time.Duration) map[string]metricStats {
+	stats := map[string]metricStats{}
+	for _, m := range h.Since(time.Now().Add(-window)) {
+		visitSamples(m, func(name, help string, value float64, labels ...string) {
+			key := seriesKey(name, labels)
+			s, ok := stats[key]
+			if !ok {
+				s = metricStats{Min: value, Max: value}
+			}
+			s.Min = min(s.Min, value)
+			s.Max = max(s.Max, value)
+			s.Avg += (value - s.Avg) / float64(s.Count+1)
+			s.Count++
+			stats[key] = s
+		})
+	}
+	return stats
+}
+
+func seriesKey(name string, labels []string) string {
+	if len(labels) == 0 {
+		return name
+	}
+	var b strings.Builder
+	b.WriteString(name)
+	b.WriteByte('{')
+	for i := 0; i+1 < len(labels); i += 2 {
+		if i > 0 {
+			b.WriteByte(',')
+		}
+		b.WriteString(labels[i] + "=\"" + labels[i+1] + "\"")
+	}
+	b.WriteByte('}')
+	return b.String()
+}
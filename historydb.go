@@ -0,0 +1,129 @@
+package main
+
+import (
+	"database/sql"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"time"
+
+	_ "modernc.org/sqlite"
+)
+
+const (
+	// Samples older than downsampleAfter are thinned to one per downsampleStep.
+	downsampleAfter = 24 * time.Hour
+	downsampleStep  = 5 * time.Minute
+	pruneEvery      = time.Hour
+)
+
+// historyStore persists every sample in SQLite so the local API can serve
+// history across restarts.
+type historyStore struct {
+	db        *sql.DB
+	retention time.Duration
+	pruned    time.Time
+}
+
+var historyDB *historyStore
+
+func openHistoryStore(path string, retention time.Duration) (*historyStore, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	db, err := sql.Open("sqlite", path+"?_pragma=journal_mode(WAL)&_pragma=busy_timeout(5000)")
+	if err != nil {
+		return nil, err
+	}
+	db.SetMaxOpenConns(1)
+
+	_, err = db.Exec(`CREATE TABLE IF NOT EXISTS samples (
+		ts   INTEGER PRIMARY KEY,
+		data BLOB NOT NULL
+	)`)
+	if err != nil {
+		db.Close()
+		return nil, fmt.Errorf("create schema: %w", err)
+	}
+	return &historyStore{db: db, retention: retention}, nil
+}
+
+func (s *historyStore) Add(m Metrics) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return
+	}
+	if _, err := s.db.Exec(`INSERT OR REPLACE INTO samples (ts, data) VALUES (?, ?)`, m.Timestamp.UnixMilli(), data); err != nil {
+		slog.Warn("History store write failed", "err", err)
+		return
+	}
+
+	if time.Since(s.pruned) >= pruneEvery {
+		s.pruned = time.Now()
+		if err := s.prune(); err != nil {
+			slog.Warn("History prune failed", "err", err)
+		}
+	}
+}
+
+func (s *historyStore) prune() error {
+	now := time.Now()
+	if _, err := s.db.Exec(`DELETE FROM samples WHERE ts < ?`, now.Add(-s.retention).UnixMilli()); err != nil {
+		return err
+	}
+
+	step := downsampleStep.Milliseconds()
+	_, err := s.db.Exec(`DELETE FROM samples WHERE ts < ? AND ts NOT IN (
+		SELECT MIN(ts) FROM samples WHERE ts < ? GROUP BY ts / ?
+	)`, now.Add(-downsampleAfter).UnixMilli(), now.Add(-downsampleAfter).UnixMilli(), step)
+	return err
+}
+
+// Close checkpoints the write-ahead log into the database file.
+func (s *historyStore) Close() error {
+	return s.db.Close()
+}
+
+// Since returns up to limit of the newest samples taken at or after t,
+// oldest first. limit <= 0 means no limit.
+func (s *historyStore) Since(t time.Time, limit int) ([]Metrics, error) {
+	if limit <= 0 {
+		limit = -1
+	}
+	rows, err := s.db.Query(`SELECT data FROM (
+		SELECT ts, data FROM samples WHERE ts >= ? ORDER BY ts DESC LIMIT ?
+	) ORDER BY ts`, t.UnixMilli(), limit)
+	if err != nil {
+		return nil, err
+	}
+	return scanSamples(rows)
+}
+
+// Between returns the samples taken in [from, to), oldest first.
+func (s *historyStore) Between(from, to time.Time) ([]Metrics, error) {
+	rows, err := s.db.Query(`SELECT data FROM samples WHERE ts >= ? AND ts < ? ORDER BY ts`, from.UnixMilli(), to.UnixMilli())
+	if err != nil {
+		return nil, err
+	}
+	return scanSamples(rows)
+}
+
+func scanSamples(rows *sql.Rows) ([]Metrics, error) {
+	defer rows.Close()
+
+	out := []Metrics{}
+	for rows.Next() {
+		var data []byte
+		if err := rows.Scan(&data); err != nil {
+			return nil, err
+		}
+		var m Metrics
+		if err := json.Unmarshal(data, &m); err != nil {
+			continue
+		}
+		out = append(out, m)
+	}
+	return out, rows.Err()
+}
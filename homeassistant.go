@@ -0,0 +1,111 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"regexp"
+	"runtime"
+	"strings"
+)
+
+type haSensor struct {
+	id          string
+	name        string
+	template    string
+	unit        string
+	deviceClass string
+}
+
+var haUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+func haSlug(s string) string {
+	return strings.ToLower(strings.Trim(haUnsafe.ReplaceAllString(s, "_"), "_"))
+}
+
+// publishHADiscovery publishes retained Home Assistant discovery configs for
+// the sensors present in m, all reading from the agent's report topic.
+func publishHADiscovery(c *mqttClient, m Metrics) error {
+	host, _ := os.Hostname()
+	node := haSlug("go_win_monitor_" + host)
+
+	sensors := []haSensor{
+		{"cpu", "CPU", "{{ value_json.cpu | round(1) }}", "%", ""},
+		{"ram", "RAM", "{{ value_json.ram | round(1) }}", "%", ""},
+		{"ram_used", "RAM used", "{{ value_json.ramUsedMb }}", "MB", "data_size"},
+		{"pagefile", "Pagefile", "{{ value_json.pagefile | round(1) }}", "%", ""},
+		{"uptime", "Uptime", "{{ value_json.uptimeSec }}", "s", "duration"},
+		{"processes", "Processes", "{{ value_json.processes }}", "", ""},
+		{"idle", "Idle time", "{{ value_json.idleSec }}", "s", "duration"},
+	}
+	if m.CPUFreqMHz > 0 {
+		sensors = append(sensors, haSensor{"cpu_freq", "CPU frequency", "{{ value_json.cpuFreqMhz }}", "MHz", "frequency"})
+	}
+	if m.GPU >= 0 {
+		sensors = append(sensors,
+			haSensor{"gpu", "GPU", "{{ value_json.gpu }}", "%", ""},
+			haSensor{"gpu_encoder", "GPU encoder", "{{ value_json.gpuEncoder }}", "%", ""},
+			haSensor{"gpu_decoder", "GPU decoder", "{{ value_json.gpuDecoder }}", "%", ""},
+		)
+	}
+	if m.WiFiSSID != "" {
+		sensors = append(sensors, haSensor{"wifi_signal", "Wi-Fi signal", "{{ value_json.wifiSignal }}", "%", ""})
+	}
+	for _, t := range m.DiskTemps {
+		sensors = append(sensors, haSensor{
+			id:          "disk_temp_" + haSlug(t.Name),
+			name:        t.Name + " temperature",
+			template:    fmt.Sprintf("{{ (value_json.diskTemps | selectattr('name', 'eq', %q) | first).tempC }}", t.Name),
+			unit:        "°C",
+			deviceClass: "temperature",
+		})
+	}
+
+	device := map[string]any{
+		"identifiers":  []string{node},
+		"name":         host,
+		"manufacturer": "go-win-monitor",
+		"model":        haModel(),
+		"sw_version":   version,
+	}
+
+	for _, s := range sensors {
+		cfg := map[string]any{
+			"name":           s.name,
+			"unique_id":      node + "_" + s.id,
+			"state_topic":    c.Topic("report"),
+			"value_template": s.template,
+			"state_class":    "measurement",
+			"device":         device,
+		}
+		if s.unit != "" {
+			cfg["unit_of_measurement"] = s.unit
+		}
+		if s.deviceClass != "" {
+			cfg["device_class"] = s.deviceClass
+		}
+
+		payload, err := json.Marshal(cfg)
+		if err != nil {
+			return fmt.Errorf("marshal %s: %w", s.id, err)
+		}
+		topic := fmt.Sprintf("%s/sensor/%s/%s/config", haPrefix, node, s.id)
+		if err := c.PublishRaw(topic, payload, true); err != nil {
+			return fmt.Errorf("publish %s: %w", s.id, err)
+		}
+	}
+	return nil
+}
+
+// haModel is the device model shown in Home Assistant for this platform.
+func haModel() string {
+	switch runtime.GOOS {
+	case "windows":
+		return "Windows PC"
+	case "darwin":
+		return "Mac"
+	case "linux":
+		return "Linux PC"
+	}
+	return runtime.GOOS
+}
@@ -0,0 +1,9 @@
+package main
+
+type VMStats struct {
+	Name       string `json:"name"`
+	CPU        uint16 `json:"cpu"`
+	Processors uint16 `json:"processors"`
+	MemMB      uint64 `json:"memMb"`
+	UptimeSec  uint64 `json:"uptimeSec"`
+}
@@ -0,0 +1,8 @@
+//go:build !windows
+
+package main
+
+// getHyperVStats reports Hyper-V as unavailable outside Windows.
+func getHyperVStats() ([]VMStats, bool) {
+	return nil, false
+}
@@ -0,0 +1,43 @@
+package main
+
+import (
+	"github.com/yusufpapurcu/wmi"
+)
+
+type msvmSummaryInformation struct {
+	ElementName        string
+	EnabledState       uint16
+	ProcessorLoad      uint16
+	NumberOfProcessors uint16
+	MemoryUsage        uint64
+	UpTime             uint64
+}
+
+const vmEnabledStateRunning = 2
+
+func getHyperVStats() ([]VMStats, bool) {
+	var dst []msvmSummaryInformation
+	err := wmi.QueryNamespace(
+		"SELECT ElementName, EnabledState, ProcessorLoad, NumberOfProcessors, MemoryUsage, UpTime FROM Msvm_SummaryInformation",
+		&dst,
+		`root\virtualization\v2`,
+	)
+	if err != nil {
+		return nil, false
+	}
+
+	var vms []VMStats
+	for _, s := range dst {
+		if s.EnabledState != vmEnabledStateRunning {
+			continue
+		}
+		vms = append(vms, VMStats{
+			Name:       s.ElementName,
+			CPU:        s.ProcessorLoad,
+			Processors: s.NumberOfProcessors,
+			MemMB:      s.MemoryUsage,
+			UptimeSec:  s.UpTime / 1000,
+		})
+	}
+	return vms, true
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"os"
+	"strings"
+	"sync"
+)
+
+// machineID is the ID the operating system generates at install time. It
+// survives renames and NAT, unlike the hostname or the source address.
+var machineID = sync.OnceValue(func() string {
+	if id := getEnv("M_MACHINE_ID", ""); id != "" {
+		return id
+	}
+	return systemMachineID()
+})
+
+// runID identifies this agent process, so the server can tell a restart
+// (Seq starting over) from lost or replayed messages.
+var runID = func() string {
+	b := make([]byte, 8)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}()
+
+func hostname() string {
+	h, _ := os.Hostname()
+	return h
+}
+
+// parseTags reads "key=value" pairs separated by commas.
+func parseTags(s string) map[string]string {
+	tags := map[string]string{}
+	for _, kv := range parseList(s) {
+		k, v, _ := strings.Cut(kv, "=")
+		if k = strings.TrimSpace(k); k != "" {
+			tags[k] = strings.TrimSpace(v)
+		}
+	}
+	if len(tags) == 0 {
+		return nil
+	}
+	return tags
+}
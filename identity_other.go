@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+import "github.com/shirou/gopsutil/v3/host"
+
+// systemMachineID returns /etc/machine-id on Linux and the hardware UUID on
+// macOS.
+func systemMachineID() string {
+	id, _ := host.HostID()
+	return id
+}
@@ -0,0 +1,14 @@
+package main
+
+import "golang.org/x/sys/windows/registry"
+
+// systemMachineID returns the MachineGuid Windows generates at install time.
+func systemMachineID() string {
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Cryptography`, registry.QUERY_VALUE|registry.WOW64_64KEY)
+	if err != nil {
+		return ""
+	}
+	defer k.Close()
+	id, _, _ := k.GetStringValue("MachineGuid")
+	return id
+}
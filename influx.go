@@ -0,0 +1,90 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+)
+
+type influxWriter struct {
+	endpoint string
+	token    string
+	host     string
+}
+
+func newInfluxWriter(base, org, bucket, token string) (*influxWriter, error) {
+	u, err := url.Parse(strings.TrimRight(base, "/") + "/api/v2/write")
+	if err != nil {
+		return nil, fmt.Errorf("parse influx url: %w", err)
+	}
+	if org == "" || bucket == "" {
+		return nil, fmt.Errorf("influx org and bucket are required")
+	}
+	q := url.Values{}
+	q.Set("org", org)
+	q.Set("bucket", bucket)
+	q.Set("precision", "s")
+	u.RawQuery = q.Encode()
+
+	host, _ := os.Hostname()
+	return &influxWriter{endpoint: u.String(), token: token, host: host}, nil
+}
+
+func (w *influxWriter) Destination() string {
+	u, _ := url.Parse(w.endpoint)
+	return u.Redacted()
+}
+
+func (w *influxWriter) Write(m Metrics) error {
+	req, err := http.NewRequest("POST", w.endpoint, strings.NewReader(formatLineProtocol(m, w.host)))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", "text/plain; charset=utf-8")
+	req.Header.Set("Authorization", "Token "+w.token)
+	req.Header.Set("User-Agent", "go-win-monitor/"+version)
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		httpClient.CloseIdleConnections()
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusNoContent && resp.StatusCode != http.StatusOK {
+		return &statusError{Code: resp.StatusCode}
+	}
+	return nil
+}
+
+// formatLineProtocol writes each sample as its own measurement with a single
+// "value" field, tagged with the host and the sample's labels.
+func formatLineProtocol(m Metrics, host string) string {
+	var b bytes.Buffer
+	ts := strconv.FormatInt(m.Timestamp.Unix(), 10)
+
+	visitSamples(m, func(name, help string, value float64, labels ...string) {
+		b.WriteString("winmon_" + name)
+		b.WriteString(",host=" + escapeTag(host))
+		for i := 0; i+1 < len(labels); i += 2 {
+			if labels[i+1] == "" {
+				continue
+			}
+			b.WriteString("," + escapeTag(labels[i]) + "=" + escapeTag(labels[i+1]))
+		}
+		b.WriteString(" value=" + strconv.FormatFloat(value, 'g', -1, 64))
+		b.WriteString(" " + ts + "\n")
+	})
+	return b.String()
+}
+
+func escapeTag(s string) string {
+	return strings.NewReplacer(`\`, `\\`, ",", `\,`, "=", `\=`, " ", `\ `, "\n", "").Replace(s)
+}
@@ -0,0 +1,40 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"syscall"
+)
+
+// instanceLock is held for the life of the process; the kernel drops the
+// lock when it exits, however it exits.
+var instanceLock *os.File
+
+// ensureSingleInstance exits when another agent already holds the lock file
+// in the data directory. There is no tray to show, so the second launch
+// only says so in the log.
+func ensureSingleInstance() {
+	dir := defaultDataDir()
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		slog.Warn("Single instance check failed", "err", err)
+		return
+	}
+	f, err := os.OpenFile(filepath.Join(dir, "monitor.lock"), os.O_CREATE|os.O_RDWR, 0o600)
+	if err != nil {
+		slog.Warn("Single instance check failed", "err", err)
+		return
+	}
+	if err := syscall.Flock(int(f.Fd()), syscall.LOCK_EX|syscall.LOCK_NB); err != nil {
+		f.Close()
+		if err == syscall.EWOULDBLOCK {
+			slog.Info("Another instance is already running, exiting")
+			os.Exit(0)
+		}
+		slog.Warn("Single instance check failed", "err", err)
+		return
+	}
+	instanceLock = f
+}
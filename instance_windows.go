@@ -0,0 +1,69 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+
+	"golang.org/x/sys/windows"
+)
+
+// The mutex is global so a tray agent and the service never both report
+// for the machine; the activation event is per session, since only a tray
+// agent in the same session has anything to show.
+const (
+	instanceMutexName = `Global\go-win-monitor`
+	activateEventName = `Local\go-win-monitor-activate`
+)
+
+// instanceMutex is held for the life of the process, or until a restart
+// hands over to the new executable.
+var instanceMutex windows.Handle
+
+// ensureSingleInstance exits when another agent is already running, after
+// asking it to let the user know.
+func ensureSingleInstance() {
+	name, _ := windows.UTF16PtrFromString(instanceMutexName)
+	var err error
+	instanceMutex, err = windows.CreateMutex(nil, false, name)
+	switch err {
+	case nil:
+		go waitForActivation()
+		return
+	case windows.ERROR_ALREADY_EXISTS, windows.ERROR_ACCESS_DENIED:
+	default:
+		slog.Warn("Single instance check failed", "err", err)
+		return
+	}
+
+	slog.Info("Another instance is already running, exiting")
+	event, _ := windows.UTF16PtrFromString(activateEventName)
+	h, err := windows.OpenEvent(windows.EVENT_MODIFY_STATE, false, event)
+	if err == nil {
+		windows.SetEvent(h)
+		windows.CloseHandle(h)
+	} else {
+		// Most likely the service, which has no tray to show.
+		showToast(tr("Computer Monitor is already running"), tr("Another instance, such as the Windows service, is already reporting for this computer"))
+	}
+	os.Exit(0)
+}
+
+// waitForActivation tells the user the agent is already running whenever a
+// second launch signals it, since that launch was probably meant to find
+// the tray icon.
+func waitForActivation() {
+	name, _ := windows.UTF16PtrFromString(activateEventName)
+	h, err := windows.CreateEvent(nil, 0, 0, name)
+	if err != nil {
+		slog.Warn("Activation event unavailable", "err", err)
+		return
+	}
+	for {
+		if ev, err := windows.WaitForSingleObject(h, windows.INFINITE); err != nil || ev != windows.WAIT_OBJECT_0 {
+			return
+		}
+		if !headless {
+			showToast(tr("Computer Monitor is already running"), tr("Its icon is in the notification area"))
+		}
+	}
+}
@@ -0,0 +1,33 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"time"
+)
+
+// pickInterval collects and sends every d from now on and saves it as the
+// config file's interval, so it survives a restart. It runs on the collection
+// loop's goroutine.
+func pickInterval(d time.Duration) {
+	interval = min(max(d, minInterval), maxInterval)
+	collectInterval, sendInterval = interval, interval
+	backoffMax = max(backoffMax, sendInterval)
+	slog.Info("Interval changed", "interval", interval)
+	updateIntervalMenu()
+
+	for _, k := range []string{"M_INTERVAL", "M_COLLECT_INTERVAL", "M_SEND_INTERVAL"} {
+		if flagSettings[k] != "" || os.Getenv(k) != "" {
+			slog.Warn("Setting outside the config file overrides the saved interval", "setting", k)
+		}
+	}
+	err := updateConfigFile(func(cfg map[string]any) error {
+		cfg["interval"] = interval.String()
+		delete(cfg, "collect_interval")
+		delete(cfg, "send_interval")
+		return nil
+	})
+	if err != nil {
+		slog.Error("Save interval failed", "err", err)
+	}
+}
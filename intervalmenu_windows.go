@@ -0,0 +1,51 @@
+package main
+
+import (
+	"sync"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+var intervalChoices = []time.Duration{5 * time.Second, 15 * time.Second, 30 * time.Second, time.Minute}
+
+var intervalMenu struct {
+	sync.Mutex
+	root  *systray.MenuItem
+	items map[time.Duration]*systray.MenuItem
+}
+
+func addIntervalMenu() {
+	intervalMenu.root = systray.AddMenuItem("", tr("Change how often metrics are collected and sent"))
+	intervalMenu.items = map[time.Duration]*systray.MenuItem{}
+	for _, d := range intervalChoices {
+		item := intervalMenu.root.AddSubMenuItemCheckbox(d.String(), "", false)
+		intervalMenu.items[d] = item
+		go func() {
+			for range item.ClickedCh {
+				select {
+				case commands <- Command{ID: "local", Type: "pickInterval", SendIntervalSec: int(d / time.Second)}:
+				default:
+				}
+			}
+		}()
+	}
+	updateIntervalMenu()
+}
+
+func updateIntervalMenu() {
+	if headless || intervalMenu.root == nil {
+		return
+	}
+	intervalMenu.Lock()
+	defer intervalMenu.Unlock()
+
+	intervalMenu.root.SetTitle(tr("Interval: %s", sendInterval))
+	for d, item := range intervalMenu.items {
+		if d == sendInterval && d == collectInterval {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+}
@@ -0,0 +1,65 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/cpu"
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+type Inventory struct {
+	Hostname   string         `json:"hostname"`
+	CPUModel   string         `json:"cpuModel"`
+	CPUCores   int            `json:"cpuCores"`
+	CPUThreads int            `json:"cpuThreads"`
+	RAMTotalMB uint64         `json:"ramTotalMb"`
+	RAMSpeed   uint32         `json:"ramSpeedMhz"`
+	GPUs       []GPUInfo      `json:"gpus"`
+	Disks      []DiskInfo     `json:"disks"`
+	Windows    WindowsVersion `json:"windows"`
+
+	Interfaces []NetworkInterface `json:"interfaces"`
+}
+
+type GPUInfo struct {
+	Name          string `json:"name"`
+	DriverVersion string `json:"driverVersion"`
+}
+
+type DiskInfo struct {
+	Model  string `json:"model"`
+	SizeGB uint64 `json:"sizeGb"`
+}
+
+type WindowsVersion struct {
+	Product        string `json:"product"`
+	Edition        string `json:"edition"`
+	DisplayVersion string `json:"displayVersion"`
+	Build          string `json:"build"`
+}
+
+func sendInventory() {
+	if err := publish("inventory", collectInventory()); err != nil {
+		slog.Warn("Inventory report failed", "err", err)
+	}
+}
+
+func collectInventory() Inventory {
+	var inv Inventory
+	inv.Hostname, _ = os.Hostname()
+
+	if info, err := cpu.Info(); err == nil && len(info) > 0 {
+		inv.CPUModel = strings.TrimSpace(info[0].ModelName)
+	}
+	inv.CPUCores, _ = cpu.Counts(false)
+	inv.CPUThreads, _ = cpu.Counts(true)
+
+	if vm, err := mem.VirtualMemory(); err == nil {
+		inv.RAMTotalMB = vm.Total / 1024 / 1024
+	}
+	inv.Interfaces = getNetworkInterfaces()
+	platformInventory(&inv)
+	return inv
+}
@@ -0,0 +1,19 @@
+//go:build !windows
+
+package main
+
+import "github.com/shirou/gopsutil/v3/host"
+
+func platformInventory(inv *Inventory) {
+	inv.Windows = getWindowsVersion()
+}
+
+// getWindowsVersion fills the operating system fields with the distribution
+// or macOS release and the kernel version, the closest there is to the
+// Windows product and build.
+func getWindowsVersion() WindowsVersion {
+	var v WindowsVersion
+	v.Product, v.Edition, v.DisplayVersion, _ = host.PlatformInformation()
+	v.Build, _ = host.KernelVersion()
+	return v
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"strconv"
+	"strings"
+
+	"github.com/yusufpapurcu/wmi"
+	"golang.org/x/sys/windows/registry"
+)
+
+type win32PhysicalMemory struct {
+	Speed uint32
+}
+
+type win32VideoController struct {
+	Name          string
+	DriverVersion string
+}
+
+type win32DiskDrive struct {
+	Model string
+	Size  uint64
+}
+
+// platformInventory adds what only WMI and the registry know: memory speed,
+// GPUs, disk models and the Windows release.
+func platformInventory(inv *Inventory) {
+	var modules []win32PhysicalMemory
+	if err := wmi.Query("SELECT Speed FROM Win32_PhysicalMemory", &modules); err == nil && len(modules) > 0 {
+		inv.RAMSpeed = modules[0].Speed
+	}
+
+	var gpus []win32VideoController
+	if err := wmi.Query("SELECT Name, DriverVersion FROM Win32_VideoController", &gpus); err == nil {
+		for _, g := range gpus {
+			inv.GPUs = append(inv.GPUs, GPUInfo{Name: g.Name, DriverVersion: g.DriverVersion})
+		}
+	}
+
+	var disks []win32DiskDrive
+	if err := wmi.Query("SELECT Model, Size FROM Win32_DiskDrive", &disks); err == nil {
+		for _, d := range disks {
+			inv.Disks = append(inv.Disks, DiskInfo{Model: d.Model, SizeGB: d.Size / 1000 / 1000 / 1000})
+		}
+	}
+
+	inv.Windows = getWindowsVersion()
+}
+
+func getWindowsVersion() WindowsVersion {
+	var v WindowsVersion
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SOFTWARE\Microsoft\Windows NT\CurrentVersion`, registry.QUERY_VALUE)
+	if err != nil {
+		return v
+	}
+	defer k.Close()
+
+	v.Product, _, _ = k.GetStringValue("ProductName")
+	v.Edition, _, _ = k.GetStringValue("EditionID")
+	v.DisplayVersion, _, _ = k.GetStringValue("DisplayVersion")
+	v.Build, _, _ = k.GetStringValue("CurrentBuild")
+
+	// Windows 11 still reports "Windows 10" in ProductName.
+	if build, err := strconv.Atoi(v.Build); err == nil && build >= 22000 {
+		v.Product = strings.Replace(v.Product, "Windows 10", "Windows 11", 1)
+	}
+	return v
+}
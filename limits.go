@@ -0,0 +1,86 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+var errRateLimited = errors.New("rate limit exceeded")
+
+// limiter is a token bucket refilled at rate messages per minute, holding
+// at most one minute's worth.
+type limiter struct {
+	mu     sync.Mutex
+	rate   float64
+	tokens float64
+	last   time.Time
+}
+
+var transmitLimiter = &limiter{rate: float64(rateLimit), tokens: float64(rateLimit)}
+
+func (l *limiter) Allow() bool {
+	if l.rate <= 0 {
+		return true
+	}
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	now := time.Now()
+	if !l.last.IsZero() {
+		l.tokens = min(l.rate, l.tokens+now.Sub(l.last).Minutes()*l.rate)
+	}
+	l.last = now
+	if l.tokens < 1 {
+		return false
+	}
+	l.tokens--
+	return true
+}
+
+// limitMetrics caps every list at maxListItems, then drops whole lists,
+// least important first, until the JSON form fits in maxPayloadKB.
+func limitMetrics(m Metrics) Metrics {
+	capList(&m, &m.TopNetwork)
+	capList(&m, &m.Interfaces)
+	capList(&m, &m.Watched)
+	capList(&m, &m.Services)
+	capList(&m, &m.Containers)
+	capList(&m, &m.VMs)
+	capList(&m, &m.Pings)
+	capList(&m, &m.Fans)
+	capList(&m, &m.DiskTemps)
+	capList(&m, &m.GPUProcesses)
+
+	drops := []func(){
+		func() { m.Aggregates = nil },
+		func() { m.TopNetwork = nil },
+		func() { m.Interfaces = nil },
+		func() { m.Containers = nil },
+		func() { m.VMs = nil },
+		func() { m.Services = nil },
+		func() { m.Watched = nil },
+		func() { m.Pings = nil },
+	}
+	for _, drop := range drops {
+		b, err := json.Marshal(m)
+		if err != nil || len(b) <= maxPayloadKB*1024 {
+			break
+		}
+		drop()
+		m.Truncated = true
+	}
+	if m.Truncated {
+		slog.Warn("Metrics payload too large, dropped some lists", "limitKB", maxPayloadKB)
+	}
+	return m
+}
+
+func capList[T any](m *Metrics, list *[]T) {
+	if maxListItems > 0 && len(*list) > maxListItems {
+		*list = (*list)[:maxListItems]
+		m.Truncated = true
+	}
+}
@@ -0,0 +1,120 @@
+package main
+
+import (
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"time"
+)
+
+// startLocalAPI serves a read-only JSON API for scripts on this machine:
+//
+//	GET /api/metrics/current
+//	GET /api/metrics/history?since=15m&limit=100
+//	GET /api/metrics/stats?window=5m
+//	GET /api/metrics/schema
+//
+// since is a duration back from now or an RFC 3339 time; limit keeps the
+// newest samples. With M_HISTORY_DB set, history comes from the SQLite store
+// instead of memory. schema describes the families of the latest sample,
+// like the metadata message.
+func startLocalAPI(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("GET /api/metrics/current", handleCurrent)
+	mux.HandleFunc("GET /api/metrics/history", handleHistory)
+	mux.HandleFunc("GET /api/metrics/stats", handleStats)
+	mux.HandleFunc("GET /api/metrics/schema", handleSchema)
+	if dashboard {
+		mux.HandleFunc("GET /{$}", handleDashboard)
+		mux.HandleFunc("GET /ws", handleLiveFeed)
+	}
+
+	go func() {
+		slog.Info("Local API listening", "url", "http://"+addr+"/api/metrics/current")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Local API listener failed", "err", err)
+		}
+	}()
+}
+
+func writeJSON(w http.ResponseWriter, v any) {
+	w.Header().Set("Content-Type", "application/json")
+	json.NewEncoder(w).Encode(v)
+}
+
+func handleCurrent(w http.ResponseWriter, r *http.Request) {
+	m, ok := latestMetrics()
+	if !ok {
+		http.Error(w, "no metrics collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, m)
+}
+
+func handleSchema(w http.ResponseWriter, r *http.Request) {
+	m, ok := latestMetrics()
+	if !ok {
+		http.Error(w, "no metrics collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	writeJSON(w, describeMetrics(m))
+}
+
+// handleStats reports min/max/avg per series over the in-memory history.
+func handleStats(w http.ResponseWriter, r *http.Request) {
+	window := 5 * time.Minute
+	if s := r.URL.Query().Get("window"); s != "" {
+		d, err := time.ParseDuration(s)
+		if err != nil || d <= 0 {
+			http.Error(w, "invalid window", http.StatusBadRequest)
+			return
+		}
+		window = d
+	}
+	writeJSON(w, history.Stats(window))
+}
+
+func handleHistory(w http.ResponseWriter, r *http.Request) {
+	var since time.Time
+	if s := r.URL.Query().Get("since"); s != "" {
+		if d, err := time.ParseDuration(s); err == nil {
+			since = time.Now().Add(-d)
+		} else if t, err := time.Parse(time.RFC3339, s); err == nil {
+			since = t
+		} else {
+			http.Error(w, "invalid since", http.StatusBadRequest)
+			return
+		}
+	}
+
+	limit := -1
+	if s := r.URL.Query().Get("limit"); s != "" {
+		n, err := strconv.Atoi(s)
+		if err != nil || n < 0 {
+			http.Error(w, "invalid limit", http.StatusBadRequest)
+			return
+		}
+		limit = n
+	}
+
+	if historyDB != nil {
+		if limit == 0 {
+			writeJSON(w, []Metrics{})
+			return
+		}
+		samples, err := historyDB.Since(since, limit)
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		writeJSON(w, samples)
+		return
+	}
+
+	samples := history.Since(since)
+	if limit >= 0 && len(samples) > limit {
+		samples = samples[len(samples)-limit:]
+	}
+	writeJSON(w, samples)
+}
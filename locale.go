@@ -0,0 +1,92 @@
+package main
+
+import (
+	"embed"
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Locale files map the English tray and notification text, which is also
+// the key, to its translation. Files in the data directory's locales folder
+// add languages or override the built-in ones.
+//
+//go:embed locales/*.json
+var localeFiles embed.FS
+
+var translations = loadTranslations(uiLanguage())
+
+// uiLanguage returns M_LANG, else the user's display language, e.g. pt-BR.
+func uiLanguage() string {
+	if l := getEnv("M_LANG", ""); l != "" {
+		return l
+	}
+	return systemLanguage()
+}
+
+// loadTranslations finds the locale file for lang, falling back from a
+// regional variant to any file of the same language, so pt-PT still gets
+// pt-BR. English needs no file.
+func loadTranslations(lang string) map[string]string {
+	base, _, _ := strings.Cut(lang, "-")
+	if strings.EqualFold(base, "en") {
+		return nil
+	}
+
+	var names []string
+	if entries, err := localeFiles.ReadDir("locales"); err == nil {
+		for _, e := range entries {
+			names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	dir := filepath.Join(defaultDataDir(), "locales")
+	if entries, err := os.ReadDir(dir); err == nil {
+		for _, e := range entries {
+			names = append(names, strings.TrimSuffix(e.Name(), ".json"))
+		}
+	}
+	name := ""
+	for _, n := range names {
+		if strings.EqualFold(n, lang) {
+			name = n
+			break
+		}
+		if b, _, _ := strings.Cut(n, "-"); name == "" && strings.EqualFold(b, base) {
+			name = n
+		}
+	}
+	if name == "" {
+		slog.Info("No translation, using English", "lang", lang)
+		return nil
+	}
+
+	t := map[string]string{}
+	for _, read := range []func() ([]byte, error){
+		func() ([]byte, error) { return localeFiles.ReadFile("locales/" + name + ".json") },
+		func() ([]byte, error) { return os.ReadFile(filepath.Join(dir, name+".json")) },
+	} {
+		data, err := read()
+		if err != nil {
+			continue
+		}
+		if err := json.Unmarshal(data, &t); err != nil {
+			slog.Warn("Locale unreadable", "file", name, "err", err)
+		}
+	}
+	return t
+}
+
+// tr translates s and, given args, formats it like fmt.Sprintf. Text with
+// no translation is used as is.
+func tr(s string, args ...any) string {
+	if t, ok := translations[s]; ok {
+		s = t
+	}
+	if len(args) == 0 {
+		return s
+	}
+	return fmt.Sprintf(s, args...)
+}
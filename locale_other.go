@@ -0,0 +1,24 @@
+//go:build !windows
+
+package main
+
+import (
+	"os"
+	"strings"
+)
+
+// systemLanguage turns the POSIX locale, e.g. pt_BR.UTF-8, into a language
+// tag. The C and POSIX locales mean English.
+func systemLanguage() string {
+	for _, key := range []string{"LC_ALL", "LC_MESSAGES", "LANG"} {
+		l, _, _ := strings.Cut(os.Getenv(key), ".")
+		if l == "" {
+			continue
+		}
+		if l == "C" || l == "POSIX" {
+			return "en"
+		}
+		return strings.ReplaceAll(l, "_", "-")
+	}
+	return "en"
+}
@@ -0,0 +1,12 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+// systemLanguage returns the user's preferred Windows display language.
+func systemLanguage() string {
+	langs, err := windows.GetUserPreferredUILanguages(windows.MUI_LANGUAGE_NAME)
+	if err != nil || len(langs) == 0 {
+		return "en"
+	}
+	return langs[0]
+}
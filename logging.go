@@ -0,0 +1,193 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+)
+
+// logLevel is the level below which records are dropped. It is set from
+// M_LOG_LEVEL at start and again on every config reload, and by the
+// server's setLogLevel command.
+var logLevel = new(slog.LevelVar)
+
+// levelOff is above every level used, so nothing is logged.
+const levelOff = slog.Level(100)
+
+var (
+	logFormat  = getEnv("M_LOG_FORMAT", "console")
+	logFile    = getEnv("M_LOG_FILE", filepath.Join(defaultDataDir(), "logs", "agent.log"))
+	logMaxMB   = getInt("M_LOG_MAX_MB", 10)
+	logBackups = getInt("M_LOG_BACKUPS", 3)
+)
+
+// agentLogFile is the open log file, closed by shutdown.
+var agentLogFile *rotatingFile
+
+// Records carrying an "event" attribute with one of these IDs, and every
+// error, also go to the service log (the Windows Application event log),
+// so admins can filter and alert on agent health with the usual tools.
+const (
+	evtError        = 1
+	evtStarted      = 100
+	evtStopped      = 101
+	evtConnected    = 110
+	evtDisconnected = 111
+	evtAuthFailed   = 112
+	evtAlert        = 120
+	evtCrash        = 130
+)
+
+// initLogging sends every record, including those of packages still using
+// the standard log package, to the rotating log file, the log viewer's
+// buffer and stderr, as console text or, with M_LOG_FORMAT=json, as JSON
+// lines. A service has no stderr; it sends the significant events to the
+// service log instead.
+func initLogging() {
+	setLogLevel(getEnv("M_LOG_LEVEL", "info"))
+
+	writers := []io.Writer{logBuffer}
+	if !runningAsService {
+		writers = append(writers, os.Stderr)
+	}
+	var fileErr error
+	if logFile != "" && logFile != "off" {
+		f, err := openRotatingFile(logFile, int64(max(logMaxMB, 1))*mb, logBackups)
+		if err != nil {
+			fileErr = err
+		} else {
+			agentLogFile = f
+			writers = append(writers, f)
+		}
+	}
+
+	opts := &slog.HandlerOptions{Level: logLevel}
+	var h slog.Handler = slog.NewTextHandler(io.MultiWriter(writers...), opts)
+	if logFormat == "json" {
+		h = slog.NewJSONHandler(io.MultiWriter(writers...), opts)
+	}
+	var serviceErr error
+	if runningAsService {
+		var sh slog.Handler
+		if sh, serviceErr = serviceLogHandler(); serviceErr == nil {
+			h = slog.NewMultiHandler(h, sh)
+		}
+	}
+	slog.SetDefault(slog.New(h))
+	if logFormat != "json" && logFormat != "console" {
+		slog.Warn("Unknown log format, using console", "format", logFormat)
+	}
+	if fileErr != nil {
+		slog.Warn("Log file disabled", "path", logFile, "err", fileErr)
+	}
+	if serviceErr != nil {
+		slog.Warn("Event log unavailable", "err", serviceErr)
+	}
+}
+
+// setLogLevel applies debug, info, warn, error or off.
+func setLogLevel(name string) bool {
+	switch strings.ToLower(name) {
+	case "debug":
+		logLevel.Set(slog.LevelDebug)
+	case "info", "":
+		logLevel.Set(slog.LevelInfo)
+	case "warn", "warning":
+		logLevel.Set(slog.LevelWarn)
+	case "error":
+		logLevel.Set(slog.LevelError)
+	case "off":
+		logLevel.Set(levelOff)
+	default:
+		slog.Warn("Unknown log level, keeping the current one", "level", name, "current", logLevel.Level())
+		return false
+	}
+	return true
+}
+
+// rotatingFile appends to path and, once a write would take it over max
+// bytes, renames it to path.1 (shifting older ones up to path.<backups>)
+// and starts a new file.
+type rotatingFile struct {
+	mu      sync.Mutex
+	path    string
+	max     int64
+	backups int
+	f       *os.File
+	size    int64
+}
+
+func openRotatingFile(path string, max int64, backups int) (*rotatingFile, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, err
+	}
+	r := &rotatingFile{path: path, max: max, backups: backups}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+func (r *rotatingFile) open() error {
+	f, err := os.OpenFile(r.path, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o600)
+	if err != nil {
+		return err
+	}
+	info, err := f.Stat()
+	if err != nil {
+		f.Close()
+		return err
+	}
+	r.f, r.size = f, info.Size()
+	return nil
+}
+
+func (r *rotatingFile) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		if err := r.open(); err != nil {
+			return 0, err
+		}
+	}
+	if r.size > 0 && r.size+int64(len(p)) > r.max {
+		if err := r.rotate(); err != nil {
+			return 0, err
+		}
+	}
+	n, err := r.f.Write(p)
+	r.size += int64(n)
+	return n, err
+}
+
+// Close flushes the file to disk. A later write opens it again.
+func (r *rotatingFile) Close() error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if r.f == nil {
+		return nil
+	}
+	r.f.Sync()
+	err := r.f.Close()
+	r.f = nil
+	return err
+}
+
+func (r *rotatingFile) rotate() error {
+	r.f.Close()
+	r.f = nil
+	if r.backups <= 0 {
+		os.Remove(r.path)
+	} else {
+		os.Remove(fmt.Sprintf("%s.%d", r.path, r.backups))
+		for i := r.backups - 1; i >= 1; i-- {
+			os.Rename(fmt.Sprintf("%s.%d", r.path, i), fmt.Sprintf("%s.%d", r.path, i+1))
+		}
+		os.Rename(r.path, r.path+".1")
+	}
+	return r.open()
+}
@@ -0,0 +1,77 @@
+package main
+
+import (
+	_ "embed"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+//go:embed web/logs.html
+var logsHTML []byte
+
+const logLines = 500
+
+// logBuffer keeps the last logLines lines written to the log, for the log
+// viewer: the GUI build has no console to read them from.
+var logBuffer = &logRing{}
+
+type logRing struct {
+	mu      sync.Mutex
+	lines   []string
+	next    int
+	partial string
+}
+
+func (r *logRing) Write(p []byte) (int, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	s := r.partial + string(p)
+	for {
+		line, rest, ok := strings.Cut(s, "\n")
+		if !ok {
+			break
+		}
+		if len(r.lines) < logLines {
+			r.lines = append(r.lines, line)
+		} else {
+			r.lines[r.next] = line
+			r.next = (r.next + 1) % logLines
+		}
+		s = rest
+	}
+	r.partial = s
+	return len(p), nil
+}
+
+// String returns the buffered lines, oldest first.
+func (r *logRing) String() string {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var b strings.Builder
+	for i := range r.lines {
+		b.WriteString(r.lines[(r.next+i)%len(r.lines)])
+		b.WriteString("\n")
+	}
+	return b.String()
+}
+
+func openLogs() {
+	openUI("logs")
+}
+
+func handleLogs(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	w.Write(logsHTML)
+}
+
+// handleLogText serves the buffered log, as a file download when asked to.
+func handleLogText(w http.ResponseWriter, r *http.Request) {
+	w.Header().Set("Content-Type", "text/plain; charset=utf-8")
+	if r.URL.Query().Get("download") == "1" {
+		name := "go-win-monitor-" + time.Now().Format("20060102-150405") + ".log"
+		w.Header().Set("Content-Disposition", `attachment; filename="`+name+`"`)
+	}
+	w.Write([]byte(logBuffer.String()))
+}
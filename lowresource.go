@@ -0,0 +1,58 @@
+package main
+
+import (
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+var (
+	lowResourceAuto     = getEnv("M_LOW_RESOURCE", "") == "1"
+	lowResourceLoad     = float64(getInt("M_LOW_RESOURCE_LOAD", 90))
+	lowResourceInterval = getInterval("M_LOW_RESOURCE_INTERVAL", time.Minute)
+)
+
+// lowResource is set while low-resource mode is on; the SMART, Windows
+// Update and event log collectors hold off until it ends.
+var lowResource atomic.Bool
+
+// lowResourceMode turns on, with M_LOW_RESOURCE=1, while the machine is on
+// battery or its CPU or GPU is at or above M_LOW_RESOURCE_LOAD, so the agent
+// does not add to the problem it is measuring: it runs at background
+// priority (the Windows background mode, niced elsewhere), collects and sends
+// at most every M_LOW_RESOURCE_INTERVAL and skips the expensive collectors.
+// Like adaptiveMode it takes a 10 point margin to leave a load-triggered
+// mode.
+type lowResourceMode struct {
+	on bool
+}
+
+func (l *lowResourceMode) update(m Metrics) bool {
+	if !lowResourceAuto {
+		return false
+	}
+	battery := onBattery()
+	load := max(m.CPU, m.GPU)
+	on := battery || load >= lowResourceLoad || l.on && load >= lowResourceLoad-adaptiveMargin
+	if on == l.on {
+		return on
+	}
+	l.on = on
+	lowResource.Store(on)
+	if on {
+		slog.Info("Entering low-resource mode", "battery", battery, "load", load)
+		setBackgroundPriority(true)
+	} else {
+		slog.Info("Leaving low-resource mode", "load", load)
+		setBackgroundPriority(false)
+	}
+	return on
+}
+
+// waitOutLowResource holds an expensive collector until low-resource mode
+// ends.
+func waitOutLowResource() {
+	for lowResource.Load() {
+		time.Sleep(time.Minute)
+	}
+}
@@ -0,0 +1,13 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// onBattery asks pmset which source the machine draws from. Desktops and
+// machines that do not know say no.
+func onBattery() bool {
+	out, err := exec.Command("pmset", "-g", "batt").Output()
+	return err == nil && strings.Contains(string(out), "'Battery Power'")
+}
@@ -0,0 +1,15 @@
+package main
+
+import "path/filepath"
+
+// onBattery reports whether a battery is discharging. Desktops and
+// machines without a power_supply class say no.
+func onBattery() bool {
+	supplies, _ := filepath.Glob("/sys/class/power_supply/*")
+	for _, dir := range supplies {
+		if readSysfs(dir+"/type") == "Battery" && readSysfs(dir+"/status") == "Discharging" {
+			return true
+		}
+	}
+	return false
+}
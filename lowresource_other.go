@@ -0,0 +1,20 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"syscall"
+)
+
+// setBackgroundPriority nices the agent, which on Linux and macOS lowers
+// its CPU share only.
+func setBackgroundPriority(on bool) {
+	nice := 0
+	if on {
+		nice = 10
+	}
+	if err := syscall.Setpriority(syscall.PRIO_PROCESS, 0, nice); err != nil {
+		slog.Warn("Process priority not changed", "err", err)
+	}
+}
@@ -0,0 +1,39 @@
+package main
+
+import (
+	"log/slog"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var procGetSystemPowerStatus = kernel32.NewProc("GetSystemPowerStatus")
+
+type systemPowerStatus struct {
+	ACLineStatus        byte
+	BatteryFlag         byte
+	BatteryLifePercent  byte
+	SystemStatusFlag    byte
+	BatteryLifeTime     uint32
+	BatteryFullLifeTime uint32
+}
+
+// onBattery reports whether the machine runs off its battery. Desktops and
+// machines that do not know say no.
+func onBattery() bool {
+	var s systemPowerStatus
+	if r, _, _ := procGetSystemPowerStatus.Call(uintptr(unsafe.Pointer(&s))); r == 0 {
+		return false
+	}
+	return s.ACLineStatus == 0
+}
+
+func setBackgroundPriority(on bool) {
+	mode := uint32(windows.PROCESS_MODE_BACKGROUND_END)
+	if on {
+		mode = windows.PROCESS_MODE_BACKGROUND_BEGIN
+	}
+	if err := windows.SetPriorityClass(windows.CurrentProcess(), mode); err != nil {
+		slog.Warn("Process priority not changed", "err", err)
+	}
+}
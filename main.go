@@ -1,195 +1,613 @@
 package main
 
 import (
-	"bytes"
-	_ "embed"
-	"encoding/json"
+	"context"
+	"errors"
 	"fmt"
-	"log"
-	"net/http"
+	"log/slog"
 	"os"
-	"os/exec"
+	"os/signal"
+	"path/filepath"
 	"strconv"
-	"strings"
-	"sync"
+	"sync/atomic"
 	"syscall"
 	"time"
-
-	"github.com/getlantern/systray"
-	"github.com/shirou/gopsutil/v3/cpu"
-	"github.com/shirou/gopsutil/v3/mem"
 )
 
-//go:embed app.ico
-var iconData []byte
-
 type Metrics struct {
+	Timestamp time.Time         `json:"timestamp"`
+	MachineID string            `json:"machineId"`
+	Hostname  string            `json:"hostname"`
+	Tags      map[string]string `json:"tags,omitempty"`
+	RunID     string            `json:"runId"`
+	Seq       uint64            `json:"seq"`
+
 	CPU        float64 `json:"cpu"`
 	RAM        float64 `json:"ram"`
 	RAMUsedMB  uint64  `json:"ramUsedMb"`
 	RAMTotalMB uint64  `json:"ramTotalMb"`
 	GPU        float64 `json:"gpu"`
+	GPUEncoder float64 `json:"gpuEncoder"`
+	GPUDecoder float64 `json:"gpuDecoder"`
+	GPUCoreMHz float64 `json:"gpuCoreClockMhz"`
+	GPUMemMHz  float64 `json:"gpuMemClockMhz"`
+	GPUTempC   float64 `json:"gpuTempC"`
+
+	CPUTempC     float64 `json:"cpuTempC"`
+	CPUThrottled bool    `json:"cpuThrottled"`
+	GPUThrottled bool    `json:"gpuThrottled"`
+
+	RAMAvailableMB uint64 `json:"ramAvailableMb"`
+	RAMCachedMB    uint64 `json:"ramCachedMb"`
+	PagedPoolMB    uint64 `json:"pagedPoolMb"`
+	NonPagedPoolMB uint64 `json:"nonPagedPoolMb"`
+
+	CommitUsedMB    uint64  `json:"commitUsedMb"`
+	CommitLimitMB   uint64  `json:"commitLimitMb"`
+	Pagefile        float64 `json:"pagefile"`
+	PagefileUsedMB  uint64  `json:"pagefileUsedMb"`
+	PagefileTotalMB uint64  `json:"pagefileTotalMb"`
+
+	CPUFreqMHz     uint32 `json:"cpuFreqMhz"`
+	CPUBaseFreqMHz uint32 `json:"cpuBaseFreqMhz"`
+
+	BootTime      uint64 `json:"bootTime"`
+	UptimeSec     uint64 `json:"uptimeSec"`
+	PendingReboot bool   `json:"pendingReboot"`
+
+	Pings []PingResult `json:"pings,omitempty"`
+
+	WiFiSSID   string `json:"wifiSsid,omitempty"`
+	WiFiSignal int    `json:"wifiSignal"`
+	PublicIP   string `json:"publicIp,omitempty"`
+
+	Fans      []FanReading      `json:"fans,omitempty"`
+	Sensors   []SensorReading   `json:"sensors,omitempty"`
+	DiskTemps []DiskTemperature `json:"diskTemps,omitempty"`
+	Volumes   []VolumeUsage     `json:"volumes,omitempty"`
+
+	// GPUProcesses lists what holds memory on the NVIDIA card, with
+	// M_GPU_PROCESSES=1.
+	GPUProcesses []GPUProcess `json:"gpuProcesses,omitempty"`
+
+	NetSentBps float64 `json:"netSentBps"`
+	NetRecvBps float64 `json:"netRecvBps"`
+
+	TCP        *TCPStats         `json:"tcp,omitempty"`
+	TopNetwork []ProcessNetwork  `json:"topNetwork,omitempty"`
+	Interfaces []InterfaceErrors `json:"interfaces,omitempty"`
+	DataUsage  *DataUsage        `json:"dataUsage,omitempty"`
+
+	Processes uint32 `json:"processes"`
+	Threads   uint32 `json:"threads"`
+	Handles   uint32 `json:"handles"`
+
+	Watched  []WatchedProcess `json:"watched,omitempty"`
+	Services []ServiceStatus  `json:"services,omitempty"`
+
+	Containers []ContainerStats `json:"containers,omitempty"`
+	VMs        []VMStats        `json:"vms,omitempty"`
+
+	ForegroundApp string `json:"foregroundApp,omitempty"`
+	IdleSec       uint64 `json:"idleSec"`
+	SessionLocked bool   `json:"sessionLocked"`
+
+	Users []UserSession `json:"users,omitempty"`
+
+	// LatencyMs is the round trip of the previous report, -1 before the
+	// first one has been answered.
+	LatencyMs float64 `json:"latencyMs"`
+
+	// ClockOffsetMs is how far the clock is ahead of M_NTP_SERVER.
+	ClockOffsetMs *float64 `json:"clockOffsetMs,omitempty"`
+
+	Agent *AgentStats `json:"agent,omitempty"`
+
+	// Counters holds the M_COUNTERS performance counters under their names.
+	Counters map[string]float64 `json:"counters,omitempty"`
+
+	// WMI holds the rows of each [[wmi_queries]] query, by query name and
+	// then instance.
+	WMI map[string]map[string]map[string]any `json:"wmi,omitempty"`
+
+	// Plugins holds each exec plugin's values under its name.
+	Plugins map[string]map[string]any `json:"plugins,omitempty"`
+
+	// Scripts holds the metrics computed by the Starlark scripts.
+	Scripts map[string]float64 `json:"scripts,omitempty"`
+
+	// Errors holds why each collector that failed this tick has no
+	// readings, by collector name.
+	Errors map[string]string `json:"errors,omitempty"`
+
+	// Aggregates holds the min, max and average of each series over the
+	// samples collected since the previous report, keyed like the alert
+	// rules' series, e.g. cpu_usage_percent or disk_temperature_celsius{disk="C:"}.
+	Aggregates map[string]Aggregate `json:"aggregates,omitempty"`
+
+	Truncated bool `json:"truncated,omitempty"`
 }
 
 var (
-	apiURL = getEnv("M_API_URL", "")
-	secret = getEnv("M_AGENT_SECRET", "")
+	version   = "dev"
+	buildDate = ""
 )
 
 var (
-	menuCPU    *systray.MenuItem
-	menuRAM    *systray.MenuItem
-	menuGPU    *systray.MenuItem
-	menuStatus *systray.MenuItem
-	menuMu     sync.Mutex
+	apiURL       = getEnv("M_API_URL", "")
+	secret       = loadSecret()
+	extraAPIURLs = parseList(getEnv("M_EXTRA_API_URLS", ""))
+	pings        = parseList(getEnv("M_PING_HOSTS", ""))
+	watch        = parseList(getEnv("M_WATCH_PROCESSES", ""))
+	services     = parseList(getEnv("M_WATCH_SERVICES", ""))
+	eventLogs    = parseList(getEnv("M_EVENT_LOGS", ""))
+	ipLookup     = getEnv("M_PUBLIC_IP_URL", "")
+	etwNet       = getEnv("M_ETW_NETWORK", "") == "1" || collectorOptIn("etw-network")
+	tags         = parseTags(getEnv("M_TAGS", ""))
+	collectors   = parseList(getEnv("M_COLLECTORS", ""))
+
+	remoteProcessControl = getEnv("M_REMOTE_PROCESS_CONTROL", "") == "1"
+)
+
+const (
+	minInterval = time.Second
+	maxInterval = 10 * time.Minute
+)
+
+var (
+	interval          = getInterval("M_INTERVAL", 30*time.Second)
+	collectInterval   = getInterval("M_COLLECT_INTERVAL", interval)
+	sendInterval      = max(getInterval("M_SEND_INTERVAL", interval), collectInterval)
+	backoffMax        = max(getInterval("M_BACKOFF_MAX", 5*time.Minute), sendInterval)
+	bufferSize        = getInt("M_BUFFER_SIZE", 120)
+	spoolMaxMB        = getInt("M_SPOOL_MAX_MB", 0)
+	spoolPath         = getEnv("M_SPOOL_PATH", filepath.Join(defaultDataDir(), "spool.jsonl"))
+	prometheusAddr    = getEnv("M_PROMETHEUS_ADDR", "")
+	pipeName          = getEnv("M_PIPE_NAME", "")
+	localAPIAddr      = getEnv("M_LOCAL_API_ADDR", "")
+	dashboard         = getEnv("M_DASHBOARD", "") == "1"
+	dashboardURL      = getEnv("M_DASHBOARD_URL", "")
+	trayIconMode      = getEnv("M_TRAY_ICON", "")
+	iconWarn          = float64(getInt("M_ICON_WARN", 70))
+	iconCrit          = float64(getInt("M_ICON_CRIT", 90))
+	iconTempWarn      = float64(getInt("M_ICON_TEMP_WARN", 55))
+	iconTempCrit      = float64(getInt("M_ICON_TEMP_CRIT", 65))
+	historySize       = getInt("M_HISTORY_SIZE", 720)
+	historyDBPath     = getEnv("M_HISTORY_DB", "")
+	historyDays       = getInt("M_HISTORY_DAYS", 7)
+	exportPath        = getEnv("M_EXPORT_PATH", filepath.Join(defaultDataDir(), "metrics.csv"))
+	exportMaxMB       = getInt("M_EXPORT_MAX_MB", 50)
+	heartbeatInterval = getInterval("M_HEARTBEAT_INTERVAL", 15*time.Second)
+	deltaMode         = getEnv("M_DELTA", "") == "1"
+	deltaThreshold    = float64(getInt("M_DELTA_THRESHOLD", 5))
+	deltaFullEvery    = getInterval("M_DELTA_FULL_INTERVAL", 10*time.Minute)
+
+	adaptive     = getEnv("M_ADAPTIVE", "") == "1"
+	adaptiveFast = getInterval("M_ADAPTIVE_FAST", 2*time.Second)
+	adaptiveSlow = getInterval("M_ADAPTIVE_SLOW", time.Minute)
+	adaptiveHigh = float64(getInt("M_ADAPTIVE_HIGH", 80))
+	adaptiveLow  = float64(getInt("M_ADAPTIVE_LOW", 10))
+
+	quietCollect = getEnv("M_QUIET_COLLECT", "1") == "1"
+
+	rateLimit    = getInt("M_RATE_LIMIT", 120)
+	maxPayloadKB = getInt("M_MAX_PAYLOAD_KB", 256)
+	maxListItems = getInt("M_MAX_LIST_ITEMS", 50)
+)
+
+var (
+	transportName = getEnv("M_TRANSPORT", "http")
+	mqttURL       = getEnv("M_MQTT_URL", "")
+	mqttTopic     = getEnv("M_MQTT_TOPIC", "go-win-monitor")
+	mqttQoS       = getInt("M_MQTT_QOS", 0)
+	mqttUser      = getEnv("M_MQTT_USER", "")
+	mqttPassword  = getEnv("M_MQTT_PASSWORD", "")
+	haDiscovery   = getEnv("M_MQTT_HA_DISCOVERY", "1") == "1"
+	haPrefix      = getEnv("M_MQTT_HA_PREFIX", "homeassistant")
+	influxURL     = getEnv("M_INFLUX_URL", "")
+	influxOrg     = getEnv("M_INFLUX_ORG", "")
+	influxBucket  = getEnv("M_INFLUX_BUCKET", "")
+	influxToken   = getEnv("M_INFLUX_TOKEN", "")
+	statsdAddr    = getEnv("M_STATSD_ADDR", "")
+	graphiteAddr  = getEnv("M_GRAPHITE_ADDR", "")
+	otlpEndpoint  = getEnv("M_OTLP_ENDPOINT", "")
+	otlpHeaders   = getEnv("M_OTLP_HEADERS", "")
+	grpcAddr      = getEnv("M_GRPC_ADDR", "")
+	grpcTLS       = getEnv("M_GRPC_TLS", "") == "1"
+)
+
+var (
+	tlsCAFile     = getEnv("M_TLS_CA_FILE", "")
+	tlsMinVersion = getEnv("M_TLS_MIN_VERSION", "")
+	tlsServerName = getEnv("M_TLS_SERVER_NAME", "")
+	tlsInsecure   = getEnv("M_TLS_INSECURE", "") == "1"
+
+	tlsCertFile    = getEnv("M_TLS_CERT_FILE", "")
+	tlsKeyFile     = getEnv("M_TLS_KEY_FILE", "")
+	tlsCertSubject = getEnv("M_TLS_CERT_SUBJECT", "")
+
+	proxySetting    = getEnv("M_PROXY", "")
+	compressBodies  = getEnv("M_COMPRESS", "") == "1"
+	payloadEncoding = getEnv("M_ENCODING", "json")
+	authMode        = getEnv("M_AUTH", "hmac")
+	sessionTokens   = getEnv("M_SESSION_TOKENS", "") == "1"
+
+	payloadEncryptionKey = getEnv("M_PAYLOAD_KEY", "")
+	extraHeaders         = parseTags(getEnv("M_HEADERS", ""))
 )
 
 func getEnv(key, fallback string) string {
-	if v := os.Getenv(key); v != "" {
+	if v := lookupSetting(key); v != "" {
 		return v
 	}
 	return fallback
 }
 
-func main() {
-	systray.Run(onReady, onExit)
-}
+func getInt(key string, fallback int) int {
+	v := lookupSetting(key)
+	if v == "" {
+		return fallback
+	}
 
-func onReady() {
-	systray.SetIcon(iconData)
-	systray.SetTitle("")
-	systray.SetTooltip("Computer Monitor")
-
-	menuCPU = systray.AddMenuItem("CPU: ---", "")
-	menuRAM = systray.AddMenuItem("RAM: ---", "")
-	menuGPU = systray.AddMenuItem("GPU: ---", "")
-	systray.AddSeparator()
-	menuStatus = systray.AddMenuItem("Status: Starting...", "")
-	systray.AddSeparator()
-	mQuit := systray.AddMenuItem("Quit", "Exit the application")
-
-	menuCPU.Disable()
-	menuRAM.Disable()
-	menuGPU.Disable()
-	menuStatus.Disable()
-
-	go func() {
-		endpoint := strings.TrimRight(apiURL, "/") + "/pc-stats/report"
-		log.Printf("Reporting to %s", endpoint)
-
-		for {
-			metrics := collectMetrics()
-			updateMenuMetrics(metrics)
-
-			if err := sendMetrics(endpoint, metrics); err != nil {
-				log.Printf("Report error: %v", err)
-				setStatus("Error")
-			} else {
-				setStatus("Connected")
-			}
+	n, err := strconv.Atoi(v)
+	if err != nil || n < 0 {
+		slog.Warn("Invalid setting, using the default", "setting", key, "value", v, "default", fallback)
+		return fallback
+	}
+	return n
+}
 
-			time.Sleep(30 * time.Second)
-		}
-	}()
+func getInterval(key string, fallback time.Duration) time.Duration {
+	v := lookupSetting(key)
+	if v == "" {
+		return fallback
+	}
 
-	go func() {
-		<-mQuit.ClickedCh
-		systray.Quit()
-	}()
+	d, err := time.ParseDuration(v)
+	if err != nil {
+		slog.Warn("Invalid setting, using the default", "setting", key, "value", v, "default", fallback)
+		return fallback
+	}
+	if d < minInterval || d > maxInterval {
+		slog.Warn("Interval out of range, using the default", "setting", key, "min", minInterval, "max", maxInterval, "default", fallback)
+		return fallback
+	}
+	return d
 }
 
-func onExit() {
-	os.Exit(0)
-}
+// paused is toggled from the tray; while set nothing is sent.
+var paused atomic.Bool
 
-func setStatus(status string) {
-	menuMu.Lock()
-	defer menuMu.Unlock()
-	menuStatus.SetTitle(fmt.Sprintf("Status: %s", status))
+// headless is set with --no-tray or as a service; the collection loop then
+// runs without any menu to update.
+var (
+	noTray   = getEnv("M_NO_TRAY", "") == "1" || !trayAvailable
+	headless = noTray || runningAsService
+)
+
+func main() {
+	if setSecretMode {
+		runSetSecret()
+	}
+	if serviceCommand != nil {
+		runServiceCommand(serviceCommand)
+	}
+	if noTray && !runningAsService {
+		attachConsole()
+	}
+	initLogging()
+	ensureSingleInstance()
+	reportForeground.Store(getEnv("M_REPORT_FOREGROUND", "") == "1" || collectorOptIn("foreground"))
+	loadOptInCollectors()
+	exportEnabled.Store(getEnv("M_EXPORT", "") == "1")
+
+	if runningAsService {
+		runService()
+		onExit()
+	}
+	if headless {
+		startServices()
+		go run(agentCtx)
+		// Go turns CTRL_CLOSE_EVENT, logoff and system shutdown into SIGTERM.
+		sig := make(chan os.Signal, 1)
+		signal.Notify(sig, os.Interrupt, syscall.SIGTERM)
+		s := <-sig
+		shutdown(s.String())
+		onExit()
+	}
+	runTray()
 }
 
-func updateMenuMetrics(m Metrics) {
-	menuMu.Lock()
-	defer menuMu.Unlock()
-	menuCPU.SetTitle(fmt.Sprintf("CPU: %.1f%%", m.CPU))
-	menuRAM.SetTitle(fmt.Sprintf("RAM: %.1f%% (%d MB / %d MB)", m.RAM, m.RAMUsedMB, m.RAMTotalMB))
-	if m.GPU >= 0 {
-		menuGPU.SetTitle(fmt.Sprintf("GPU: %.0f%%", m.GPU))
+// startServices starts everything besides the collection loop that does
+// not depend on the tray.
+func startServices() {
+	watchPower()
+	scripts = loadScripts(scriptDir())
+	setWMIQueries(parseWMITables(fileWMIQueries))
+	if reportSessions && !runningAsService {
+		go supervise("session watcher", watchSessions)
+	}
+	go supervise("updater", runSelfUpdater)
+	if path := configFilePath(); path != "" {
+		go supervise("config watcher", func() { watchConfig(path) })
+	}
+	if etwNet {
+		if err := startNetworkTrace(); err != nil {
+			slog.Warn("Per-process network tracing disabled", "err", err)
+		}
+	}
+
+	if prometheusAddr != "" {
+		startPrometheus(prometheusAddr)
+	}
+	if pipeName != "" {
+		startPipeServer(pipeName)
+	}
+	if historyDBPath != "" {
+		if historyDBPath == "1" {
+			historyDBPath = filepath.Join(defaultDataDir(), "history.db")
+		}
+		s, err := openHistoryStore(historyDBPath, time.Duration(max(historyDays, 1))*24*time.Hour)
+		if err != nil {
+			slog.Warn("History store disabled", "err", err)
+		} else {
+			historyDB = s
+		}
+	}
+	if dashboard && localAPIAddr == "" {
+		localAPIAddr = "127.0.0.1:9183"
+	}
+	if localAPIAddr != "" {
+		startLocalAPI(localAPIAddr)
+	}
+	if e, err := newFileExporter(exportPath, int64(max(exportMaxMB, 1))*1024*1024); err != nil {
+		slog.Warn("File export unavailable", "err", err)
 	} else {
-		menuGPU.SetTitle("GPU: N/A")
+		exporter = e
 	}
 }
 
-func sendMetrics(endpoint string, metrics Metrics) error {
-	body, err := json.Marshal(metrics)
-	if err != nil {
-		return fmt.Errorf("marshal: %w", err)
+func run(ctx context.Context) {
+	if needsSetup() {
+		waitForSetup()
+	}
+	if err := initTransport(); err != nil {
+		slog.Error("Transport unavailable", "err", err)
+		setStatus("Error")
+		return
 	}
 
-	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	outs, err := metricOutputs()
 	if err != nil {
-		return fmt.Errorf("new request: %w", err)
+		slog.Error("Outputs unavailable", "err", err)
+		setStatus("Error")
+		return
+	}
+	if len(outs) == 0 {
+		slog.Error("No outputs configured")
+		setStatus("Error")
+		return
+	}
+	slog.Info("Agent started", "version", version, "event", evtStarted)
+	for _, o := range outs {
+		slog.Info("Reporting", "to", o.dest, "send", sendInterval, "collect", collectInterval)
 	}
 
-	req.Header.Set("Content-Type", "application/json")
-	req.Header.Set("Authorization", "Bearer "+secret)
+	if transportName != "none" || len(apiTargets) > 0 {
+		if err := sendHello(); isUpgradeRequired(err) {
+			slog.Error("Server refused the agent version", "version", version)
+			setStatus("Update required")
+			return
+		} else if err != nil {
+			hellos.Failed(err)
+		}
 
-	resp, err := http.DefaultClient.Do(req)
-	if err != nil {
-		return fmt.Errorf("request: %w", err)
+		go supervise("inventory", runInventory)
+		go supervise("SMART collector", runSmartCollector)
+		go supervise("Windows Update collector", runUpdateCollector)
+		go supervise("health collector", runHealthCollector)
+		go supervise("heartbeat", runHeartbeat)
+		if dailySummary {
+			go supervise("daily summary", runDailySummary)
+		}
+		if len(eventLogs) > 0 {
+			go supervise("event log collector", func() { runEventLogCollector(eventLogs) })
+		}
 	}
-	defer resp.Body.Close()
 
-	if resp.StatusCode != http.StatusOK {
-		return fmt.Errorf("unexpected status: %d", resp.StatusCode)
+	collecting.Add(1)
+	defer collecting.Done()
+	var seq uint64
+	supervise("collection loop", func() { collectLoop(ctx, outs, &seq) })
+}
+
+// collectLoop collects, evaluates and reports until ctx is cancelled, then
+// gives the reporters a last chance to send what they hold in memory. The
+// report sequence lives in seq so it carries on when the loop is restarted
+// after a panic.
+func collectLoop(ctx context.Context, outs []output, seq *uint64) {
+	ticker := time.NewTicker(collectInterval)
+	defer ticker.Stop()
+
+	reps := make([]*reporter, len(outs))
+	for i, o := range outs {
+		reps[i] = newReporter(o.name, o.send)
+		if o.name == transportName {
+			reps[i].onReconnect = hellos.Schedule
+		}
 	}
+	discoveryPending := mqttSink != nil && haDiscovery
+	forceSend := false
+	var adapt adaptiveMode
+	var saver lowResourceMode
+	var offline offlineNotifier
+	var auth authNotifier
+	var agg sampleAggregator
+	curCollect := collectInterval
+	wasQuiet := false
+
+	defer func() {
+		for _, rep := range reps {
+			rep.Close()
+		}
+	}()
 
-	return nil
-}
+	for tick := 0; ctx.Err() == nil; tick++ {
+		quiet := inQuietHours(time.Now())
+		if quiet != wasQuiet {
+			wasQuiet = quiet
+			if quiet {
+				slog.Info("Quiet hours, pausing transmission")
+				setStatus("Quiet hours")
+			} else {
+				slog.Info("Quiet hours over, resuming transmission")
+			}
+		}
+		if quiet && !quietCollect {
+			waitForTick(ctx, ticker)
+			continue
+		}
 
-func collectMetrics() Metrics {
-	m := Metrics{GPU: -1}
+		metrics := collectMetrics(ctx)
+		conds := runScripts(&metrics)
+		setLatestMetrics(metrics)
+		history.Add(metrics)
+		updateMenuMetrics(metrics)
+		if historyDB != nil {
+			historyDB.Add(metrics)
+		}
+		if exporter != nil && exportEnabled.Load() {
+			if err := exporter.Write(metrics); err != nil {
+				slog.Warn("Export failed", "err", err)
+			}
+		}
+		broadcastMetrics(metrics)
+		for _, ev := range alerts.Evaluate(metrics, time.Now()) {
+			raiseAlert(ev)
+		}
+		for _, ev := range alerts.EvaluateConditions(metrics, conds, time.Now()) {
+			raiseAlert(ev)
+		}
 
-	cpuPercent, err := cpu.Percent(0, false)
-	if err == nil && len(cpuPercent) > 0 {
-		m.CPU = cpuPercent[0]
-	}
+		collect, send := adapt.intervals(metrics)
+		if saver.update(metrics) {
+			collect, send = max(collect, lowResourceInterval), max(send, lowResourceInterval)
+		}
+		if collect != curCollect {
+			ticker.Reset(collect)
+			curCollect = collect
+		}
+		sendEvery := max(1, int((send+collect/2)/collect))
 
-	memStat, err := mem.VirtualMemory()
-	if err == nil {
-		m.RAM = memStat.UsedPercent
-		m.RAMUsedMB = memStat.Used / 1024 / 1024
-		m.RAMTotalMB = memStat.Total / 1024 / 1024
-	}
+		if discoveryPending {
+			if err := publishHADiscovery(mqttSink, metrics); err != nil {
+				slog.Warn("Home Assistant discovery failed", "err", err)
+			} else {
+				discoveryPending = false
+			}
+		}
 
-	if gpuUsage, ok := getNvidiaGPU(); ok {
-		m.GPU = gpuUsage
-	}
+		// Samples taken while sending is off are not summarized later.
+		sending := !quiet && !paused.Load() && !suspended.Load()
+		if sending && !hellos.Run() {
+			setStatus("Update required")
+			return
+		}
+		if sending && aggregateSamples {
+			agg.Add(metrics)
+		}
+		if sending && (tick%sendEvery == 0 || forceSend) {
+			forceSend = false
+			metrics.Aggregates = agg.Take()
+			metrics = limitMetrics(metrics)
+			*seq++
+			metrics.RunID = runID
+			metrics.Seq = *seq
+			metrics.LatencyMs = latencyMs()
+			announceMetrics(metrics)
+
+			if reconnectPending {
+				reconnectPending = false
+				for _, rep := range reps {
+					rep.RetryNow()
+				}
+			}
+
+			status, authFailed := "Connected", false
+			for i, rep := range reps {
+				// A reload or setInterval may have changed the intervals.
+				rep.SetBackoff(sendInterval, backoffMax)
+				err := rep.Report(metrics)
+				switch {
+				case err == nil:
+					slog.Debug("Sent report", "seq", metrics.Seq, "output", outs[i].name)
+				case errors.Is(err, errBackoff):
+					status = "Error"
+				case errors.Is(err, errRateLimited):
+					slog.Debug("Report held back by the rate limit", "seq", metrics.Seq, "output", outs[i].name)
+				default:
+					slog.Warn("Report failed", "output", outs[i].name, "err", err, "retryIn", rep.RetryIn().Round(time.Second))
+					status = "Error"
+				}
+				authFailed = authFailed || err != nil && rep.AuthFailed()
+			}
+			if authFailed {
+				status = "Auth failed"
+			}
+			auth.Update(authFailed)
+			offline.Update(status == "Connected", time.Now())
+			if ms := latencyMs(); status == "Connected" && ms >= 0 {
+				status = tr("Connected · %.0f ms", ms)
+			}
+			setStatus(status)
+		}
 
-	return m
+		forceSend = waitForTick(ctx, ticker)
+	}
 }
 
-func getNvidiaGPU() (float64, bool) {
-	cmd := exec.Command(
-		"nvidia-smi",
-		"--query-gpu=utilization.gpu",
-		"--format=csv,noheader,nounits",
-	)
-	cmd.SysProcAttr = &syscall.SysProcAttr{HideWindow: true}
+// waitForTick runs commands until the next tick or ctx is cancelled, or
+// until one of them asks for a report to be sent right away, which it then
+// reports.
+func waitForTick(ctx context.Context, ticker *time.Ticker) bool {
+	for {
+		select {
+		case <-ticker.C:
+			return false
+		case <-ctx.Done():
+			return false
+		case c := <-commands:
+			if runCommand(c) {
+				return true
+			}
+		}
+	}
+}
 
-	var out bytes.Buffer
-	cmd.Stdout = &out
+func onExit() {
+	shutdown("exit")
+	os.Exit(0)
+}
 
-	err := cmd.Run()
-	if err != nil {
-		return 0, false
+func formatRate(bps float64) string {
+	units := []string{"B/s", "KB/s", "MB/s", "GB/s"}
+	i := 0
+	for bps >= 1024 && i < len(units)-1 {
+		bps /= 1024
+		i++
 	}
-
-	value := strings.TrimSpace(out.String())
-	usage, err := strconv.ParseFloat(value, 64)
-	if err != nil {
-		return 0, false
+	if i == 0 {
+		return fmt.Sprintf("%.0f %s", bps, units[i])
 	}
+	return fmt.Sprintf("%.1f %s", bps, units[i])
+}
 
-	return usage, true
+func formatUptime(sec uint64) string {
+	d := sec / 86400
+	h := sec % 86400 / 3600
+	min := sec % 3600 / 60
+	if d > 0 {
+		return fmt.Sprintf("%dd %dh %dm", d, h, min)
+	}
+	return fmt.Sprintf("%dh %dm", h, min)
 }
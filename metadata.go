@@ -0,0 +1,106 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// MetricInfo describes one sample family, so a dashboard can build its
+// panels and units without knowing the agent's metrics up front.
+type MetricInfo struct {
+	Name        string   `json:"name"`
+	Type        string   `json:"type"`
+	Unit        string   `json:"unit,omitempty"`
+	Description string   `json:"description"`
+	Labels      []string `json:"labels,omitempty"`
+}
+
+// Metadata is the /pc-stats/metadata message, sent after the hello and
+// again whenever a family appears or goes away, e.g. a plugin or a disk.
+type Metadata struct {
+	MachineID string       `json:"machineId"`
+	Hostname  string       `json:"hostname"`
+	Metrics   []MetricInfo `json:"metrics"`
+}
+
+// metricUnits maps name suffixes to units, longest first.
+var metricUnits = []struct{ suffix, unit string }{
+	{"_bytes_per_second", "bytes/s"},
+	{"_milliseconds", "ms"},
+	{"_celsius", "°C"},
+	{"_percent", "%"},
+	{"_seconds", "s"},
+	{"_bytes", "bytes"},
+	{"_mhz", "MHz"},
+	{"_rpm", "rpm"},
+}
+
+func metricUnit(name string) string {
+	for _, u := range metricUnits {
+		if strings.HasSuffix(name, u.suffix) {
+			return u.unit
+		}
+	}
+	return ""
+}
+
+// describeMetrics lists the families in m in the order the outputs send
+// them. Every sample is a gauge.
+func describeMetrics(m Metrics) []MetricInfo {
+	var infos []MetricInfo
+	index := map[string]int{}
+	visitSamples(m, func(name, help string, _ float64, labels ...string) {
+		i, ok := index[name]
+		if !ok {
+			i = len(infos)
+			index[name] = i
+			infos = append(infos, MetricInfo{Name: name, Type: "gauge", Unit: metricUnit(name), Description: help})
+		}
+		for j := 0; j+1 < len(labels); j += 2 {
+			if !slices.Contains(infos[i].Labels, labels[j]) {
+				infos[i].Labels = append(infos[i].Labels, labels[j])
+			}
+		}
+	})
+	return infos
+}
+
+// metadataSent holds the family names of the last metadata that went
+// through; sendHello clears it so a new server gets its own.
+var metadataSent struct {
+	sync.Mutex
+	names string
+}
+
+func resetMetadata() {
+	metadataSent.Lock()
+	metadataSent.names = ""
+	metadataSent.Unlock()
+}
+
+// announceMetrics sends the metadata for m in the background when its
+// families differ from the last ones sent. A failed send is retried with
+// the next sample.
+func announceMetrics(m Metrics) {
+	infos := describeMetrics(m)
+	names := make([]string, len(infos))
+	for i, info := range infos {
+		names[i] = info.Name
+	}
+	key := strings.Join(names, ",")
+
+	metadataSent.Lock()
+	defer metadataSent.Unlock()
+	if key == metadataSent.names {
+		return
+	}
+	metadataSent.names = key
+	go func() {
+		if err := publish("metadata", Metadata{MachineID: m.MachineID, Hostname: m.Hostname, Metrics: infos}); err != nil {
+			slog.Warn("Metadata report failed", "err", err)
+			resetMetadata()
+		}
+	}()
+}
@@ -0,0 +1,889 @@
+// Code generated by protoc-gen-go. DO NOT EDIT.
+// versions:
+// 	protoc-gen-go v1.36.11
+// 	protoc        (unknown)
+// source: monitor.proto
+
+package monitorpb
+
+import (
+	protoreflect "google.golang.org/protobuf/reflect/protoreflect"
+	protoimpl "google.golang.org/protobuf/runtime/protoimpl"
+	reflect "reflect"
+	sync "sync"
+	unsafe "unsafe"
+)
+
+const (
+	// Verify that this generated code is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(20 - protoimpl.MinVersion)
+	// Verify that runtime/protoimpl is sufficiently up-to-date.
+	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
+)
+
+type AgentMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	// Types that are valid to be assigned to Body:
+	//
+	//	*AgentMessage_Auth
+	//	*AgentMessage_Metrics
+	//	*AgentMessage_Event
+	Body          isAgentMessage_Body `protobuf_oneof:"body"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AgentMessage) Reset() {
+	*x = AgentMessage{}
+	mi := &file_monitor_proto_msgTypes[0]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AgentMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AgentMessage) ProtoMessage() {}
+
+func (x *AgentMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[0]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AgentMessage.ProtoReflect.Descriptor instead.
+func (*AgentMessage) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{0}
+}
+
+func (x *AgentMessage) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *AgentMessage) GetBody() isAgentMessage_Body {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetAuth() *Auth {
+	if x != nil {
+		if x, ok := x.Body.(*AgentMessage_Auth); ok {
+			return x.Auth
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetMetrics() *Metrics {
+	if x != nil {
+		if x, ok := x.Body.(*AgentMessage_Metrics); ok {
+			return x.Metrics
+		}
+	}
+	return nil
+}
+
+func (x *AgentMessage) GetEvent() *Event {
+	if x != nil {
+		if x, ok := x.Body.(*AgentMessage_Event); ok {
+			return x.Event
+		}
+	}
+	return nil
+}
+
+type isAgentMessage_Body interface {
+	isAgentMessage_Body()
+}
+
+type AgentMessage_Auth struct {
+	Auth *Auth `protobuf:"bytes,2,opt,name=auth,proto3,oneof"`
+}
+
+type AgentMessage_Metrics struct {
+	Metrics *Metrics `protobuf:"bytes,3,opt,name=metrics,proto3,oneof"`
+}
+
+type AgentMessage_Event struct {
+	Event *Event `protobuf:"bytes,4,opt,name=event,proto3,oneof"`
+}
+
+func (*AgentMessage_Auth) isAgentMessage_Body() {}
+
+func (*AgentMessage_Metrics) isAgentMessage_Body() {}
+
+func (*AgentMessage_Event) isAgentMessage_Body() {}
+
+type Auth struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Secret        string                 `protobuf:"bytes,1,opt,name=secret,proto3" json:"secret,omitempty"`
+	AgentVersion  string                 `protobuf:"bytes,2,opt,name=agent_version,json=agentVersion,proto3" json:"agent_version,omitempty"`
+	Hostname      string                 `protobuf:"bytes,3,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Auth) Reset() {
+	*x = Auth{}
+	mi := &file_monitor_proto_msgTypes[1]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Auth) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Auth) ProtoMessage() {}
+
+func (x *Auth) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[1]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Auth.ProtoReflect.Descriptor instead.
+func (*Auth) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{1}
+}
+
+func (x *Auth) GetSecret() string {
+	if x != nil {
+		return x.Secret
+	}
+	return ""
+}
+
+func (x *Auth) GetAgentVersion() string {
+	if x != nil {
+		return x.AgentVersion
+	}
+	return ""
+}
+
+func (x *Auth) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+type Metrics struct {
+	state           protoimpl.MessageState `protogen:"open.v1"`
+	TimestampUnixMs int64                  `protobuf:"varint,1,opt,name=timestamp_unix_ms,json=timestampUnixMs,proto3" json:"timestamp_unix_ms,omitempty"`
+	Cpu             float64                `protobuf:"fixed64,2,opt,name=cpu,proto3" json:"cpu,omitempty"`
+	CpuFreqMhz      uint32                 `protobuf:"varint,3,opt,name=cpu_freq_mhz,json=cpuFreqMhz,proto3" json:"cpu_freq_mhz,omitempty"`
+	Ram             float64                `protobuf:"fixed64,4,opt,name=ram,proto3" json:"ram,omitempty"`
+	RamUsedMb       uint64                 `protobuf:"varint,5,opt,name=ram_used_mb,json=ramUsedMb,proto3" json:"ram_used_mb,omitempty"`
+	RamTotalMb      uint64                 `protobuf:"varint,6,opt,name=ram_total_mb,json=ramTotalMb,proto3" json:"ram_total_mb,omitempty"`
+	Pagefile        float64                `protobuf:"fixed64,7,opt,name=pagefile,proto3" json:"pagefile,omitempty"`
+	// GPU readings are -1 when no supported GPU is present.
+	Gpu           float64 `protobuf:"fixed64,8,opt,name=gpu,proto3" json:"gpu,omitempty"`
+	GpuEncoder    float64 `protobuf:"fixed64,9,opt,name=gpu_encoder,json=gpuEncoder,proto3" json:"gpu_encoder,omitempty"`
+	GpuDecoder    float64 `protobuf:"fixed64,10,opt,name=gpu_decoder,json=gpuDecoder,proto3" json:"gpu_decoder,omitempty"`
+	UptimeSec     uint64  `protobuf:"varint,11,opt,name=uptime_sec,json=uptimeSec,proto3" json:"uptime_sec,omitempty"`
+	PendingReboot bool    `protobuf:"varint,12,opt,name=pending_reboot,json=pendingReboot,proto3" json:"pending_reboot,omitempty"`
+	Processes     uint32  `protobuf:"varint,13,opt,name=processes,proto3" json:"processes,omitempty"`
+	Threads       uint32  `protobuf:"varint,14,opt,name=threads,proto3" json:"threads,omitempty"`
+	Handles       uint32  `protobuf:"varint,15,opt,name=handles,proto3" json:"handles,omitempty"`
+	WifiSsid      string  `protobuf:"bytes,16,opt,name=wifi_ssid,json=wifiSsid,proto3" json:"wifi_ssid,omitempty"`
+	PublicIp      string  `protobuf:"bytes,17,opt,name=public_ip,json=publicIp,proto3" json:"public_ip,omitempty"`
+	ForegroundApp string  `protobuf:"bytes,18,opt,name=foreground_app,json=foregroundApp,proto3" json:"foreground_app,omitempty"`
+	IdleSec       uint64  `protobuf:"varint,19,opt,name=idle_sec,json=idleSec,proto3" json:"idle_sec,omitempty"`
+	SessionLocked bool    `protobuf:"varint,20,opt,name=session_locked,json=sessionLocked,proto3" json:"session_locked,omitempty"`
+	// Every reading, including the per-disk, per-fan, per-host... series,
+	// flattened the same way as the Prometheus endpoint.
+	Samples   []*Sample         `protobuf:"bytes,21,rep,name=samples,proto3" json:"samples,omitempty"`
+	MachineId string            `protobuf:"bytes,22,opt,name=machine_id,json=machineId,proto3" json:"machine_id,omitempty"`
+	Hostname  string            `protobuf:"bytes,23,opt,name=hostname,proto3" json:"hostname,omitempty"`
+	Tags      map[string]string `protobuf:"bytes,24,rep,name=tags,proto3" json:"tags,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	// seq increases by one for every report within a run_id, so gaps and
+	// replays of buffered data can be detected.
+	RunId         string `protobuf:"bytes,25,opt,name=run_id,json=runId,proto3" json:"run_id,omitempty"`
+	Seq           uint64 `protobuf:"varint,26,opt,name=seq,proto3" json:"seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Metrics) Reset() {
+	*x = Metrics{}
+	mi := &file_monitor_proto_msgTypes[2]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Metrics) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Metrics) ProtoMessage() {}
+
+func (x *Metrics) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[2]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Metrics.ProtoReflect.Descriptor instead.
+func (*Metrics) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{2}
+}
+
+func (x *Metrics) GetTimestampUnixMs() int64 {
+	if x != nil {
+		return x.TimestampUnixMs
+	}
+	return 0
+}
+
+func (x *Metrics) GetCpu() float64 {
+	if x != nil {
+		return x.Cpu
+	}
+	return 0
+}
+
+func (x *Metrics) GetCpuFreqMhz() uint32 {
+	if x != nil {
+		return x.CpuFreqMhz
+	}
+	return 0
+}
+
+func (x *Metrics) GetRam() float64 {
+	if x != nil {
+		return x.Ram
+	}
+	return 0
+}
+
+func (x *Metrics) GetRamUsedMb() uint64 {
+	if x != nil {
+		return x.RamUsedMb
+	}
+	return 0
+}
+
+func (x *Metrics) GetRamTotalMb() uint64 {
+	if x != nil {
+		return x.RamTotalMb
+	}
+	return 0
+}
+
+func (x *Metrics) GetPagefile() float64 {
+	if x != nil {
+		return x.Pagefile
+	}
+	return 0
+}
+
+func (x *Metrics) GetGpu() float64 {
+	if x != nil {
+		return x.Gpu
+	}
+	return 0
+}
+
+func (x *Metrics) GetGpuEncoder() float64 {
+	if x != nil {
+		return x.GpuEncoder
+	}
+	return 0
+}
+
+func (x *Metrics) GetGpuDecoder() float64 {
+	if x != nil {
+		return x.GpuDecoder
+	}
+	return 0
+}
+
+func (x *Metrics) GetUptimeSec() uint64 {
+	if x != nil {
+		return x.UptimeSec
+	}
+	return 0
+}
+
+func (x *Metrics) GetPendingReboot() bool {
+	if x != nil {
+		return x.PendingReboot
+	}
+	return false
+}
+
+func (x *Metrics) GetProcesses() uint32 {
+	if x != nil {
+		return x.Processes
+	}
+	return 0
+}
+
+func (x *Metrics) GetThreads() uint32 {
+	if x != nil {
+		return x.Threads
+	}
+	return 0
+}
+
+func (x *Metrics) GetHandles() uint32 {
+	if x != nil {
+		return x.Handles
+	}
+	return 0
+}
+
+func (x *Metrics) GetWifiSsid() string {
+	if x != nil {
+		return x.WifiSsid
+	}
+	return ""
+}
+
+func (x *Metrics) GetPublicIp() string {
+	if x != nil {
+		return x.PublicIp
+	}
+	return ""
+}
+
+func (x *Metrics) GetForegroundApp() string {
+	if x != nil {
+		return x.ForegroundApp
+	}
+	return ""
+}
+
+func (x *Metrics) GetIdleSec() uint64 {
+	if x != nil {
+		return x.IdleSec
+	}
+	return 0
+}
+
+func (x *Metrics) GetSessionLocked() bool {
+	if x != nil {
+		return x.SessionLocked
+	}
+	return false
+}
+
+func (x *Metrics) GetSamples() []*Sample {
+	if x != nil {
+		return x.Samples
+	}
+	return nil
+}
+
+func (x *Metrics) GetMachineId() string {
+	if x != nil {
+		return x.MachineId
+	}
+	return ""
+}
+
+func (x *Metrics) GetHostname() string {
+	if x != nil {
+		return x.Hostname
+	}
+	return ""
+}
+
+func (x *Metrics) GetTags() map[string]string {
+	if x != nil {
+		return x.Tags
+	}
+	return nil
+}
+
+func (x *Metrics) GetRunId() string {
+	if x != nil {
+		return x.RunId
+	}
+	return ""
+}
+
+func (x *Metrics) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type Sample struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Name          string                 `protobuf:"bytes,1,opt,name=name,proto3" json:"name,omitempty"`
+	Value         float64                `protobuf:"fixed64,2,opt,name=value,proto3" json:"value,omitempty"`
+	Labels        map[string]string      `protobuf:"bytes,3,rep,name=labels,proto3" json:"labels,omitempty" protobuf_key:"bytes,1,opt,name=key" protobuf_val:"bytes,2,opt,name=value"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Sample) Reset() {
+	*x = Sample{}
+	mi := &file_monitor_proto_msgTypes[3]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Sample) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Sample) ProtoMessage() {}
+
+func (x *Sample) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[3]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Sample.ProtoReflect.Descriptor instead.
+func (*Sample) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{3}
+}
+
+func (x *Sample) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *Sample) GetValue() float64 {
+	if x != nil {
+		return x.Value
+	}
+	return 0
+}
+
+func (x *Sample) GetLabels() map[string]string {
+	if x != nil {
+		return x.Labels
+	}
+	return nil
+}
+
+// Event carries the less frequent reports (hello, inventory, smart,
+// updates, health, events) as the same JSON documents the HTTP API takes.
+type Event struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kind          string                 `protobuf:"bytes,1,opt,name=kind,proto3" json:"kind,omitempty"`
+	Json          []byte                 `protobuf:"bytes,2,opt,name=json,proto3" json:"json,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Event) Reset() {
+	*x = Event{}
+	mi := &file_monitor_proto_msgTypes[4]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Event) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Event) ProtoMessage() {}
+
+func (x *Event) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[4]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Event.ProtoReflect.Descriptor instead.
+func (*Event) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{4}
+}
+
+func (x *Event) GetKind() string {
+	if x != nil {
+		return x.Kind
+	}
+	return ""
+}
+
+func (x *Event) GetJson() []byte {
+	if x != nil {
+		return x.Json
+	}
+	return nil
+}
+
+type ServerMessage struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Types that are valid to be assigned to Body:
+	//
+	//	*ServerMessage_Ack
+	//	*ServerMessage_Error
+	Body          isServerMessage_Body `protobuf_oneof:"body"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *ServerMessage) Reset() {
+	*x = ServerMessage{}
+	mi := &file_monitor_proto_msgTypes[5]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *ServerMessage) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*ServerMessage) ProtoMessage() {}
+
+func (x *ServerMessage) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[5]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use ServerMessage.ProtoReflect.Descriptor instead.
+func (*ServerMessage) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{5}
+}
+
+func (x *ServerMessage) GetBody() isServerMessage_Body {
+	if x != nil {
+		return x.Body
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetAck() *Ack {
+	if x != nil {
+		if x, ok := x.Body.(*ServerMessage_Ack); ok {
+			return x.Ack
+		}
+	}
+	return nil
+}
+
+func (x *ServerMessage) GetError() *Error {
+	if x != nil {
+		if x, ok := x.Body.(*ServerMessage_Error); ok {
+			return x.Error
+		}
+	}
+	return nil
+}
+
+type isServerMessage_Body interface {
+	isServerMessage_Body()
+}
+
+type ServerMessage_Ack struct {
+	Ack *Ack `protobuf:"bytes,1,opt,name=ack,proto3,oneof"`
+}
+
+type ServerMessage_Error struct {
+	Error *Error `protobuf:"bytes,2,opt,name=error,proto3,oneof"`
+}
+
+func (*ServerMessage_Ack) isServerMessage_Body() {}
+
+func (*ServerMessage_Error) isServerMessage_Body() {}
+
+type Ack struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Seq           uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Ack) Reset() {
+	*x = Ack{}
+	mi := &file_monitor_proto_msgTypes[6]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Ack) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Ack) ProtoMessage() {}
+
+func (x *Ack) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[6]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Ack.ProtoReflect.Descriptor instead.
+func (*Ack) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{6}
+}
+
+func (x *Ack) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+type Error struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	Seq   uint64                 `protobuf:"varint,1,opt,name=seq,proto3" json:"seq,omitempty"`
+	// HTTP-style status code, e.g. 401 or 426.
+	Code          int32  `protobuf:"varint,2,opt,name=code,proto3" json:"code,omitempty"`
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Error) Reset() {
+	*x = Error{}
+	mi := &file_monitor_proto_msgTypes[7]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Error) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Error) ProtoMessage() {}
+
+func (x *Error) ProtoReflect() protoreflect.Message {
+	mi := &file_monitor_proto_msgTypes[7]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Error.ProtoReflect.Descriptor instead.
+func (*Error) Descriptor() ([]byte, []int) {
+	return file_monitor_proto_rawDescGZIP(), []int{7}
+}
+
+func (x *Error) GetSeq() uint64 {
+	if x != nil {
+		return x.Seq
+	}
+	return 0
+}
+
+func (x *Error) GetCode() int32 {
+	if x != nil {
+		return x.Code
+	}
+	return 0
+}
+
+func (x *Error) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+var File_monitor_proto protoreflect.FileDescriptor
+
+const file_monitor_proto_rawDesc = "" +
+	"\n" +
+	"\rmonitor.proto\x12\x0fgowinmonitor.v1\"\xbb\x01\n" +
+	"\fAgentMessage\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12+\n" +
+	"\x04auth\x18\x02 \x01(\v2\x15.gowinmonitor.v1.AuthH\x00R\x04auth\x124\n" +
+	"\ametrics\x18\x03 \x01(\v2\x18.gowinmonitor.v1.MetricsH\x00R\ametrics\x12.\n" +
+	"\x05event\x18\x04 \x01(\v2\x16.gowinmonitor.v1.EventH\x00R\x05eventB\x06\n" +
+	"\x04body\"_\n" +
+	"\x04Auth\x12\x16\n" +
+	"\x06secret\x18\x01 \x01(\tR\x06secret\x12#\n" +
+	"\ragent_version\x18\x02 \x01(\tR\fagentVersion\x12\x1a\n" +
+	"\bhostname\x18\x03 \x01(\tR\bhostname\"\xf0\x06\n" +
+	"\aMetrics\x12*\n" +
+	"\x11timestamp_unix_ms\x18\x01 \x01(\x03R\x0ftimestampUnixMs\x12\x10\n" +
+	"\x03cpu\x18\x02 \x01(\x01R\x03cpu\x12 \n" +
+	"\fcpu_freq_mhz\x18\x03 \x01(\rR\n" +
+	"cpuFreqMhz\x12\x10\n" +
+	"\x03ram\x18\x04 \x01(\x01R\x03ram\x12\x1e\n" +
+	"\vram_used_mb\x18\x05 \x01(\x04R\tramUsedMb\x12 \n" +
+	"\fram_total_mb\x18\x06 \x01(\x04R\n" +
+	"ramTotalMb\x12\x1a\n" +
+	"\bpagefile\x18\a \x01(\x01R\bpagefile\x12\x10\n" +
+	"\x03gpu\x18\b \x01(\x01R\x03gpu\x12\x1f\n" +
+	"\vgpu_encoder\x18\t \x01(\x01R\n" +
+	"gpuEncoder\x12\x1f\n" +
+	"\vgpu_decoder\x18\n" +
+	" \x01(\x01R\n" +
+	"gpuDecoder\x12\x1d\n" +
+	"\n" +
+	"uptime_sec\x18\v \x01(\x04R\tuptimeSec\x12%\n" +
+	"\x0epending_reboot\x18\f \x01(\bR\rpendingReboot\x12\x1c\n" +
+	"\tprocesses\x18\r \x01(\rR\tprocesses\x12\x18\n" +
+	"\athreads\x18\x0e \x01(\rR\athreads\x12\x18\n" +
+	"\ahandles\x18\x0f \x01(\rR\ahandles\x12\x1b\n" +
+	"\twifi_ssid\x18\x10 \x01(\tR\bwifiSsid\x12\x1b\n" +
+	"\tpublic_ip\x18\x11 \x01(\tR\bpublicIp\x12%\n" +
+	"\x0eforeground_app\x18\x12 \x01(\tR\rforegroundApp\x12\x19\n" +
+	"\bidle_sec\x18\x13 \x01(\x04R\aidleSec\x12%\n" +
+	"\x0esession_locked\x18\x14 \x01(\bR\rsessionLocked\x121\n" +
+	"\asamples\x18\x15 \x03(\v2\x17.gowinmonitor.v1.SampleR\asamples\x12\x1d\n" +
+	"\n" +
+	"machine_id\x18\x16 \x01(\tR\tmachineId\x12\x1a\n" +
+	"\bhostname\x18\x17 \x01(\tR\bhostname\x126\n" +
+	"\x04tags\x18\x18 \x03(\v2\".gowinmonitor.v1.Metrics.TagsEntryR\x04tags\x12\x15\n" +
+	"\x06run_id\x18\x19 \x01(\tR\x05runId\x12\x10\n" +
+	"\x03seq\x18\x1a \x01(\x04R\x03seq\x1a7\n" +
+	"\tTagsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"\xaa\x01\n" +
+	"\x06Sample\x12\x12\n" +
+	"\x04name\x18\x01 \x01(\tR\x04name\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\x01R\x05value\x12;\n" +
+	"\x06labels\x18\x03 \x03(\v2#.gowinmonitor.v1.Sample.LabelsEntryR\x06labels\x1a9\n" +
+	"\vLabelsEntry\x12\x10\n" +
+	"\x03key\x18\x01 \x01(\tR\x03key\x12\x14\n" +
+	"\x05value\x18\x02 \x01(\tR\x05value:\x028\x01\"/\n" +
+	"\x05Event\x12\x12\n" +
+	"\x04kind\x18\x01 \x01(\tR\x04kind\x12\x12\n" +
+	"\x04json\x18\x02 \x01(\fR\x04json\"q\n" +
+	"\rServerMessage\x12(\n" +
+	"\x03ack\x18\x01 \x01(\v2\x14.gowinmonitor.v1.AckH\x00R\x03ack\x12.\n" +
+	"\x05error\x18\x02 \x01(\v2\x16.gowinmonitor.v1.ErrorH\x00R\x05errorB\x06\n" +
+	"\x04body\"\x17\n" +
+	"\x03Ack\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\"G\n" +
+	"\x05Error\x12\x10\n" +
+	"\x03seq\x18\x01 \x01(\x04R\x03seq\x12\x12\n" +
+	"\x04code\x18\x02 \x01(\x05R\x04code\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage2V\n" +
+	"\aMonitor\x12K\n" +
+	"\x06Stream\x12\x1d.gowinmonitor.v1.AgentMessage\x1a\x1e.gowinmonitor.v1.ServerMessage(\x010\x01B\x1aZ\x18go-win-monitor/monitorpbb\x06proto3"
+
+var (
+	file_monitor_proto_rawDescOnce sync.Once
+	file_monitor_proto_rawDescData []byte
+)
+
+func file_monitor_proto_rawDescGZIP() []byte {
+	file_monitor_proto_rawDescOnce.Do(func() {
+		file_monitor_proto_rawDescData = protoimpl.X.CompressGZIP(unsafe.Slice(unsafe.StringData(file_monitor_proto_rawDesc), len(file_monitor_proto_rawDesc)))
+	})
+	return file_monitor_proto_rawDescData
+}
+
+var file_monitor_proto_msgTypes = make([]protoimpl.MessageInfo, 10)
+var file_monitor_proto_goTypes = []any{
+	(*AgentMessage)(nil),  // 0: gowinmonitor.v1.AgentMessage
+	(*Auth)(nil),          // 1: gowinmonitor.v1.Auth
+	(*Metrics)(nil),       // 2: gowinmonitor.v1.Metrics
+	(*Sample)(nil),        // 3: gowinmonitor.v1.Sample
+	(*Event)(nil),         // 4: gowinmonitor.v1.Event
+	(*ServerMessage)(nil), // 5: gowinmonitor.v1.ServerMessage
+	(*Ack)(nil),           // 6: gowinmonitor.v1.Ack
+	(*Error)(nil),         // 7: gowinmonitor.v1.Error
+	nil,                   // 8: gowinmonitor.v1.Metrics.TagsEntry
+	nil,                   // 9: gowinmonitor.v1.Sample.LabelsEntry
+}
+var file_monitor_proto_depIdxs = []int32{
+	1, // 0: gowinmonitor.v1.AgentMessage.auth:type_name -> gowinmonitor.v1.Auth
+	2, // 1: gowinmonitor.v1.AgentMessage.metrics:type_name -> gowinmonitor.v1.Metrics
+	4, // 2: gowinmonitor.v1.AgentMessage.event:type_name -> gowinmonitor.v1.Event
+	3, // 3: gowinmonitor.v1.Metrics.samples:type_name -> gowinmonitor.v1.Sample
+	8, // 4: gowinmonitor.v1.Metrics.tags:type_name -> gowinmonitor.v1.Metrics.TagsEntry
+	9, // 5: gowinmonitor.v1.Sample.labels:type_name -> gowinmonitor.v1.Sample.LabelsEntry
+	6, // 6: gowinmonitor.v1.ServerMessage.ack:type_name -> gowinmonitor.v1.Ack
+	7, // 7: gowinmonitor.v1.ServerMessage.error:type_name -> gowinmonitor.v1.Error
+	0, // 8: gowinmonitor.v1.Monitor.Stream:input_type -> gowinmonitor.v1.AgentMessage
+	5, // 9: gowinmonitor.v1.Monitor.Stream:output_type -> gowinmonitor.v1.ServerMessage
+	9, // [9:10] is the sub-list for method output_type
+	8, // [8:9] is the sub-list for method input_type
+	8, // [8:8] is the sub-list for extension type_name
+	8, // [8:8] is the sub-list for extension extendee
+	0, // [0:8] is the sub-list for field type_name
+}
+
+func init() { file_monitor_proto_init() }
+func file_monitor_proto_init() {
+	if File_monitor_proto != nil {
+		return
+	}
+	file_monitor_proto_msgTypes[0].OneofWrappers = []any{
+		(*AgentMessage_Auth)(nil),
+		(*AgentMessage_Metrics)(nil),
+		(*AgentMessage_Event)(nil),
+	}
+	file_monitor_proto_msgTypes[5].OneofWrappers = []any{
+		(*ServerMessage_Ack)(nil),
+		(*ServerMessage_Error)(nil),
+	}
+	type x struct{}
+	out := protoimpl.TypeBuilder{
+		File: protoimpl.DescBuilder{
+			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
+			RawDescriptor: unsafe.Slice(unsafe.StringData(file_monitor_proto_rawDesc), len(file_monitor_proto_rawDesc)),
+			NumEnums:      0,
+			NumMessages:   10,
+			NumExtensions: 0,
+			NumServices:   1,
+		},
+		GoTypes:           file_monitor_proto_goTypes,
+		DependencyIndexes: file_monitor_proto_depIdxs,
+		MessageInfos:      file_monitor_proto_msgTypes,
+	}.Build()
+	File_monitor_proto = out.File
+	file_monitor_proto_goTypes = nil
+	file_monitor_proto_depIdxs = nil
+}
@@ -0,0 +1,334 @@
+package main
+
+import (
+	"bufio"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/url"
+	"os"
+	"sync"
+	"time"
+)
+
+// mqttClient is a minimal MQTT 3.1.1 publisher: it connects lazily, publishes
+// at QoS 0 or 1, pings the broker when idle and reconnects on the next
+// publish after any error.
+type mqttClient struct {
+	mu        sync.Mutex
+	url       *url.URL
+	prefix    string
+	qos       byte
+	username  string
+	password  string
+	clientID  string
+	keepAlive time.Duration
+	conn      net.Conn
+	r         *bufio.Reader
+	packetID  uint16
+	lastSent  time.Time
+}
+
+func newMQTTClient(rawURL, prefix string, qos int, username, password string) (*mqttClient, error) {
+	u, err := url.Parse(rawURL)
+	if err != nil {
+		return nil, fmt.Errorf("parse broker url: %w", err)
+	}
+	if qos != 0 && qos != 1 {
+		return nil, fmt.Errorf("unsupported QoS %d", qos)
+	}
+	if password != "" && username == "" {
+		// MQTT 3.1.1 only allows a password along with a user name.
+		return nil, errors.New("M_MQTT_PASSWORD needs M_MQTT_USER")
+	}
+
+	host, _ := os.Hostname()
+	c := &mqttClient{
+		url:       u,
+		prefix:    prefix,
+		qos:       byte(qos),
+		username:  username,
+		password:  password,
+		clientID:  "go-win-monitor-" + host,
+		keepAlive: max(time.Minute, 2*sendInterval),
+	}
+	go c.keepAliveLoop()
+	return c, nil
+}
+
+func (c *mqttClient) Topic(kind string) string {
+	host, _ := os.Hostname()
+	return c.prefix + "/" + host + "/" + kind
+}
+
+func (c *mqttClient) Publish(kind string, v any) error {
+	payload, err := json.Marshal(v)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+	_, payload, err = seal("application/json", "", payload)
+	if err != nil {
+		return err
+	}
+	return c.PublishRaw(c.Topic(kind), payload, false)
+}
+
+func (c *mqttClient) PublishRaw(topic string, payload []byte, retain bool) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	// A broker that dropped an idle connection is only noticed on write, so
+	// retry once on a fresh connection.
+	err := c.publish(topic, payload, retain)
+	if err != nil {
+		c.close()
+		err = c.publish(topic, payload, retain)
+		if err != nil {
+			c.close()
+		}
+	}
+	return err
+}
+
+func (c *mqttClient) publish(topic string, payload []byte, retain bool) error {
+	if c.conn == nil {
+		if err := c.connect(); err != nil {
+			return err
+		}
+	}
+
+	header := byte(0x30) | c.qos<<1
+	if retain {
+		header |= 0x01
+	}
+
+	var body []byte
+	body = appendString(body, topic)
+	if c.qos > 0 {
+		c.packetID++
+		if c.packetID == 0 {
+			c.packetID = 1
+		}
+		body = append(body, byte(c.packetID>>8), byte(c.packetID))
+	}
+	body = append(body, payload...)
+
+	c.conn.SetDeadline(time.Now().Add(requestTimeout))
+	if err := writePacket(c.conn, header, body); err != nil {
+		return fmt.Errorf("publish: %w", err)
+	}
+	c.lastSent = time.Now()
+	if c.qos == 0 {
+		return nil
+	}
+
+	for {
+		typ, data, err := readPacket(c.r)
+		if err != nil {
+			return fmt.Errorf("puback: %w", err)
+		}
+		if typ>>4 == 4 && len(data) >= 2 && uint16(data[0])<<8|uint16(data[1]) == c.packetID {
+			return nil
+		}
+	}
+}
+
+func (c *mqttClient) connect() error {
+	addr := c.url.Host
+	var conn net.Conn
+	var err error
+	dialer := &net.Dialer{Timeout: 5 * time.Second}
+
+	switch c.url.Scheme {
+	case "tcp", "mqtt":
+		if c.url.Port() == "" {
+			addr = net.JoinHostPort(c.url.Hostname(), "1883")
+		}
+		conn, err = dialer.Dial("tcp", addr)
+	case "ssl", "tls", "mqtts":
+		if c.url.Port() == "" {
+			addr = net.JoinHostPort(c.url.Hostname(), "8883")
+		}
+		conn, err = tls.DialWithDialer(dialer, "tcp", addr, clientTLS(c.url.Hostname()))
+	default:
+		return fmt.Errorf("unsupported broker scheme %q", c.url.Scheme)
+	}
+	if err != nil {
+		return fmt.Errorf("dial: %w", err)
+	}
+
+	flags := byte(0x02) // clean session
+	if c.username != "" {
+		flags |= 0x80
+	}
+	if c.password != "" {
+		flags |= 0x40
+	}
+
+	var body []byte
+	body = appendString(body, "MQTT")
+	body = append(body, 4, flags)
+	ka := min(c.keepAlive/time.Second, 0xFFFF)
+	body = append(body, byte(ka>>8), byte(ka))
+	body = appendString(body, c.clientID)
+	if c.username != "" {
+		body = appendString(body, c.username)
+	}
+	if c.password != "" {
+		body = appendString(body, c.password)
+	}
+
+	conn.SetDeadline(time.Now().Add(requestTimeout))
+	if err := writePacket(conn, 0x10, body); err != nil {
+		conn.Close()
+		return fmt.Errorf("connect: %w", err)
+	}
+
+	r := bufio.NewReader(conn)
+	typ, data, err := readPacket(r)
+	if err != nil {
+		conn.Close()
+		return fmt.Errorf("connack: %w", err)
+	}
+	if typ>>4 != 2 || len(data) < 2 {
+		conn.Close()
+		return errors.New("connack: unexpected packet")
+	}
+	if data[1] != 0 {
+		conn.Close()
+		return fmt.Errorf("connection refused: code %d", data[1])
+	}
+
+	c.conn = conn
+	c.r = r
+	c.lastSent = time.Now()
+	return nil
+}
+
+// keepAliveLoop pings the broker when nothing went out for half the
+// keep-alive, so it doesn't drop the connection while reports are held back
+// (paused, quiet hours).
+func (c *mqttClient) keepAliveLoop() {
+	for range time.Tick(c.keepAlive / 2) {
+		c.mu.Lock()
+		if c.conn != nil && time.Since(c.lastSent) >= c.keepAlive/2 {
+			if err := c.ping(); err != nil {
+				slog.Debug("MQTT ping failed", "err", err)
+				c.close()
+			}
+		}
+		c.mu.Unlock()
+	}
+}
+
+func (c *mqttClient) ping() error {
+	c.conn.SetDeadline(time.Now().Add(requestTimeout))
+	if err := writePacket(c.conn, 0xC0, nil); err != nil {
+		return fmt.Errorf("pingreq: %w", err)
+	}
+	c.lastSent = time.Now()
+	for {
+		typ, _, err := readPacket(c.r)
+		if err != nil {
+			return fmt.Errorf("pingresp: %w", err)
+		}
+		if typ>>4 == 13 {
+			return nil
+		}
+	}
+}
+
+// Send publishes v, going over HTTPS instead while MQTT keeps failing.
+func (c *mqttClient) Send(kind string, v any) ([]byte, error) {
+	if !fallbackActive() {
+		err := c.Publish(kind, v)
+		if !recordMQTT(err) {
+			return nil, err
+		}
+	}
+	return httpSink{}.Send(kind, v)
+}
+
+// Reset drops the connection so the next publish dials again, and gives up
+// an active HTTPS fallback.
+func (c *mqttClient) Reset() {
+	c.Close()
+	resetFallback()
+}
+
+// Close disconnects from the broker.
+func (c *mqttClient) Close() {
+	c.mu.Lock()
+	c.close()
+	c.mu.Unlock()
+}
+
+func (c *mqttClient) Status() string {
+	s := c.url.Redacted() + " (" + c.Topic("report") + ")"
+	if fallbackActive() {
+		s += ", falling back to " + apiEndpoint("report")
+	}
+	return s
+}
+
+func (c *mqttClient) close() {
+	if c.conn != nil {
+		writePacket(c.conn, 0xE0, nil)
+		c.conn.Close()
+		c.conn = nil
+	}
+}
+
+func appendString(b []byte, s string) []byte {
+	b = append(b, byte(len(s)>>8), byte(len(s)))
+	return append(b, s...)
+}
+
+func writePacket(w io.Writer, header byte, body []byte) error {
+	pkt := []byte{header}
+	n := len(body)
+	for {
+		digit := byte(n % 128)
+		n /= 128
+		if n > 0 {
+			digit |= 0x80
+		}
+		pkt = append(pkt, digit)
+		if n == 0 {
+			break
+		}
+	}
+	pkt = append(pkt, body...)
+	_, err := w.Write(pkt)
+	return err
+}
+
+func readPacket(r *bufio.Reader) (byte, []byte, error) {
+	typ, err := r.ReadByte()
+	if err != nil {
+		return 0, nil, err
+	}
+
+	var n, mult int = 0, 1
+	for i := 0; i < 4; i++ {
+		b, err := r.ReadByte()
+		if err != nil {
+			return 0, nil, err
+		}
+		n += int(b&0x7F) * mult
+		if b&0x80 == 0 {
+			break
+		}
+		mult *= 128
+	}
+
+	data := make([]byte, n)
+	if _, err := io.ReadFull(r, data); err != nil {
+		return 0, nil, err
+	}
+	return typ, data, nil
+}
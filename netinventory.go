@@ -0,0 +1,86 @@
+package main
+
+import (
+	"log/slog"
+	"net"
+	"slices"
+	"strings"
+	"time"
+)
+
+// NetworkInterface is one adapter as listed in the inventory. SpeedMbps is
+// the link speed, 0 when unknown or down.
+type NetworkInterface struct {
+	Name      string   `json:"name"`
+	MAC       string   `json:"mac,omitempty"`
+	IPv4      []string `json:"ipv4,omitempty"`
+	IPv6      []string `json:"ipv6,omitempty"`
+	SpeedMbps uint64   `json:"speedMbps,omitempty"`
+	Up        bool     `json:"up"`
+}
+
+// addressPollInterval is how often runInventory looks for new addresses.
+const addressPollInterval = time.Minute
+
+// getNetworkInterfaces lists every adapter but the loopback ones, with
+// their addresses. IPv6 link-local addresses are left out; they cannot be
+// used to reach the machine from elsewhere.
+func getNetworkInterfaces() []NetworkInterface {
+	ifaces, err := net.Interfaces()
+	if err != nil {
+		return nil
+	}
+	var result []NetworkInterface
+	for _, ifc := range ifaces {
+		if ifc.Flags&net.FlagLoopback != 0 {
+			continue
+		}
+		ni := NetworkInterface{Name: ifc.Name, MAC: ifc.HardwareAddr.String(), Up: ifc.Flags&net.FlagUp != 0 && ifc.Flags&net.FlagRunning != 0}
+		addrs, _ := ifc.Addrs()
+		for _, a := range addrs {
+			ipNet, ok := a.(*net.IPNet)
+			if !ok {
+				continue
+			}
+			switch ip := ipNet.IP; {
+			case ip.To4() != nil:
+				ni.IPv4 = append(ni.IPv4, ip.String())
+			case !ip.IsLinkLocalUnicast():
+				ni.IPv6 = append(ni.IPv6, ip.String())
+			}
+		}
+		if ni.Up {
+			ni.SpeedMbps = linkSpeedMbps(ifc)
+		}
+		result = append(result, ni)
+	}
+	return result
+}
+
+// addressKey is what runInventory compares: the addresses of each
+// interface, in a stable order.
+func addressKey(ifaces []NetworkInterface) string {
+	var parts []string
+	for _, ni := range ifaces {
+		parts = append(parts, ni.Name+"="+strings.Join(slices.Sorted(slices.Values(append(ni.IPv4, ni.IPv6...))), ","))
+	}
+	slices.Sort(parts)
+	return strings.Join(parts, ";")
+}
+
+// runInventory sends the inventory at startup and again whenever the
+// addresses change, e.g. a new DHCP lease, another network or a VPN
+// coming up, so the server always knows where to reach the machine.
+func runInventory() {
+	sendInventory()
+	last := addressKey(getNetworkInterfaces())
+	for range time.Tick(addressPollInterval) {
+		key := addressKey(getNetworkInterfaces())
+		if key == last {
+			continue
+		}
+		last = key
+		slog.Info("Network addresses changed, resending inventory")
+		sendInventory()
+	}
+}
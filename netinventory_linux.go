@@ -0,0 +1,9 @@
+package main
+
+import "net"
+
+// linkSpeedMbps reads the speed the driver negotiated; Wi-Fi and virtual
+// interfaces have none.
+func linkSpeedMbps(ifc net.Interface) uint64 {
+	return uint64(max(readSysfsInt("/sys/class/net/"+ifc.Name+"/speed"), 0))
+}
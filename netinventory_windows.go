@@ -0,0 +1,37 @@
+package main
+
+import (
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// linkSpeedMbps reads the adapter's transmit speed from
+// GetAdaptersAddresses, which reports bits per second.
+func linkSpeedMbps(ifc net.Interface) uint64 {
+	size := uint32(15 * 1024)
+	for range 3 {
+		buf := make([]byte, size)
+		first := (*windows.IpAdapterAddresses)(unsafe.Pointer(&buf[0]))
+		err := windows.GetAdaptersAddresses(windows.AF_UNSPEC, windows.GAA_FLAG_SKIP_ANYCAST|windows.GAA_FLAG_SKIP_MULTICAST|windows.GAA_FLAG_SKIP_DNS_SERVER, 0, first, &size)
+		if err == windows.ERROR_BUFFER_OVERFLOW {
+			continue
+		}
+		if err != nil {
+			return 0
+		}
+		for a := first; a != nil; a = a.Next {
+			if int(a.IfIndex) != ifc.Index && int(a.Ipv6IfIndex) != ifc.Index {
+				continue
+			}
+			// An unknown speed is reported as the largest value.
+			if a.TransmitLinkSpeed == 0 || a.TransmitLinkSpeed == ^uint64(0) {
+				return 0
+			}
+			return a.TransmitLinkSpeed / 1_000_000
+		}
+		return 0
+	}
+	return 0
+}
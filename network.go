@@ -0,0 +1,100 @@
+package main
+
+import (
+	"time"
+
+	"github.com/shirou/gopsutil/v3/net"
+)
+
+type TCPStats struct {
+	Established int `json:"established"`
+	TimeWait    int `json:"timeWait"`
+	Listen      int `json:"listen"`
+}
+
+func getTCPStats() (TCPStats, bool) {
+	conns, err := net.Connections("tcp")
+	if err != nil {
+		return TCPStats{}, false
+	}
+
+	var s TCPStats
+	for _, c := range conns {
+		switch c.Status {
+		case "ESTABLISHED":
+			s.Established++
+		case "TIME_WAIT":
+			s.TimeWait++
+		case "LISTEN":
+			s.Listen++
+		}
+	}
+	return s, true
+}
+
+type InterfaceErrors struct {
+	Name    string `json:"name"`
+	ErrIn   uint64 `json:"errIn"`
+	ErrOut  uint64 `json:"errOut"`
+	DropIn  uint64 `json:"dropIn"`
+	DropOut uint64 `json:"dropOut"`
+}
+
+var lastIOCounters = map[string]net.IOCountersStat{}
+
+// getInterfaceCounters reads the per-interface counters that the error
+// counts and data usage both work from.
+func getInterfaceCounters() []net.IOCountersStat {
+	counters, _ := net.IOCounters(true)
+	return counters
+}
+
+func getInterfaceErrors(counters []net.IOCountersStat) []InterfaceErrors {
+	var result []InterfaceErrors
+	for _, c := range counters {
+		prev, ok := lastIOCounters[c.Name]
+		lastIOCounters[c.Name] = c
+		if !ok || c.BytesRecv+c.BytesSent == 0 {
+			continue
+		}
+		result = append(result, InterfaceErrors{
+			Name:    c.Name,
+			ErrIn:   delta(c.Errin, prev.Errin),
+			ErrOut:  delta(c.Errout, prev.Errout),
+			DropIn:  delta(c.Dropin, prev.Dropin),
+			DropOut: delta(c.Dropout, prev.Dropout),
+		})
+	}
+	return result
+}
+
+var lastTotals struct {
+	at         time.Time
+	sent, recv uint64
+}
+
+// getNetworkThroughput returns bytes per second over all interfaces since
+// the previous call; the first call returns zero.
+func getNetworkThroughput() (sent, recv float64) {
+	counters, err := net.IOCounters(false)
+	if err != nil || len(counters) == 0 {
+		return 0, 0
+	}
+	now := time.Now()
+	c := counters[0]
+	if !lastTotals.at.IsZero() {
+		secs := now.Sub(lastTotals.at).Seconds()
+		sent = float64(delta(c.BytesSent, lastTotals.sent)) / secs
+		recv = float64(delta(c.BytesRecv, lastTotals.recv)) / secs
+	}
+	lastTotals.at, lastTotals.sent, lastTotals.recv = now, c.BytesSent, c.BytesRecv
+	return sent, recv
+}
+
+// delta tolerates counter resets (adapter reset, driver reload).
+func delta(cur, prev uint64) uint64 {
+	if cur < prev {
+		return cur
+	}
+	return cur - prev
+}
@@ -0,0 +1,109 @@
+package main
+
+import (
+	"context"
+	"encoding/binary"
+	"fmt"
+	"net"
+	"sync"
+	"time"
+)
+
+// M_NTP_SERVER is the server the clock is compared against, e.g.
+// time.windows.com; empty turns the check off. It is asked every
+// M_NTP_INTERVAL, 10 minutes by default.
+var (
+	ntpServer   = getEnv("M_NTP_SERVER", "")
+	ntpInterval = getInterval("M_NTP_INTERVAL", 10*time.Minute)
+)
+
+// clockOffset is the last measured offset; a failed check keeps the
+// previous one, and its error, until the next interval.
+var clockOffset struct {
+	sync.Mutex
+	value     time.Duration
+	ok        bool
+	err       error
+	checkedAt time.Time
+}
+
+func getClockOffset(ctx context.Context) (time.Duration, bool, error) {
+	clockOffset.Lock()
+	defer clockOffset.Unlock()
+
+	if time.Since(clockOffset.checkedAt) >= ntpInterval {
+		clockOffset.checkedAt = time.Now()
+		offset, err := queryNTP(ctx, ntpServer)
+		if err == nil {
+			clockOffset.value, clockOffset.ok = offset, true
+		}
+		clockOffset.err = err
+	}
+	return clockOffset.value, clockOffset.ok, clockOffset.err
+}
+
+// ntpEpochOffset is the seconds from 1900, the NTP epoch, to 1970.
+const ntpEpochOffset = 2208988800
+
+// queryNTP sends one SNTP request and returns how far the local clock is
+// ahead of the server's, positive when it runs fast.
+func queryNTP(ctx context.Context, server string) (time.Duration, error) {
+	if _, _, err := net.SplitHostPort(server); err != nil {
+		server = net.JoinHostPort(server, "123")
+	}
+	var d net.Dialer
+	conn, err := d.DialContext(ctx, "udp", server)
+	if err != nil {
+		return 0, fmt.Errorf("dial: %w", err)
+	}
+	defer conn.Close()
+	deadline := time.Now().Add(3 * time.Second)
+	if dl, ok := ctx.Deadline(); ok && dl.Before(deadline) {
+		deadline = dl
+	}
+	conn.SetDeadline(deadline)
+
+	req := make([]byte, 48)
+	req[0] = 0x23 // no leap warning, version 4, client mode
+	t1 := time.Now()
+	if _, err := conn.Write(req); err != nil {
+		return 0, fmt.Errorf("send: %w", err)
+	}
+	resp := make([]byte, 48)
+	n, err := conn.Read(resp)
+	t4 := time.Now()
+	if err != nil {
+		return 0, fmt.Errorf("receive: %w", err)
+	}
+	if n < 48 || resp[0]&0x07 != 4 {
+		return 0, fmt.Errorf("not an NTP server reply")
+	}
+	if resp[1] == 0 {
+		return 0, fmt.Errorf("server is not synchronized")
+	}
+
+	t2 := ntpTime(resp[32:40])
+	t3 := ntpTime(resp[40:48])
+	// The server's clock minus ours, averaged over both legs.
+	serverAhead := (t2.Sub(t1) + t3.Sub(t4)) / 2
+	return -serverAhead, nil
+}
+
+func ntpTime(b []byte) time.Time {
+	secs := binary.BigEndian.Uint32(b[0:4])
+	frac := binary.BigEndian.Uint32(b[4:8])
+	nsec := int64(frac) * 1e9 >> 32
+	return time.Unix(int64(secs)-ntpEpochOffset, nsec)
+}
+
+func collectClock(ctx context.Context) (fields, error) {
+	if ntpServer == "" {
+		return nil, nil
+	}
+	offset, ok, err := getClockOffset(ctx)
+	if !ok {
+		return nil, err
+	}
+	ms := float64(offset) / float64(time.Millisecond)
+	return func(m *Metrics) { m.ClockOffsetMs = &ms }, err
+}
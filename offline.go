@@ -0,0 +1,70 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"sync/atomic"
+	"time"
+)
+
+// offlineNotifyAfter is how long reports must keep failing before a toast
+// says so; 0 turns the toasts off.
+var offlineNotifyAfter = time.Duration(getInt("M_NOTIFY_OFFLINE_MIN", 5)) * time.Minute
+
+// offlineNotifier raises a toast once the server has been unreachable for
+// offlineNotifyAfter, and another when a report gets through again.
+type offlineNotifier struct {
+	downSince time.Time
+	notified  bool
+}
+
+func (n *offlineNotifier) Update(ok bool, now time.Time) {
+	if offlineNotifyAfter <= 0 {
+		return
+	}
+	if ok {
+		if n.notified {
+			slog.Info("Connection restored", "offline", now.Sub(n.downSince).Round(time.Second))
+			showToast("go-win-monitor", tr("Reconnected after %s offline", formatOffline(now.Sub(n.downSince))))
+		}
+		n.downSince, n.notified = time.Time{}, false
+		return
+	}
+	if n.downSince.IsZero() {
+		n.downSince = now
+	}
+	if !n.notified && now.Sub(n.downSince) >= offlineNotifyAfter {
+		n.notified = true
+		showToast("go-win-monitor", tr("No report has gone through for %s", formatOffline(now.Sub(n.downSince))))
+	}
+}
+
+// authRejected is set while the server rejects the secret, for the
+// settings page.
+var authRejected atomic.Bool
+
+// authNotifier raises a toast and shows the tray's "Enter new secret…" item
+// once the server rejects the secret, and hides the item when a report gets
+// through again.
+type authNotifier struct {
+	failing bool
+}
+
+func (n *authNotifier) Update(failed bool) {
+	if failed == n.failing {
+		return
+	}
+	n.failing = failed
+	authRejected.Store(failed)
+	showSecretMenu(failed)
+	if failed {
+		showToast(tr("Authentication failed"), tr("The server rejected this computer's secret. Choose \"Enter new secret…\" in the tray menu to retry with a new one"))
+	}
+}
+
+func formatOffline(d time.Duration) string {
+	if d < time.Hour {
+		return fmt.Sprintf("%d min", int(d.Minutes()))
+	}
+	return fmt.Sprintf("%dh%02d", int(d.Hours()), int(d.Minutes())%60)
+}
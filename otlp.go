@@ -0,0 +1,130 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"runtime"
+	"strconv"
+	"strings"
+)
+
+// otlpExporter posts metrics to an OpenTelemetry collector using the
+// OTLP/HTTP JSON encoding.
+type otlpExporter struct {
+	endpoint string
+	headers  map[string]string
+	resource otlpResource
+}
+
+type otlpKeyValue struct {
+	Key   string `json:"key"`
+	Value struct {
+		StringValue string `json:"stringValue"`
+	} `json:"value"`
+}
+
+type otlpResource struct {
+	Attributes []otlpKeyValue `json:"attributes"`
+}
+
+type otlpDataPoint struct {
+	Attributes   []otlpKeyValue `json:"attributes,omitempty"`
+	TimeUnixNano string         `json:"timeUnixNano"`
+	AsDouble     float64        `json:"asDouble"`
+}
+
+type otlpMetric struct {
+	Name        string `json:"name"`
+	Description string `json:"description,omitempty"`
+	Gauge       struct {
+		DataPoints []otlpDataPoint `json:"dataPoints"`
+	} `json:"gauge"`
+}
+
+func otlpAttrs(kv ...string) []otlpKeyValue {
+	var attrs []otlpKeyValue
+	for i := 0; i+1 < len(kv); i += 2 {
+		a := otlpKeyValue{Key: kv[i]}
+		a.Value.StringValue = kv[i+1]
+		attrs = append(attrs, a)
+	}
+	return attrs
+}
+
+func newOTLPExporter(endpoint, headers string) *otlpExporter {
+	host, _ := os.Hostname()
+	e := &otlpExporter{
+		endpoint: strings.TrimRight(endpoint, "/") + "/v1/metrics",
+		headers:  map[string]string{},
+		resource: otlpResource{Attributes: otlpAttrs(
+			"service.name", "go-win-monitor",
+			"service.version", version,
+			"host.name", host,
+			"os.type", runtime.GOOS,
+		)},
+	}
+	for _, h := range parseList(headers) {
+		if k, v, ok := strings.Cut(h, "="); ok {
+			e.headers[strings.TrimSpace(k)] = strings.TrimSpace(v)
+		}
+	}
+	return e
+}
+
+func (e *otlpExporter) Write(m Metrics) error {
+	ts := strconv.FormatInt(m.Timestamp.UnixNano(), 10)
+
+	var metrics []*otlpMetric
+	visitSamples(m, func(name, help string, value float64, labels ...string) {
+		name = "winmon." + name
+		if len(metrics) == 0 || metrics[len(metrics)-1].Name != name {
+			metrics = append(metrics, &otlpMetric{Name: name, Description: help})
+		}
+		cur := metrics[len(metrics)-1]
+		cur.Gauge.DataPoints = append(cur.Gauge.DataPoints, otlpDataPoint{
+			Attributes:   otlpAttrs(labels...),
+			TimeUnixNano: ts,
+			AsDouble:     value,
+		})
+	})
+
+	body, err := json.Marshal(map[string]any{
+		"resourceMetrics": []any{map[string]any{
+			"resource": e.resource,
+			"scopeMetrics": []any{map[string]any{
+				"scope":   map[string]string{"name": "go-win-monitor", "version": version},
+				"metrics": metrics,
+			}},
+		}},
+	})
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	req, err := http.NewRequest("POST", e.endpoint, bytes.NewReader(body))
+	if err != nil {
+		return fmt.Errorf("new request: %w", err)
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("User-Agent", "go-win-monitor/"+version)
+	for k, v := range e.headers {
+		req.Header.Set(k, v)
+	}
+
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		httpClient.CloseIdleConnections()
+		return fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+	io.Copy(io.Discard, resp.Body)
+
+	if resp.StatusCode != http.StatusOK {
+		return &statusError{Code: resp.StatusCode}
+	}
+	return nil
+}
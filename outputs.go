@@ -0,0 +1,40 @@
+package main
+
+// output is one destination for metric samples. Each gets its own reporter,
+// so one being down doesn't hold back the others.
+type output struct {
+	name string
+	dest string
+	send func(Metrics) error
+}
+
+func metricOutputs() ([]output, error) {
+	var outs []output
+	if transportName != "none" {
+		outs = append(outs, output{name: transportName, dest: sink.Status(), send: sendMetrics})
+	}
+
+	for _, t := range apiTargets {
+		outs = append(outs, output{name: t.name, dest: t.endpoint("report"), send: t.sendMetrics})
+	}
+
+	if influxURL != "" {
+		w, err := newInfluxWriter(influxURL, influxOrg, influxBucket, influxToken)
+		if err != nil {
+			return nil, err
+		}
+		outs = append(outs, output{name: "influx", dest: w.Destination(), send: w.Write})
+	}
+
+	if statsdAddr != "" {
+		outs = append(outs, output{name: "statsd", dest: "udp://" + statsdAddr, send: newStatsdEmitter(statsdAddr).Write})
+	}
+	if graphiteAddr != "" {
+		outs = append(outs, output{name: "graphite", dest: "tcp://" + graphiteAddr, send: newGraphiteEmitter(graphiteAddr).Write})
+	}
+	if otlpEndpoint != "" {
+		e := newOTLPExporter(otlpEndpoint, otlpHeaders)
+		outs = append(outs, output{name: "otlp", dest: e.endpoint, send: e.Write})
+	}
+	return outs, nil
+}
@@ -0,0 +1,290 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"strings"
+	"sync"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	gdi32                          = windows.NewLazySystemDLL("gdi32.dll")
+	procRegisterClassEx            = user32.NewProc("RegisterClassExW")
+	procCreateWindowEx             = user32.NewProc("CreateWindowExW")
+	procDefWindowProc              = user32.NewProc("DefWindowProcW")
+	procGetMessage                 = user32.NewProc("GetMessageW")
+	procTranslateMessage           = user32.NewProc("TranslateMessage")
+	procDispatchMessage            = user32.NewProc("DispatchMessageW")
+	procShowWindow                 = user32.NewProc("ShowWindow")
+	procSetWindowPos               = user32.NewProc("SetWindowPos")
+	procInvalidateRect             = user32.NewProc("InvalidateRect")
+	procBeginPaint                 = user32.NewProc("BeginPaint")
+	procEndPaint                   = user32.NewProc("EndPaint")
+	procGetClientRect              = user32.NewProc("GetClientRect")
+	procDrawText                   = user32.NewProc("DrawTextW")
+	procSetLayeredWindowAttributes = user32.NewProc("SetLayeredWindowAttributes")
+	procSystemParametersInfo       = user32.NewProc("SystemParametersInfoW")
+	procCreateSolidBrush           = gdi32.NewProc("CreateSolidBrush")
+	procCreateFont                 = gdi32.NewProc("CreateFontW")
+	procSelectObject               = gdi32.NewProc("SelectObject")
+	procSetTextColor               = gdi32.NewProc("SetTextColor")
+	procSetBkMode                  = gdi32.NewProc("SetBkMode")
+)
+
+const (
+	wmPaint     = 0x000f
+	wmNCHitTest = 0x0084
+	htCaption   = 2
+
+	wsPopup         = 0x80000000
+	wsExTopmost     = 0x00000008
+	wsExToolWindow  = 0x00000080
+	wsExLayered     = 0x00080000
+	wsExNoActivate  = 0x08000000
+	swHide          = 0
+	swShowNoActive  = 4
+	swpNoMove       = 0x0002
+	swpNoZOrder     = 0x0004
+	swpNoActivate   = 0x0010
+	lwaAlpha        = 0x2
+	spiGetWorkArea  = 0x0030
+	dtNoPrefix      = 0x0800
+	bkTransparent   = 1
+	overlayWidth    = 190
+	overlayLine     = 18
+	overlayPadding  = 8
+	overlayOpacity  = 220
+	overlayBgColor  = 0x202020
+	overlayFgColor  = 0xe0e0e0
+	overlayFontSize = 15
+	defaultCharset  = 1
+	clearTypeRender = 5
+)
+
+type wndClassEx struct {
+	cbSize        uint32
+	style         uint32
+	lpfnWndProc   uintptr
+	cbClsExtra    int32
+	cbWndExtra    int32
+	hInstance     uintptr
+	hIcon         uintptr
+	hCursor       uintptr
+	hbrBackground uintptr
+	lpszMenuName  *uint16
+	lpszClassName *uint16
+	hIconSm       uintptr
+}
+
+type winMsg struct {
+	hwnd    uintptr
+	message uint32
+	wParam  uintptr
+	lParam  uintptr
+	time    uint32
+	pt      struct{ x, y int32 }
+	private uint32
+}
+
+type paintStruct struct {
+	hdc         uintptr
+	erase       int32
+	rcPaint     windows.Rect
+	restore     int32
+	incUpdate   int32
+	rgbReserved [32]byte
+}
+
+var (
+	overlayEnabled = getEnv("M_OVERLAY", "") == "1"
+	overlayCorner  = getEnv("M_OVERLAY_CORNER", "top-right")
+)
+
+// overlay is a small always-on-top window with the latest readings. It can
+// be dragged anywhere and never takes the focus, so it stays out of the way
+// of games and full-screen apps. The window and its message loop live on one
+// locked thread, started the first time it is shown.
+var overlay struct {
+	sync.Mutex
+	once  sync.Once
+	hwnd  uintptr
+	text  string
+	lines int
+}
+
+func setOverlayVisible(on bool) {
+	if on {
+		overlay.once.Do(func() {
+			ready := make(chan struct{})
+			go runOverlay(ready)
+			<-ready
+		})
+	}
+	overlay.Lock()
+	hwnd := overlay.hwnd
+	overlay.Unlock()
+	if hwnd == 0 {
+		return
+	}
+	if on {
+		procShowWindow.Call(hwnd, swShowNoActive)
+	} else {
+		procShowWindow.Call(hwnd, swHide)
+	}
+}
+
+// updateOverlay redraws the overlay with m, growing or shrinking it to fit.
+func updateOverlay(m Metrics) {
+	lines := []string{fmt.Sprintf("CPU  %.0f%%", m.CPU), fmt.Sprintf("RAM  %.0f%%", m.RAM)}
+	if m.GPU >= 0 {
+		lines = append(lines, fmt.Sprintf("GPU  %.0f%%", m.GPU))
+	}
+	hottest := -1
+	for _, t := range m.DiskTemps {
+		hottest = max(hottest, t.TempC)
+	}
+	if hottest >= 0 {
+		lines = append(lines, tr("Disk  %d °C", hottest))
+	}
+	lines = append(lines, tr("Net  ↓ %s ↑ %s", formatRate(m.NetRecvBps), formatRate(m.NetSentBps)))
+
+	// The window calls are made without the lock, which painting needs.
+	overlay.Lock()
+	overlay.text = strings.Join(lines, "\n")
+	hwnd, resize := overlay.hwnd, len(lines) != overlay.lines
+	overlay.lines = len(lines)
+	overlay.Unlock()
+	if hwnd == 0 {
+		return
+	}
+	if resize {
+		procSetWindowPos.Call(hwnd, 0, 0, 0, overlayWidth, uintptr(overlayHeight(len(lines))), swpNoMove|swpNoZOrder|swpNoActivate)
+	}
+	procInvalidateRect.Call(hwnd, 0, 1)
+}
+
+func overlayHeight(lines int) int32 {
+	return int32(lines*overlayLine + 2*overlayPadding)
+}
+
+func runOverlay(ready chan<- struct{}) {
+	runtime.LockOSThread()
+	hwnd, err := createOverlay()
+	if err != nil {
+		slog.Error("Overlay unavailable", "err", err)
+	}
+	overlay.Lock()
+	overlay.hwnd = hwnd
+	overlay.Unlock()
+	close(ready)
+	if hwnd == 0 {
+		return
+	}
+	pumpMessages()
+}
+
+// pumpMessages dispatches the window messages of the calling thread until
+// WM_QUIT.
+func pumpMessages() {
+	var msg winMsg
+	for {
+		if r, _, _ := procGetMessage.Call(uintptr(unsafe.Pointer(&msg)), 0, 0, 0); int32(r) <= 0 {
+			return
+		}
+		procTranslateMessage.Call(uintptr(unsafe.Pointer(&msg)))
+		procDispatchMessage.Call(uintptr(unsafe.Pointer(&msg)))
+	}
+}
+
+func createOverlay() (uintptr, error) {
+	className, _ := windows.UTF16PtrFromString("GoWinMonitorOverlay")
+	var instance windows.Handle
+	windows.GetModuleHandleEx(0, nil, &instance)
+	brush, _, _ := procCreateSolidBrush.Call(overlayBgColor)
+	wc := wndClassEx{
+		lpfnWndProc:   windows.NewCallback(overlayWndProc),
+		hInstance:     uintptr(instance),
+		hbrBackground: brush,
+		lpszClassName: className,
+	}
+	wc.cbSize = uint32(unsafe.Sizeof(wc))
+	if r, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wc))); r == 0 {
+		return 0, fmt.Errorf("RegisterClassEx: %w", err)
+	}
+
+	overlay.Lock()
+	lines := max(overlay.lines, 3)
+	overlay.Unlock()
+	x, y := overlayOrigin(overlayWidth, overlayHeight(lines))
+	hwnd, _, err := procCreateWindowEx.Call(
+		wsExTopmost|wsExToolWindow|wsExLayered|wsExNoActivate,
+		uintptr(unsafe.Pointer(className)), 0, wsPopup,
+		uintptr(x), uintptr(y), overlayWidth, uintptr(overlayHeight(lines)),
+		0, 0, uintptr(instance), 0)
+	if hwnd == 0 {
+		return 0, fmt.Errorf("CreateWindowEx: %w", err)
+	}
+	procSetLayeredWindowAttributes.Call(hwnd, 0, overlayOpacity, lwaAlpha)
+	return hwnd, nil
+}
+
+// overlayOrigin places the window in the M_OVERLAY_CORNER corner of the
+// work area, clear of the taskbar.
+func overlayOrigin(w, h int32) (int32, int32) {
+	var area windows.Rect
+	procSystemParametersInfo.Call(spiGetWorkArea, 0, uintptr(unsafe.Pointer(&area)), 0)
+	const margin = 12
+	x, y := area.Right-w-margin, area.Top+margin
+	switch overlayCorner {
+	case "top-left":
+		x = area.Left + margin
+	case "bottom-left":
+		x, y = area.Left+margin, area.Bottom-h-margin
+	case "bottom-right":
+		y = area.Bottom - h - margin
+	case "top-right":
+	default:
+		slog.Warn("Unknown M_OVERLAY_CORNER, using top-right", "corner", overlayCorner)
+	}
+	return x, y
+}
+
+var overlayFont uintptr
+
+func overlayWndProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	switch msg {
+	case wmNCHitTest:
+		// Dragging anywhere moves the window.
+		return htCaption
+	case wmPaint:
+		var ps paintStruct
+		hdc, _, _ := procBeginPaint.Call(hwnd, uintptr(unsafe.Pointer(&ps)))
+		if overlayFont == 0 {
+			face, _ := windows.UTF16PtrFromString("Segoe UI")
+			// A negative height asks for the character height in pixels.
+			height := int32(-overlayFontSize)
+			overlayFont, _, _ = procCreateFont.Call(uintptr(height), 0, 0, 0, 600, 0, 0, 0,
+				defaultCharset, 0, 0, clearTypeRender, 0, uintptr(unsafe.Pointer(face)))
+		}
+		procSelectObject.Call(hdc, overlayFont)
+		procSetTextColor.Call(hdc, overlayFgColor)
+		procSetBkMode.Call(hdc, bkTransparent)
+
+		overlay.Lock()
+		text, _ := windows.UTF16FromString(overlay.text)
+		overlay.Unlock()
+		var r windows.Rect
+		procGetClientRect.Call(hwnd, uintptr(unsafe.Pointer(&r)))
+		r.Left += overlayPadding
+		r.Top += overlayPadding
+		procDrawText.Call(hdc, uintptr(unsafe.Pointer(&text[0])), uintptr(len(text)-1), uintptr(unsafe.Pointer(&r)), dtNoPrefix)
+		procEndPaint.Call(hwnd, uintptr(unsafe.Pointer(&ps)))
+		return 0
+	}
+	r, _, _ := procDefWindowProc.Call(hwnd, msg, wParam, lParam)
+	return r
+}
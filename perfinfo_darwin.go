@@ -0,0 +1,18 @@
+package main
+
+import (
+	"github.com/shirou/gopsutil/v3/mem"
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// getSystemCounters fills in the process count and the file cache size.
+// Thread and handle counts would need a walk over every process, which
+// is too slow to do on every tick.
+func getSystemCounters(m *Metrics) {
+	if pids, err := process.Pids(); err == nil {
+		m.Processes = uint32(len(pids))
+	}
+	if v, err := mem.VirtualMemory(); err == nil {
+		m.RAMCachedMB = v.Cached / 1024 / 1024
+	}
+}
@@ -0,0 +1,37 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/mem"
+)
+
+// getSystemCounters fills in the process and thread counts from /proc, the
+// open file handles from /proc/sys/fs/file-nr and the page cache size.
+// Linux has no kernel pools comparable to the Windows ones.
+func getSystemCounters(m *Metrics) {
+	if entries, err := os.ReadDir("/proc"); err == nil {
+		for _, e := range entries {
+			if _, err := strconv.Atoi(e.Name()); err == nil && e.IsDir() {
+				m.Processes++
+			}
+		}
+	}
+	// The fourth field of loadavg is running/total scheduling entities,
+	// i.e. threads.
+	if f := strings.Fields(readSysfs("/proc/loadavg")); len(f) >= 4 {
+		if _, total, ok := strings.Cut(f[3], "/"); ok {
+			n, _ := strconv.ParseUint(total, 10, 32)
+			m.Threads = uint32(n)
+		}
+	}
+	if f := strings.Fields(readSysfs("/proc/sys/fs/file-nr")); len(f) > 0 {
+		n, _ := strconv.ParseUint(f[0], 10, 32)
+		m.Handles = uint32(n)
+	}
+	if v, err := mem.VirtualMemory(); err == nil {
+		m.RAMCachedMB = v.Cached / 1024 / 1024
+	}
+}
@@ -0,0 +1,53 @@
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type performanceInformation struct {
+	cb                uint32
+	CommitTotal       uintptr
+	CommitLimit       uintptr
+	CommitPeak        uintptr
+	PhysicalTotal     uintptr
+	PhysicalAvailable uintptr
+	SystemCache       uintptr
+	KernelTotal       uintptr
+	KernelPaged       uintptr
+	KernelNonpaged    uintptr
+	PageSize          uintptr
+	HandleCount       uint32
+	ProcessCount      uint32
+	ThreadCount       uint32
+}
+
+var (
+	psapi                  = windows.NewLazySystemDLL("psapi.dll")
+	procGetPerformanceInfo = psapi.NewProc("GetPerformanceInfo")
+)
+
+func getPerformanceInfo() (performanceInformation, bool) {
+	var info performanceInformation
+	info.cb = uint32(unsafe.Sizeof(info))
+	r, _, _ := procGetPerformanceInfo.Call(uintptr(unsafe.Pointer(&info)), uintptr(info.cb))
+	return info, r != 0
+}
+
+// getSystemCounters fills in the process, thread and handle counts and the
+// cache and kernel pool sizes.
+func getSystemCounters(m *Metrics) {
+	perf, ok := getPerformanceInfo()
+	if !ok {
+		return
+	}
+	m.Processes = perf.ProcessCount
+	m.Threads = perf.ThreadCount
+	m.Handles = perf.HandleCount
+
+	page := uint64(perf.PageSize)
+	m.RAMCachedMB = uint64(perf.SystemCache) * page / 1024 / 1024
+	m.PagedPoolMB = uint64(perf.KernelPaged) * page / 1024 / 1024
+	m.NonPagedPoolMB = uint64(perf.KernelNonpaged) * page / 1024 / 1024
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"strings"
+	"sync"
+)
+
+type PingResult struct {
+	Host  string  `json:"host"`
+	RTTMs float64 `json:"rttMs"`
+	Loss  float64 `json:"loss"`
+}
+
+const (
+	pingCount     = 4
+	pingTimeoutMs = 1000
+)
+
+func parseList(s string) []string {
+	var out []string
+	for _, v := range strings.Split(s, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			out = append(out, v)
+		}
+	}
+	return out
+}
+
+func pingHosts(hosts []string) []PingResult {
+	results := make([]PingResult, len(hosts))
+	var wg sync.WaitGroup
+	for i, h := range hosts {
+		wg.Add(1)
+		go func(i int, h string) {
+			defer wg.Done()
+			results[i] = pingHost(h)
+		}(i, h)
+	}
+	wg.Wait()
+	return results
+}
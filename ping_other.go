@@ -0,0 +1,34 @@
+//go:build !windows
+
+package main
+
+import (
+	"os/exec"
+	"regexp"
+	"runtime"
+	"strconv"
+)
+
+var (
+	pingLossRe = regexp.MustCompile(`([\d.]+)% packet loss`)
+	pingRTTRe  = regexp.MustCompile(`= [\d.]+/([\d.]+)/`)
+)
+
+// pingHost runs the system ping, which is setuid or uses ping sockets, so
+// the agent needs no raw socket privilege of its own.
+func pingHost(host string) PingResult {
+	r := PingResult{Host: host, RTTMs: -1, Loss: 100}
+
+	wait := strconv.Itoa(pingTimeoutMs / 1000)
+	if runtime.GOOS == "darwin" {
+		wait = strconv.Itoa(pingTimeoutMs)
+	}
+	out, _ := exec.Command("ping", "-n", "-c", strconv.Itoa(pingCount), "-W", wait, host).Output()
+	if m := pingLossRe.FindSubmatch(out); m != nil {
+		r.Loss, _ = strconv.ParseFloat(string(m[1]), 64)
+	}
+	if m := pingRTTRe.FindSubmatch(out); m != nil {
+		r.RTTMs, _ = strconv.ParseFloat(string(m[1]), 64)
+	}
+	return r
+}
@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/binary"
+	"fmt"
+	"net"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	iphlpapi            = windows.NewLazySystemDLL("iphlpapi.dll")
+	procIcmpCreateFile  = iphlpapi.NewProc("IcmpCreateFile")
+	procIcmpCloseHandle = iphlpapi.NewProc("IcmpCloseHandle")
+	procIcmpSendEcho    = iphlpapi.NewProc("IcmpSendEcho")
+)
+
+func pingHost(host string) PingResult {
+	r := PingResult{Host: host, RTTMs: -1, Loss: 100}
+
+	addr, err := net.ResolveIPAddr("ip4", host)
+	if err != nil {
+		return r
+	}
+
+	handle, _, _ := procIcmpCreateFile.Call()
+	if windows.Handle(handle) == windows.InvalidHandle {
+		return r
+	}
+	defer procIcmpCloseHandle.Call(handle)
+
+	var received int
+	var total uint32
+	for i := 0; i < pingCount; i++ {
+		rtt, err := icmpEcho(handle, addr.IP.To4())
+		if err != nil {
+			continue
+		}
+		received++
+		total += rtt
+	}
+
+	r.Loss = float64(pingCount-received) / pingCount * 100
+	if received > 0 {
+		r.RTTMs = float64(total) / float64(received)
+	}
+	return r
+}
+
+func icmpEcho(handle uintptr, ip net.IP) (uint32, error) {
+	payload := []byte("go-win-monitor")
+	// ICMP_ECHO_REPLY plus room for the echoed payload and an ICMP error message.
+	reply := make([]byte, 64+len(payload)+8)
+
+	n, _, _ := procIcmpSendEcho.Call(
+		handle,
+		uintptr(binary.LittleEndian.Uint32(ip)),
+		uintptr(unsafe.Pointer(&payload[0])),
+		uintptr(len(payload)),
+		0,
+		uintptr(unsafe.Pointer(&reply[0])),
+		uintptr(len(reply)),
+		pingTimeoutMs,
+	)
+	if n == 0 {
+		return 0, fmt.Errorf("no reply")
+	}
+
+	status := binary.LittleEndian.Uint32(reply[4:8])
+	if status != 0 {
+		return 0, fmt.Errorf("icmp status %d", status)
+	}
+	return binary.LittleEndian.Uint32(reply[8:12]), nil
+}
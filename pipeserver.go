@@ -0,0 +1,47 @@
+package main
+
+import (
+	"bufio"
+	"encoding/json"
+	"io"
+)
+
+type pipeRequest struct {
+	Method string `json:"method"`
+}
+
+type pipeResponse struct {
+	Metrics *Metrics `json:"metrics,omitempty"`
+	Error   string   `json:"error,omitempty"`
+	OK      bool     `json:"ok"`
+}
+
+// servePipeRequests answers one client until it hangs up.
+func servePipeRequests(f io.ReadWriter) {
+	sc := bufio.NewScanner(f)
+	enc := json.NewEncoder(f)
+	for sc.Scan() {
+		var req pipeRequest
+		var resp pipeResponse
+		if err := json.Unmarshal(sc.Bytes(), &req); err != nil {
+			resp.Error = "invalid request"
+		} else {
+			switch req.Method {
+			case "ping":
+				resp.OK = true
+			case "metrics":
+				if m, ok := latestMetrics(); ok {
+					resp.Metrics = &m
+					resp.OK = true
+				} else {
+					resp.Error = "no metrics collected yet"
+				}
+			default:
+				resp.Error = "unknown method"
+			}
+		}
+		if err := enc.Encode(resp); err != nil {
+			return
+		}
+	}
+}
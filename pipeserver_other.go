@@ -0,0 +1,40 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"net"
+	"os"
+)
+
+// startPipeServer serves the pipe protocol on a Unix socket at name, e.g.
+// /run/user/1000/go-win-monitor.sock. The socket is made owner-only, so as
+// on Windows only the user running the agent may connect.
+func startPipeServer(name string) {
+	os.Remove(name)
+	l, err := net.Listen("unix", name)
+	if err != nil {
+		slog.Warn("Pipe server disabled", "err", err)
+		return
+	}
+	if err := os.Chmod(name, 0o600); err != nil {
+		l.Close()
+		slog.Warn("Pipe server disabled", "err", err)
+		return
+	}
+	go func() {
+		slog.Info("Serving metrics on a socket", "socket", name)
+		for {
+			c, err := l.Accept()
+			if err != nil {
+				slog.Error("Pipe server failed", "err", err)
+				return
+			}
+			go func() {
+				defer c.Close()
+				servePipeRequests(c)
+			}()
+		}
+	}()
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+const pipeBufferSize = 64 * 1024
+
+// startPipeServer serves the latest metrics on a named pipe for local
+// consumers (Rainmeter skins, stream overlays). The protocol is one JSON
+// object per line each way: {"method": "metrics"} or {"method": "ping"}.
+// Only the user running the agent may connect.
+func startPipeServer(name string) {
+	sd, err := pipeSecurity()
+	if err != nil {
+		slog.Warn("Pipe server disabled", "err", err)
+		return
+	}
+	sa := &windows.SecurityAttributes{SecurityDescriptor: sd}
+	sa.Length = uint32(unsafe.Sizeof(*sa))
+
+	path, _ := windows.UTF16PtrFromString(name)
+	go func() {
+		slog.Info("Serving metrics on a pipe", "pipe", name)
+		for {
+			h, err := windows.CreateNamedPipe(path,
+				windows.PIPE_ACCESS_DUPLEX,
+				windows.PIPE_TYPE_BYTE|windows.PIPE_READMODE_BYTE|windows.PIPE_WAIT|windows.PIPE_REJECT_REMOTE_CLIENTS,
+				windows.PIPE_UNLIMITED_INSTANCES, pipeBufferSize, pipeBufferSize, 0, sa)
+			if err != nil {
+				slog.Error("Pipe server failed", "err", err)
+				return
+			}
+			if err := windows.ConnectNamedPipe(h, nil); err != nil && err != windows.ERROR_PIPE_CONNECTED {
+				windows.CloseHandle(h)
+				continue
+			}
+			go servePipe(h)
+		}
+	}()
+}
+
+func pipeSecurity() (*windows.SECURITY_DESCRIPTOR, error) {
+	user, err := windows.GetCurrentProcessToken().GetTokenUser()
+	if err != nil {
+		return nil, err
+	}
+	return windows.SecurityDescriptorFromString("D:P(A;;GA;;;" + user.User.Sid.String() + ")(A;;GA;;;SY)")
+}
+
+func servePipe(h windows.Handle) {
+	f := os.NewFile(uintptr(h), "pipe")
+	defer func() {
+		windows.FlushFileBuffers(h)
+		windows.DisconnectNamedPipe(h)
+		f.Close()
+	}()
+	servePipeRequests(f)
+}
@@ -0,0 +1,131 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os/exec"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// M_PLUGINS lists exec plugins as name=command pairs, written as a
+// [plugins] table in the config file, e.g. ups = 'C:\tools\ups.exe --json'.
+// Each becomes the collector plugin-<name>.
+var (
+	pluginCommands = parseTags(getEnv("M_PLUGINS", ""))
+	pluginTimeout  = getInterval("M_PLUGIN_TIMEOUT", 10*time.Second)
+)
+
+const (
+	pluginMaxOutput = 64 * 1024
+	pluginMaxKeys   = 100
+)
+
+// pluginCollector runs a user command on every collect and reports the
+// JSON object it prints under plugins.<name>. Only strings, numbers and
+// booleans are kept; numbers and booleans also become plugin_value samples.
+type pluginCollector struct {
+	name    string
+	command string
+}
+
+func pluginCollectors() []Collector {
+	names := make([]string, 0, len(pluginCommands))
+	for name := range pluginCommands {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+	var cs []Collector
+	for _, name := range names {
+		cs = append(cs, pluginCollector{name: name, command: pluginCommands[name]})
+	}
+	return cs
+}
+
+func (p pluginCollector) Name() string { return "plugin-" + p.name }
+
+func (p pluginCollector) Timeout() time.Duration { return pluginTimeout }
+
+// Collect runs the command until collectMetrics cancels ctx, at the
+// plugin's timeout.
+func (p pluginCollector) Collect(ctx context.Context) (fields, error) {
+	args := splitCommandLine(p.command)
+	if len(args) == 0 {
+		return nil, fmt.Errorf("plugin %s has no command", p.name)
+	}
+	if strings.EqualFold(filepath.Ext(args[0]), ".ps1") {
+		args = append([]string{"powershell.exe", "-NoProfile", "-NonInteractive", "-ExecutionPolicy", "Bypass", "-File"}, args...)
+	}
+	cmd := exec.CommandContext(ctx, args[0], args[1:]...)
+	hideWindow(cmd)
+	var out bytes.Buffer
+	cmd.Stdout = &limitedBuffer{buf: &out, max: pluginMaxOutput}
+	if err := cmd.Run(); err != nil {
+		return nil, fmt.Errorf("plugin %s: %w", p.name, err)
+	}
+
+	var raw map[string]any
+	if err := json.Unmarshal(out.Bytes(), &raw); err != nil {
+		return nil, fmt.Errorf("plugin %s printed no JSON object: %w", p.name, err)
+	}
+	values := make(map[string]any, len(raw))
+	for k, v := range raw {
+		switch v.(type) {
+		case string, float64, bool:
+			if len(values) < pluginMaxKeys {
+				values[k] = v
+			}
+		}
+	}
+	return func(m *Metrics) {
+		if m.Plugins == nil {
+			m.Plugins = map[string]map[string]any{}
+		}
+		m.Plugins[p.name] = values
+	}, nil
+}
+
+// splitCommandLine splits on spaces outside double quotes, so a path with
+// spaces can be quoted: "C:\Program Files\ups\ups.exe" --json.
+func splitCommandLine(s string) []string {
+	var args []string
+	var cur strings.Builder
+	quoted, started := false, false
+	for _, r := range s {
+		switch {
+		case r == '"':
+			quoted, started = !quoted, true
+		case r == ' ' && !quoted:
+			if started {
+				args = append(args, cur.String())
+				cur.Reset()
+				started = false
+			}
+		default:
+			cur.WriteRune(r)
+			started = true
+		}
+	}
+	if started {
+		args = append(args, cur.String())
+	}
+	return args
+}
+
+// limitedBuffer drops output past max instead of growing without bound
+// when a plugin misbehaves.
+type limitedBuffer struct {
+	buf *bytes.Buffer
+	max int
+}
+
+func (b *limitedBuffer) Write(p []byte) (int, error) {
+	if room := b.max - b.buf.Len(); room > 0 {
+		b.buf.Write(p[:min(len(p), room)])
+	}
+	return len(p), nil
+}
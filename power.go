@@ -0,0 +1,16 @@
+package main
+
+import "sync/atomic"
+
+// suspended is set between the suspend and resume notifications; nothing
+// is sent meanwhile, so no report is left half-written on a socket that
+// will be dead on wake-up.
+var suspended atomic.Bool
+
+// queueLocal hands a command to the collection loop without blocking.
+func queueLocal(typ string) {
+	select {
+	case commands <- Command{ID: "local", Type: typ}:
+	default:
+	}
+}
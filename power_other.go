@@ -0,0 +1,7 @@
+//go:build !windows
+
+package main
+
+// watchPower has no suspend notification to subscribe to outside Windows;
+// after a resume the connections recover through the usual backoff.
+func watchPower() {}
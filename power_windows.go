@@ -0,0 +1,53 @@
+package main
+
+import (
+	"log/slog"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	powrprof                                   = windows.NewLazySystemDLL("powrprof.dll")
+	procPowerRegisterSuspendResumeNotification = powrprof.NewProc("PowerRegisterSuspendResumeNotification")
+)
+
+const (
+	deviceNotifyCallback  = 2
+	pbtAPMSuspend         = 0x4
+	pbtAPMResumeAutomatic = 0x12
+)
+
+type deviceNotifySubscribeParameters struct {
+	callback uintptr
+	context  uintptr
+}
+
+// powerNotify is kept for the life of the process, as Windows holds on to it.
+var powerNotify deviceNotifySubscribeParameters
+
+// watchPower subscribes to suspend and resume. On suspend the connections
+// are closed; on resume they are dropped again in case the suspend came
+// too late, the backoff is cleared and a fresh sample is sent right away
+// instead of waiting out the next tick. It works without a window, so the
+// service gets it too.
+func watchPower() {
+	powerNotify.callback = windows.NewCallback(func(_, event, _ uintptr) uintptr {
+		switch event {
+		case pbtAPMSuspend:
+			suspended.Store(true)
+			slog.Info("System suspending, pausing transmission")
+			queueLocal("suspend")
+		case pbtAPMResumeAutomatic:
+			suspended.Store(false)
+			slog.Info("System resumed, reconnecting")
+			queueLocal("reconnect")
+		}
+		return 0
+	})
+	var handle uintptr
+	r, _, _ := procPowerRegisterSuspendResumeNotification.Call(deviceNotifyCallback, uintptr(unsafe.Pointer(&powerNotify)), uintptr(unsafe.Pointer(&handle)))
+	if r != 0 {
+		slog.Warn("Suspend and resume will not be followed", "err", windows.Errno(r))
+	}
+}
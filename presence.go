@@ -0,0 +1,5 @@
+package main
+
+import "sync/atomic"
+
+var reportForeground atomic.Bool
@@ -0,0 +1,12 @@
+//go:build !windows
+
+package main
+
+// The foreground app, idle time and lock state come from the Windows
+// desktop; elsewhere they are left out of the report.
+
+func getForegroundApp() (string, bool) { return "", false }
+
+func getIdleSeconds() (uint64, bool) { return 0, false }
+
+func isSessionLocked() bool { return false }
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"path/filepath"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type lastInputInfo struct {
+	cbSize uint32
+	dwTime uint32
+}
+
+const (
+	desktopSwitchDesktop = 0x0100
+	uoiName              = 2
+)
+
+var (
+	user32                       = windows.NewLazySystemDLL("user32.dll")
+	kernel32                     = windows.NewLazySystemDLL("kernel32.dll")
+	procGetLastInputInfo         = user32.NewProc("GetLastInputInfo")
+	procOpenInputDesktop         = user32.NewProc("OpenInputDesktop")
+	procCloseDesktop             = user32.NewProc("CloseDesktop")
+	procGetUserObjectInformation = user32.NewProc("GetUserObjectInformationW")
+	procGetTickCount             = kernel32.NewProc("GetTickCount")
+)
+
+func getForegroundApp() (string, bool) {
+	hwnd := windows.GetForegroundWindow()
+	if hwnd == 0 {
+		return "", false
+	}
+
+	var pid uint32
+	if _, err := windows.GetWindowThreadProcessId(hwnd, &pid); err != nil || pid == 0 {
+		return "", false
+	}
+
+	h, err := windows.OpenProcess(windows.PROCESS_QUERY_LIMITED_INFORMATION, false, pid)
+	if err != nil {
+		return "", false
+	}
+	defer windows.CloseHandle(h)
+
+	buf := make([]uint16, windows.MAX_LONG_PATH)
+	size := uint32(len(buf))
+	if err := windows.QueryFullProcessImageName(h, 0, &buf[0], &size); err != nil {
+		return "", false
+	}
+	return filepath.Base(windows.UTF16ToString(buf[:size])), true
+}
+
+func getIdleSeconds() (uint64, bool) {
+	info := lastInputInfo{cbSize: uint32(unsafe.Sizeof(lastInputInfo{}))}
+	if r, _, _ := procGetLastInputInfo.Call(uintptr(unsafe.Pointer(&info))); r == 0 {
+		return 0, false
+	}
+	now, _, _ := procGetTickCount.Call()
+	return uint64(uint32(now)-info.dwTime) / 1000, true
+}
+
+// isSessionLocked reports whether the interactive desktop is something other
+// than "Default", which is the case while the lock screen (Winlogon) is shown.
+func isSessionLocked() bool {
+	desk, _, _ := procOpenInputDesktop.Call(0, 0, desktopSwitchDesktop)
+	if desk == 0 {
+		return true
+	}
+	defer procCloseDesktop.Call(desk)
+
+	buf := make([]uint16, 256)
+	var needed uint32
+	r, _, _ := procGetUserObjectInformation.Call(desk, uoiName, uintptr(unsafe.Pointer(&buf[0])), uintptr(len(buf)*2), uintptr(unsafe.Pointer(&needed)))
+	if r == 0 {
+		return false
+	}
+	return windows.UTF16ToString(buf) != "Default"
+}
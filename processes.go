@@ -0,0 +1,75 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+type WatchedProcess struct {
+	Name    string  `json:"name"`
+	Running bool    `json:"running"`
+	Count   int     `json:"count"`
+	CPU     float64 `json:"cpu"`
+	RAMMB   uint64  `json:"ramMb"`
+}
+
+var procCache = map[int32]*process.Process{}
+
+func getWatchedProcesses(names []string) ([]WatchedProcess, error) {
+	procs, err := listProcesses()
+	if err != nil {
+		return nil, fmt.Errorf("list processes: %w", err)
+	}
+
+	seen := map[int32]bool{}
+	results := make([]WatchedProcess, len(names))
+	for i, name := range names {
+		w := WatchedProcess{Name: name}
+		for pid, exe := range procs {
+			if !strings.EqualFold(exe, name) {
+				continue
+			}
+			w.Running = true
+			w.Count++
+
+			p, ok := procCache[int32(pid)]
+			if !ok {
+				p, err = process.NewProcess(int32(pid))
+				if err != nil {
+					continue
+				}
+				procCache[int32(pid)] = p
+			}
+			seen[int32(pid)] = true
+
+			if cpu, err := p.Percent(0); err == nil {
+				w.CPU += cpu / float64(runtime.NumCPU())
+			}
+			if mi, err := p.MemoryInfo(); err == nil {
+				w.RAMMB += mi.RSS / 1024 / 1024
+			}
+		}
+		results[i] = w
+	}
+
+	for pid := range procCache {
+		if !seen[pid] {
+			delete(procCache, pid)
+		}
+	}
+
+	return results, nil
+}
+
+func missingProcesses(watched []WatchedProcess) []string {
+	var missing []string
+	for _, w := range watched {
+		if !w.Running {
+			missing = append(missing, w.Name)
+		}
+	}
+	return missing
+}
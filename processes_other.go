@@ -0,0 +1,27 @@
+//go:build !windows
+
+package main
+
+import (
+	"syscall"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+func listProcesses() (map[uint32]string, error) {
+	all, err := process.Processes()
+	if err != nil {
+		return nil, err
+	}
+	procs := map[uint32]string{}
+	for _, p := range all {
+		if name, err := p.Name(); err == nil {
+			procs[uint32(p.Pid)] = name
+		}
+	}
+	return procs, nil
+}
+
+func terminate(pid uint32) error {
+	return syscall.Kill(int(pid), syscall.SIGKILL)
+}
@@ -0,0 +1,52 @@
+package main
+
+import (
+	"sync"
+
+	"github.com/getlantern/systray"
+)
+
+var profileMenu struct {
+	sync.Mutex
+	root  *systray.MenuItem
+	items map[string]*systray.MenuItem
+}
+
+func addProfileMenu() {
+	if len(profileNames()) == 0 {
+		return
+	}
+	profileMenu.root = systray.AddMenuItem("", tr("Switch the server connection"))
+	profileMenu.items = map[string]*systray.MenuItem{}
+	for _, name := range profileNames() {
+		item := profileMenu.root.AddSubMenuItemCheckbox(name, "", false)
+		profileMenu.items[name] = item
+		go func() {
+			for range item.ClickedCh {
+				select {
+				case commands <- Command{ID: "local", Type: "switchProfile", Profile: name}:
+				default:
+				}
+			}
+		}()
+	}
+	updateProfileMenu()
+}
+
+func updateProfileMenu() {
+	if headless || profileMenu.root == nil {
+		return
+	}
+	profileMenu.Lock()
+	defer profileMenu.Unlock()
+
+	active := fileSetting("M_PROFILE")
+	profileMenu.root.SetTitle(tr("Profile: %s", active))
+	for name, item := range profileMenu.items {
+		if name == active {
+			item.Check()
+		} else {
+			item.Uncheck()
+		}
+	}
+}
@@ -0,0 +1,93 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"slices"
+	"strings"
+)
+
+func profileStatePath() string {
+	return filepath.Join(defaultDataDir(), "profile")
+}
+
+// activeProfile returns the profile chosen with M_PROFILE, else the last one
+// picked from the tray, else the config file's profile key.
+func activeProfile(file map[string]string, profs map[string]map[string]string) string {
+	if p := flagSettings["M_PROFILE"]; p != "" {
+		return p
+	}
+	if p := os.Getenv("M_PROFILE"); p != "" {
+		return p
+	}
+	if data, err := os.ReadFile(profileStatePath()); err == nil {
+		if p := strings.TrimSpace(string(data)); profs[p] != nil {
+			return p
+		}
+	}
+	return file["M_PROFILE"]
+}
+
+func overlayProfile(settings map[string]string, profs map[string]map[string]string) {
+	name := activeProfile(settings, profs)
+	if name == "" {
+		return
+	}
+	p, ok := profs[name]
+	if !ok {
+		slog.Warn("Unknown profile", "profile", name)
+		return
+	}
+	for k, v := range p {
+		settings[k] = v
+	}
+	settings["M_PROFILE"] = name
+}
+
+func profileNames() []string {
+	configMu.RLock()
+	defer configMu.RUnlock()
+	names := make([]string, 0, len(profiles))
+	for n := range profiles {
+		names = append(names, n)
+	}
+	slices.Sort(names)
+	return names
+}
+
+// switchProfile remembers name for the next start and reconnects with its
+// settings. It runs on the collection loop's goroutine.
+func switchProfile(name string) {
+	if !slices.Contains(profileNames(), name) {
+		slog.Warn("Unknown profile", "profile", name)
+		return
+	}
+	if err := os.WriteFile(profileStatePath(), []byte(name+"\n"), 0o600); err != nil {
+		slog.Error("Save profile failed", "err", err)
+	}
+	reloadConfig()
+	if err := applyConnection(); err != nil {
+		slog.Error("Profile unusable", "profile", name, "err", err)
+		setStatus("Error")
+		return
+	}
+	slog.Info("Switched profile", "profile", name, "to", apiEndpoint("report"))
+	updateProfileMenu()
+	hellos.Schedule()
+}
+
+// applyConnection re-reads the server and TLS settings and drops pooled
+// connections to the previous server.
+func applyConnection() error {
+	apiURL = getEnv("M_API_URL", "")
+	secret = loadSecret()
+	tlsCAFile = getEnv("M_TLS_CA_FILE", "")
+	tlsMinVersion = getEnv("M_TLS_MIN_VERSION", "")
+	tlsServerName = getEnv("M_TLS_SERVER_NAME", "")
+	tlsInsecure = getEnv("M_TLS_INSECURE", "") == "1"
+	tlsCertFile = getEnv("M_TLS_CERT_FILE", "")
+	tlsKeyFile = getEnv("M_TLS_KEY_FILE", "")
+	tlsCertSubject = getEnv("M_TLS_CERT_SUBJECT", "")
+	return initTLS()
+}
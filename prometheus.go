@@ -0,0 +1,91 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+var latest struct {
+	sync.RWMutex
+	metrics Metrics
+	ok      bool
+}
+
+func setLatestMetrics(m Metrics) {
+	latest.Lock()
+	latest.metrics = m
+	latest.ok = true
+	latest.Unlock()
+}
+
+func latestMetrics() (Metrics, bool) {
+	latest.RLock()
+	defer latest.RUnlock()
+	return latest.metrics, latest.ok
+}
+
+func startPrometheus(addr string) {
+	mux := http.NewServeMux()
+	mux.HandleFunc("/metrics", handlePrometheus)
+
+	go func() {
+		slog.Info("Prometheus metrics listening", "url", "http://"+addr+"/metrics")
+		if err := http.ListenAndServe(addr, mux); err != nil {
+			slog.Error("Prometheus listener failed", "err", err)
+		}
+	}()
+}
+
+func handlePrometheus(w http.ResponseWriter, r *http.Request) {
+	m, ok := latestMetrics()
+	if !ok {
+		http.Error(w, "no metrics collected yet", http.StatusServiceUnavailable)
+		return
+	}
+	w.Header().Set("Content-Type", "text/plain; version=0.0.4; charset=utf-8")
+	fmt.Fprint(w, formatPrometheus(m))
+}
+
+type promWriter struct {
+	b       strings.Builder
+	current string
+}
+
+// gauge writes one sample. Samples of the same family must be written
+// consecutively; HELP and TYPE are emitted for the first one.
+func (p *promWriter) gauge(name, help string, value float64, labels ...string) {
+	name = "winmon_" + name
+	if p.current != name {
+		fmt.Fprintf(&p.b, "# HELP %s %s\n# TYPE %s gauge\n", name, help, name)
+		p.current = name
+	}
+
+	p.b.WriteString(name)
+	if len(labels) > 0 {
+		p.b.WriteByte('{')
+		for i := 0; i+1 < len(labels); i += 2 {
+			if i > 0 {
+				p.b.WriteByte(',')
+			}
+			fmt.Fprintf(&p.b, "%s=\"%s\"", labels[i], escapeLabel(labels[i+1]))
+		}
+		p.b.WriteByte('}')
+	}
+	p.b.WriteByte(' ')
+	p.b.WriteString(strconv.FormatFloat(value, 'g', -1, 64))
+	p.b.WriteByte('\n')
+}
+
+func escapeLabel(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+func formatPrometheus(m Metrics) string {
+	var p promWriter
+	visitSamples(m, p.gauge)
+	return p.b.String()
+}
@@ -0,0 +1,31 @@
+package main
+
+import (
+	"fmt"
+	"net/http"
+	"net/url"
+)
+
+// initProxy picks how HTTP requests reach the server: M_PROXY when set
+// ("direct" disables proxying), otherwise the HTTP(S)_PROXY variables,
+// otherwise the system proxy settings where there are any (Windows).
+func initProxy() error {
+	switch proxySetting {
+	case "":
+		httpTransport.Proxy = systemProxy
+	case "direct":
+		httpTransport.Proxy = nil
+	default:
+		u, err := url.Parse(proxySetting)
+		if err != nil || u.Host == "" {
+			return fmt.Errorf("invalid M_PROXY %q", proxySetting)
+		}
+		switch u.Scheme {
+		case "http", "https", "socks5", "socks5h":
+		default:
+			return fmt.Errorf("unsupported proxy scheme %q", u.Scheme)
+		}
+		httpTransport.Proxy = http.ProxyURL(u)
+	}
+	return nil
+}
@@ -0,0 +1,14 @@
+//go:build !windows
+
+package main
+
+import (
+	"net/http"
+	"net/url"
+)
+
+// systemProxy has no desktop settings to fall back on; the HTTP(S)_PROXY
+// variables are the system proxy on Linux and macOS.
+func systemProxy(req *http.Request) (*url.URL, error) {
+	return http.ProxyFromEnvironment(req)
+}
@@ -0,0 +1,80 @@
+package main
+
+import (
+	"net"
+	"net/http"
+	"net/url"
+	"path"
+	"strings"
+
+	"golang.org/x/sys/windows/registry"
+)
+
+func systemProxy(req *http.Request) (*url.URL, error) {
+	if u, err := http.ProxyFromEnvironment(req); u != nil || err != nil {
+		return u, err
+	}
+
+	k, err := registry.OpenKey(registry.CURRENT_USER, `Software\Microsoft\Windows\CurrentVersion\Internet Settings`, registry.QUERY_VALUE)
+	if err != nil {
+		return nil, nil
+	}
+	defer k.Close()
+
+	if enabled, _, err := k.GetIntegerValue("ProxyEnable"); err != nil || enabled == 0 {
+		return nil, nil
+	}
+	server, _, err := k.GetStringValue("ProxyServer")
+	if err != nil || server == "" {
+		return nil, nil
+	}
+	override, _, _ := k.GetStringValue("ProxyOverride")
+	if bypassProxy(req.URL.Hostname(), override) {
+		return nil, nil
+	}
+
+	return parseWinINetProxy(server, req.URL.Scheme)
+}
+
+// parseWinINetProxy handles both "host:port" and the per-protocol form
+// "http=host:port;https=host:port;socks=host:port".
+func parseWinINetProxy(server, scheme string) (*url.URL, error) {
+	if !strings.Contains(server, "=") {
+		return url.Parse("http://" + server)
+	}
+
+	byScheme := map[string]string{}
+	for _, part := range strings.Split(server, ";") {
+		if k, v, ok := strings.Cut(strings.TrimSpace(part), "="); ok {
+			byScheme[strings.ToLower(k)] = v
+		}
+	}
+	if addr := byScheme[scheme]; addr != "" {
+		return url.Parse("http://" + addr)
+	}
+	if addr := byScheme["socks"]; addr != "" {
+		return url.Parse("socks5://" + addr)
+	}
+	return nil, nil
+}
+
+// bypassProxy applies the ProxyOverride list: "*" wildcards and "<local>"
+// for single-label host names.
+func bypassProxy(host, override string) bool {
+	host = strings.ToLower(host)
+	for _, pattern := range strings.Split(override, ";") {
+		pattern = strings.ToLower(strings.TrimSpace(pattern))
+		switch {
+		case pattern == "":
+		case pattern == "<local>":
+			if !strings.Contains(host, ".") && net.ParseIP(host) == nil {
+				return true
+			}
+		default:
+			if ok, _ := path.Match(pattern, host); ok {
+				return true
+			}
+		}
+	}
+	return false
+}
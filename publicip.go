@@ -0,0 +1,60 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+)
+
+const publicIPTTL = 10 * time.Minute
+
+var publicIP struct {
+	sync.Mutex
+	value     string
+	checkedAt time.Time
+}
+
+func getPublicIP(lookupURL string) string {
+	publicIP.Lock()
+	defer publicIP.Unlock()
+
+	if time.Since(publicIP.checkedAt) < publicIPTTL {
+		return publicIP.value
+	}
+	publicIP.checkedAt = time.Now()
+
+	ip, err := lookupPublicIP(lookupURL)
+	if err != nil {
+		return publicIP.value
+	}
+	publicIP.value = ip
+	return ip
+}
+
+func lookupPublicIP(lookupURL string) (string, error) {
+	client := http.Client{Timeout: 10 * time.Second}
+	resp, err := client.Get(lookupURL)
+	if err != nil {
+		return "", fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode != http.StatusOK {
+		return "", &statusError{Code: resp.StatusCode}
+	}
+
+	body, err := io.ReadAll(io.LimitReader(resp.Body, 256))
+	if err != nil {
+		return "", fmt.Errorf("read: %w", err)
+	}
+
+	ip := strings.TrimSpace(string(body))
+	if net.ParseIP(ip) == nil {
+		return "", fmt.Errorf("invalid address %q", ip)
+	}
+	return ip, nil
+}
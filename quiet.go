@@ -0,0 +1,52 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strings"
+	"time"
+)
+
+// quietWindow is a daily span in minutes after midnight. end < start wraps
+// past midnight, e.g. 23:00-07:00.
+type quietWindow struct {
+	start, end int
+}
+
+var quietHours = parseQuietHours(getEnv("M_QUIET_HOURS", ""))
+
+func parseQuietHours(s string) []quietWindow {
+	var windows []quietWindow
+	for _, span := range parseList(s) {
+		from, to, ok := strings.Cut(span, "-")
+		start, err1 := parseClock(from)
+		end, err2 := parseClock(to)
+		if !ok || err1 != nil || err2 != nil {
+			slog.Warn("Invalid M_QUIET_HOURS span, expected HH:MM-HH:MM", "span", span)
+			continue
+		}
+		windows = append(windows, quietWindow{start, end})
+	}
+	return windows
+}
+
+func parseClock(s string) (int, error) {
+	t, err := time.Parse("15:04", strings.TrimSpace(s))
+	if err != nil {
+		return 0, fmt.Errorf("parse %q: %w", s, err)
+	}
+	return t.Hour()*60 + t.Minute(), nil
+}
+
+func inQuietHours(t time.Time) bool {
+	now := t.Hour()*60 + t.Minute()
+	for _, w := range quietHours {
+		if w.start <= w.end && now >= w.start && now < w.end {
+			return true
+		}
+		if w.start > w.end && (now >= w.start || now < w.end) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,6 @@
+package main
+
+// isRebootPending is not known on macOS.
+func isRebootPending() bool {
+	return false
+}
@@ -0,0 +1,10 @@
+package main
+
+import "os"
+
+// isRebootPending checks the flag file Debian and Ubuntu package updates
+// leave behind when they need a reboot.
+func isRebootPending() bool {
+	_, err := os.Stat("/var/run/reboot-required")
+	return err == nil
+}
@@ -0,0 +1,29 @@
+package main
+
+import (
+	"golang.org/x/sys/windows/registry"
+)
+
+var rebootPendingKeys = []string{
+	`SOFTWARE\Microsoft\Windows\CurrentVersion\Component Based Servicing\RebootPending`,
+	`SOFTWARE\Microsoft\Windows\CurrentVersion\WindowsUpdate\Auto Update\RebootRequired`,
+}
+
+func isRebootPending() bool {
+	for _, path := range rebootPendingKeys {
+		k, err := registry.OpenKey(registry.LOCAL_MACHINE, path, registry.QUERY_VALUE)
+		if err == nil {
+			k.Close()
+			return true
+		}
+	}
+
+	k, err := registry.OpenKey(registry.LOCAL_MACHINE, `SYSTEM\CurrentControlSet\Control\Session Manager`, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+
+	ops, _, err := k.GetStringsValue("PendingFileRenameOperations")
+	return err == nil && len(ops) > 0
+}
@@ -0,0 +1,123 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/shirou/gopsutil/v3/process"
+)
+
+// controlProcess handles the kill and restart commands. Only processes on
+// the M_WATCH_PROCESSES list can be targeted, and only when
+// M_REMOTE_PROCESS_CONTROL=1. Every attempt is written to the audit log.
+func controlProcess(c Command) {
+	err := doControlProcess(c)
+
+	result := "ok"
+	if err != nil {
+		result = err.Error()
+	}
+	audit(fmt.Sprintf("command=%s id=%s process=%s result=%q", c.Type, c.ID, c.Process, result))
+
+	if err != nil {
+		slog.Warn("Process command failed", "type", c.Type, "process", c.Process, "err", err)
+		return
+	}
+	msg := tr("Killed %s on request from the server", c.Process)
+	if c.Type == "restart" {
+		msg = tr("Restarted %s on request from the server", c.Process)
+	}
+	showToast("go-win-monitor", msg)
+}
+
+func doControlProcess(c Command) error {
+	if !remoteProcessControl {
+		return fmt.Errorf("remote process control is disabled")
+	}
+	if !slices.ContainsFunc(watch, func(w string) bool { return strings.EqualFold(w, c.Process) }) {
+		return fmt.Errorf("%s is not on the watch list", c.Process)
+	}
+
+	procs, err := listProcesses()
+	if err != nil {
+		return fmt.Errorf("list processes: %w", err)
+	}
+	var pids []uint32
+	for pid, exe := range procs {
+		if strings.EqualFold(exe, c.Process) {
+			pids = append(pids, pid)
+		}
+	}
+	if len(pids) == 0 {
+		return fmt.Errorf("not running")
+	}
+
+	// Remember how the first instance was started before killing it.
+	var restart *exec.Cmd
+	if c.Type == "restart" {
+		restart, err = relaunchCommand(pids[0])
+		if err != nil {
+			return err
+		}
+	}
+
+	for _, pid := range pids {
+		if err := terminate(pid); err != nil {
+			return fmt.Errorf("terminate %d: %w", pid, err)
+		}
+	}
+
+	if restart != nil {
+		time.Sleep(time.Second)
+		if err := restart.Start(); err != nil {
+			return fmt.Errorf("start: %w", err)
+		}
+		restart.Process.Release()
+	}
+	return nil
+}
+
+func relaunchCommand(pid uint32) (*exec.Cmd, error) {
+	p, err := process.NewProcess(int32(pid))
+	if err != nil {
+		return nil, fmt.Errorf("open %d: %w", pid, err)
+	}
+	exe, err := p.Exe()
+	if err != nil {
+		return nil, fmt.Errorf("executable of %d: %w", pid, err)
+	}
+	var args []string
+	if cmdline, err := p.CmdlineSlice(); err == nil && len(cmdline) > 1 {
+		args = cmdline[1:]
+	}
+
+	cmd := exec.Command(exe, args...)
+	if cwd, err := p.Cwd(); err == nil {
+		cmd.Dir = cwd
+	} else {
+		cmd.Dir = filepath.Dir(exe)
+	}
+	return cmd, nil
+}
+
+func audit(entry string) {
+	slog.Info("Audit", "entry", entry)
+
+	path := filepath.Join(defaultDataDir(), "audit.log")
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return
+	}
+	f, err := os.OpenFile(path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o600)
+	if err != nil {
+		slog.Error("Audit log write failed", "err", err)
+		return
+	}
+	defer f.Close()
+	fmt.Fprintf(f, "%s %s\n", time.Now().UTC().Format(time.RFC3339), entry)
+}
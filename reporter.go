@@ -0,0 +1,182 @@
+package main
+
+import (
+	"errors"
+	"log/slog"
+	"net/http"
+	"strings"
+	"time"
+)
+
+var errBackoff = errors.New("waiting to retry")
+
+type reporter struct {
+	name       string
+	send       func(Metrics) error
+	bufferSize int
+	buffer     []Metrics
+	spool      *spool
+	bo         backoff
+	retryAt    time.Time
+	connected  bool
+
+	// onReconnect, when set, runs once reports go through after an outage.
+	onReconnect func()
+
+	// failure is the event logged when the current outage began, 0 while
+	// reports go through.
+	failure int
+}
+
+// newReporter builds the reporter for one output. The primary transport keeps
+// spoolPath; other outputs spool next to it with their name as a suffix.
+func newReporter(name string, send func(Metrics) error) *reporter {
+	r := &reporter{
+		name:       name,
+		send:       send,
+		bufferSize: bufferSize,
+		bo:         backoff{base: sendInterval, max: backoffMax},
+	}
+	if spoolMaxMB > 0 {
+		path := spoolPath
+		if name != transportName {
+			path = strings.TrimSuffix(spoolPath, ".jsonl") + "-" + name + ".jsonl"
+		}
+		s, err := newSpool(path, int64(spoolMaxMB)*1024*1024)
+		if err != nil {
+			slog.Warn("Spool disabled", "err", err)
+		} else {
+			r.spool = s
+		}
+	}
+	return r
+}
+
+// SetBackoff applies changed intervals to the delays between retries; a retry
+// already scheduled keeps its time.
+func (r *reporter) SetBackoff(base, limit time.Duration) {
+	r.bo.base, r.bo.max = base, limit
+}
+
+// Report sends m after any metrics held back during an outage. While backing
+// off, or when the send fails, m is held back instead: on disk when the spool
+// is enabled, otherwise in memory, dropping the oldest once the buffer is full.
+func (r *reporter) Report(m Metrics) error {
+	if time.Now().Before(r.retryAt) {
+		r.enqueue(m)
+		return errBackoff
+	}
+
+	err := r.flush()
+	if err == nil {
+		err = r.send(m)
+	}
+	if errors.Is(err, errRateLimited) {
+		// The server is fine, the agent just sent too much since the outage:
+		// hold m and carry on with the backlog next tick.
+		r.enqueue(m)
+		return err
+	}
+	if err != nil {
+		r.enqueue(m)
+		r.retryAt = time.Now().Add(r.bo.Next())
+		r.failed(err)
+		sendErrors.Add(1)
+		return err
+	}
+
+	switch {
+	case r.failure != 0:
+		r.failure = 0
+		reconnects.Add(1)
+		slog.Info("Reconnected", "output", r.name, "event", evtConnected)
+		if r.onReconnect != nil {
+			r.onReconnect()
+		}
+	case !r.connected:
+		slog.Info("Connected", "output", r.name, "event", evtConnected)
+	}
+	r.connected = true
+	r.bo.Reset()
+	return nil
+}
+
+// failed logs the start of an outage, and a switch to rejected credentials
+// during one, once rather than on every retry.
+func (r *reporter) failed(err error) {
+	var se *statusError
+	if errors.As(err, &se) && (se.Code == http.StatusUnauthorized || se.Code == http.StatusForbidden) {
+		if r.failure != evtAuthFailed {
+			r.failure = evtAuthFailed
+			slog.Error("Server rejected the agent's credentials", "output", r.name, "status", se.Code, "event", evtAuthFailed)
+		}
+		return
+	}
+	if r.failure == 0 {
+		r.failure = evtDisconnected
+		slog.Warn("Server unreachable", "output", r.name, "err", err, "event", evtDisconnected)
+	}
+}
+
+// AuthFailed reports whether the server's last answer rejected the
+// credentials.
+func (r *reporter) AuthFailed() bool {
+	return r.failure == evtAuthFailed
+}
+
+// RetryNow cancels a pending backoff so the next Report tries right away.
+func (r *reporter) RetryNow() {
+	r.retryAt = time.Time{}
+	r.bo.Reset()
+}
+
+func (r *reporter) RetryIn() time.Duration {
+	return time.Until(r.retryAt)
+}
+
+func (r *reporter) flush() error {
+	if r.spool != nil {
+		if err := r.spool.Drain(r.send); err != nil {
+			return err
+		}
+	}
+
+	for len(r.buffer) > 0 {
+		if err := r.send(r.buffer[0]); err != nil {
+			return err
+		}
+		r.buffer = r.buffer[1:]
+	}
+	return nil
+}
+
+// Close tries once more to send the samples held in memory, which are lost
+// when the agent exits. Spooled samples stay on disk for the next start.
+func (r *reporter) Close() {
+	if !time.Now().Before(r.retryAt) {
+		for len(r.buffer) > 0 && r.send(r.buffer[0]) == nil {
+			r.buffer = r.buffer[1:]
+		}
+	}
+	if n := len(r.buffer); n > 0 {
+		slog.Warn("Unsent samples dropped", "count", n)
+	}
+}
+
+func (r *reporter) enqueue(m Metrics) {
+	if r.spool != nil {
+		err := r.spool.Append(m)
+		if err == nil {
+			return
+		}
+		slog.Warn("Spool write failed", "err", err)
+	}
+
+	if r.bufferSize <= 0 {
+		return
+	}
+	if len(r.buffer) >= r.bufferSize {
+		r.buffer = r.buffer[1:]
+	}
+	r.buffer = append(r.buffer, m)
+}
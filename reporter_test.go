@@ -0,0 +1,144 @@
+package main
+
+import (
+	"errors"
+	"slices"
+	"testing"
+	"time"
+)
+
+// fakeSender records what a reporter sends and fails the first fail calls.
+type fakeSender struct {
+	fail  int
+	calls int
+	sent  []string
+}
+
+var errFakeDown = errors.New("server down")
+
+func (f *fakeSender) send(m Metrics) error {
+	f.calls++
+	if f.fail > 0 {
+		f.fail--
+		return errFakeDown
+	}
+	f.sent = append(f.sent, m.MachineID)
+	return nil
+}
+
+func newTestReporter(f *fakeSender, bufferSize int) *reporter {
+	return &reporter{name: "test", send: f.send, bufferSize: bufferSize, bo: backoff{base: time.Minute, max: 4 * time.Minute}}
+}
+
+func sample(id string) Metrics {
+	return Metrics{MachineID: id}
+}
+
+func TestReporterRetriesAfterBackoff(t *testing.T) {
+	f := &fakeSender{fail: 1}
+	r := newTestReporter(f, 10)
+
+	if err := r.Report(sample("1")); !errors.Is(err, errFakeDown) {
+		t.Fatalf("first report: got %v, want %v", err, errFakeDown)
+	}
+	if d := r.RetryIn(); d <= 0 || d > time.Minute {
+		t.Fatalf("retry in %s, want within the base interval", d)
+	}
+
+	if err := r.Report(sample("2")); !errors.Is(err, errBackoff) {
+		t.Fatalf("report while backing off: got %v, want %v", err, errBackoff)
+	}
+	if f.calls != 1 {
+		t.Fatalf("sender called %d times while backing off, want 1", f.calls)
+	}
+
+	r.RetryNow()
+	if err := r.Report(sample("3")); err != nil {
+		t.Fatalf("report after the outage: %v", err)
+	}
+	if got, want := f.sent, []string{"1", "2", "3"}; !slices.Equal(got, want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+	if len(r.buffer) != 0 || r.bo.attempt != 0 {
+		t.Fatalf("buffer %d, backoff attempt %d after reconnecting, want both 0", len(r.buffer), r.bo.attempt)
+	}
+}
+
+func TestReporterBacksOffLongerEachFailure(t *testing.T) {
+	f := &fakeSender{fail: 100}
+	r := newTestReporter(f, 10)
+
+	for i, ceiling := range []time.Duration{time.Minute, 2 * time.Minute, 4 * time.Minute, 4 * time.Minute} {
+		// Skip the wait without resetting the backoff, as RetryNow would.
+		r.retryAt = time.Time{}
+		r.Report(sample("x"))
+		if d := r.RetryIn(); d < ceiling/2-time.Second || d > ceiling {
+			t.Fatalf("failure %d: retry in %s, want between %s and %s", i+1, d, ceiling/2, ceiling)
+		}
+	}
+}
+
+func TestReporterDropsOldestWhenBufferFull(t *testing.T) {
+	f := &fakeSender{fail: 3}
+	r := newTestReporter(f, 2)
+
+	for _, id := range []string{"1", "2", "3"} {
+		r.RetryNow()
+		r.Report(sample(id))
+	}
+	r.RetryNow()
+	if err := r.Report(sample("4")); err != nil {
+		t.Fatalf("report after the outage: %v", err)
+	}
+	if got, want := f.sent, []string{"2", "3", "4"}; !slices.Equal(got, want) {
+		t.Fatalf("sent %v, want %v", got, want)
+	}
+}
+
+func TestBackoffNext(t *testing.T) {
+	b := backoff{base: time.Second, max: 8 * time.Second}
+	for _, ceiling := range []time.Duration{time.Second, 2 * time.Second, 4 * time.Second, 8 * time.Second, 8 * time.Second} {
+		if d := b.Next(); d < ceiling/2 || d > ceiling {
+			t.Fatalf("attempt %d: got %s, want between %s and %s", b.attempt, d, ceiling/2, ceiling)
+		}
+	}
+	b.Reset()
+	if d := b.Next(); d > time.Second {
+		t.Fatalf("after reset: got %s, want at most %s", d, time.Second)
+	}
+}
+
+func TestReporterWaitsOutTheRateLimit(t *testing.T) {
+	limited := 2
+	var sent []string
+	r := &reporter{name: "test", bufferSize: 10, bo: backoff{base: time.Minute, max: 4 * time.Minute}}
+	r.send = func(m Metrics) error {
+		if limited > 0 {
+			limited--
+			return errRateLimited
+		}
+		sent = append(sent, m.MachineID)
+		return nil
+	}
+	r.buffer = []Metrics{sample("1")}
+	errorsBefore := sendErrors.Load()
+
+	for _, id := range []string{"2", "3"} {
+		if err := r.Report(sample(id)); !errors.Is(err, errRateLimited) {
+			t.Fatalf("report %s: got %v, want %v", id, err, errRateLimited)
+		}
+		if r.RetryIn() > 0 || r.bo.attempt != 0 || r.failure != 0 {
+			t.Fatalf("report %s: backing off (retry in %s, attempt %d, failure %d) on a rate limit", id, r.RetryIn(), r.bo.attempt, r.failure)
+		}
+	}
+	if n := sendErrors.Load() - errorsBefore; n != 0 {
+		t.Fatalf("%d send errors counted for rate-limited reports", n)
+	}
+
+	if err := r.Report(sample("4")); err != nil {
+		t.Fatalf("report once the limit refilled: %v", err)
+	}
+	if want := []string{"1", "2", "3", "4"}; !slices.Equal(sent, want) {
+		t.Fatalf("sent %v, want %v", sent, want)
+	}
+}
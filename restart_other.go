@@ -0,0 +1,25 @@
+//go:build !windows
+
+package main
+
+import (
+	"log/slog"
+	"os"
+	"runtime"
+	"syscall"
+)
+
+// updateAsset is the release asset holding this platform's binary, e.g.
+// monitor-linux-amd64.
+const updateAsset = "monitor-" + runtime.GOOS + "-" + runtime.GOARCH
+
+// restart replaces the process with the new executable, keeping its PID so
+// systemd or launchd keep tracking it. The instance lock is not inherited
+// and is taken again by the new image.
+func restart(exe string) {
+	shutdown("update")
+	if err := syscall.Exec(exe, os.Args, os.Environ()); err != nil {
+		slog.Error("Restart failed", "err", err)
+	}
+	onExit()
+}
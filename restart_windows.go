@@ -0,0 +1,28 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"os/exec"
+
+	"golang.org/x/sys/windows"
+)
+
+// updateAsset is the release asset holding this platform's binary.
+const updateAsset = "monitor.exe"
+
+// restart starts the new executable with the same arguments and exits. A
+// service exits with an error instead, so the service manager's recovery
+// action starts the new binary.
+func restart(exe string) {
+	shutdown("update")
+	if runningAsService {
+		os.Exit(1)
+	}
+	windows.CloseHandle(instanceMutex)
+	cmd := exec.Command(exe, os.Args[1:]...)
+	if err := cmd.Start(); err != nil {
+		slog.Error("Restart failed", "err", err)
+	}
+	onExit()
+}
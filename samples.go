@@ -0,0 +1,266 @@
+package main
+
+import (
+	"maps"
+	"math"
+	"slices"
+	"strconv"
+)
+
+type sampleFunc func(name, help string, value float64, labels ...string)
+
+// optional skips the -1 sentinel used for unavailable readings.
+func (g sampleFunc) optional(name, help string, value float64, labels ...string) {
+	if value >= 0 {
+		g(name, help, value, labels...)
+	}
+}
+
+func boolFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+	return 0
+}
+
+// sampleCollectors names the collector behind each family that reads 0
+// rather than -1 or nothing when its collector failed.
+var sampleCollectors = map[string]string{
+	"cpu_usage_percent":                 "cpu",
+	"cpu_frequency_mhz":                 "cpu",
+	"cpu_base_frequency_mhz":            "cpu",
+	"cpu_throttling":                    "cpu",
+	"memory_used_percent":               "memory",
+	"memory_used_bytes":                 "memory",
+	"memory_total_bytes":                "memory",
+	"memory_available_bytes":            "memory",
+	"memory_cached_bytes":               "system",
+	"memory_paged_pool_bytes":           "system",
+	"memory_nonpaged_pool_bytes":        "system",
+	"memory_commit_bytes":               "pagefile",
+	"memory_commit_limit_bytes":         "pagefile",
+	"pagefile_used_bytes":               "pagefile",
+	"pagefile_total_bytes":              "pagefile",
+	"uptime_seconds":                    "system",
+	"pending_reboot":                    "system",
+	"processes":                         "system",
+	"threads":                           "system",
+	"handles":                           "system",
+	"idle_seconds":                      "session",
+	"session_locked":                    "session",
+	"network_sent_bytes_per_second":     "network",
+	"network_received_bytes_per_second": "network",
+}
+
+// skipFailed leaves out the families of the collectors listed in errs, so
+// alerts, aggregates and history do not take their zeros for readings.
+func (g sampleFunc) skipFailed(errs map[string]string) sampleFunc {
+	return func(name, help string, value float64, labels ...string) {
+		if c, ok := sampleCollectors[name]; ok {
+			if _, failed := errs[c]; failed {
+				return
+			}
+		}
+		g(name, help, value, labels...)
+	}
+}
+
+const mb = 1024 * 1024
+
+// visitSamples flattens m into gauge samples, leaving out those of the
+// collectors that failed. Samples of the same family are visited
+// consecutively.
+func visitSamples(m Metrics, g sampleFunc) {
+	if len(m.Errors) > 0 {
+		g = g.skipFailed(m.Errors)
+	}
+	g("cpu_usage_percent", "CPU utilization.", m.CPU)
+	if m.CPUFreqMHz > 0 {
+		g("cpu_frequency_mhz", "Current CPU clock.", float64(m.CPUFreqMHz))
+		g("cpu_base_frequency_mhz", "Base CPU clock.", float64(m.CPUBaseFreqMHz))
+		g("cpu_throttling", "Whether the CPU clock dropped under load.", boolFloat(m.CPUThrottled))
+	}
+	g.optional("cpu_temperature_celsius", "Hottest ACPI thermal zone.", m.CPUTempC)
+
+	g("memory_used_percent", "Physical memory in use.", m.RAM)
+	g("memory_used_bytes", "Physical memory in use.", float64(m.RAMUsedMB*mb))
+	g("memory_total_bytes", "Installed physical memory.", float64(m.RAMTotalMB*mb))
+	g("memory_available_bytes", "Available physical memory.", float64(m.RAMAvailableMB*mb))
+	g("memory_cached_bytes", "System file cache.", float64(m.RAMCachedMB*mb))
+	g("memory_paged_pool_bytes", "Kernel paged pool.", float64(m.PagedPoolMB*mb))
+	g("memory_nonpaged_pool_bytes", "Kernel non-paged pool.", float64(m.NonPagedPoolMB*mb))
+	g("memory_commit_bytes", "Committed memory.", float64(m.CommitUsedMB*mb))
+	g("memory_commit_limit_bytes", "Commit limit.", float64(m.CommitLimitMB*mb))
+	g("pagefile_used_bytes", "Pagefile in use.", float64(m.PagefileUsedMB*mb))
+	g("pagefile_total_bytes", "Pagefile size.", float64(m.PagefileTotalMB*mb))
+
+	g.optional("gpu_usage_percent", "GPU utilization.", m.GPU)
+	g.optional("gpu_encoder_percent", "GPU video encoder utilization.", m.GPUEncoder)
+	g.optional("gpu_decoder_percent", "GPU video decoder utilization.", m.GPUDecoder)
+	g.optional("gpu_core_clock_mhz", "GPU core clock.", m.GPUCoreMHz)
+	g.optional("gpu_memory_clock_mhz", "GPU memory clock.", m.GPUMemMHz)
+	g.optional("gpu_temperature_celsius", "GPU temperature.", m.GPUTempC)
+	if m.GPU >= 0 {
+		g("gpu_throttling", "Whether the GPU clock dropped under load.", boolFloat(m.GPUThrottled))
+	}
+	for _, p := range m.GPUProcesses {
+		g.optional("gpu_process_memory_bytes", "GPU memory held by a process.", p.VRAMMB*mb, "process", p.Name, "pid", strconv.Itoa(int(p.PID)))
+	}
+
+	if a := m.Agent; a != nil {
+		g("agent_cpu_percent", "CPU used by the agent, as a share of all cores.", a.CPU)
+		g("agent_memory_bytes", "Working set of the agent.", float64(a.RSSBytes))
+		g("agent_goroutines", "Goroutines in the agent.", float64(a.Goroutines))
+		g("agent_reconnects", "Reports sent after a failed one since the agent started.", float64(a.Reconnects))
+		g("agent_send_errors", "Failed reports since the agent started.", float64(a.SendErrors))
+		g("agent_low_resource", "Whether the agent is in low-resource mode.", boolFloat(a.LowResource))
+	}
+
+	g("uptime_seconds", "Time since boot.", float64(m.UptimeSec))
+	g("pending_reboot", "Whether a reboot is pending.", boolFloat(m.PendingReboot))
+	g("processes", "Running processes.", float64(m.Processes))
+	g("threads", "Running threads.", float64(m.Threads))
+	g("handles", "Open handles.", float64(m.Handles))
+	g("idle_seconds", "Time since last user input.", float64(m.IdleSec))
+	g("session_locked", "Whether the console session is locked.", boolFloat(m.SessionLocked))
+	for _, u := range m.Users {
+		g("user_session", "A user session open on the machine.", 1, "user", u.User, "state", u.State, "station", u.Station)
+	}
+
+	if m.WiFiSSID != "" {
+		g("wifi_signal_percent", "Wi-Fi signal quality.", float64(m.WiFiSignal), "ssid", m.WiFiSSID)
+	}
+
+	if m.TCP != nil {
+		g("tcp_connections", "TCP connections by state.", float64(m.TCP.Established), "state", "established")
+		g("tcp_connections", "TCP connections by state.", float64(m.TCP.TimeWait), "state", "time_wait")
+		g("tcp_connections", "TCP connections by state.", float64(m.TCP.Listen), "state", "listen")
+	}
+
+	g.optional("report_latency_milliseconds", "Round trip of the previous report.", m.LatencyMs)
+	if m.ClockOffsetMs != nil {
+		g("clock_offset_milliseconds", "How far the clock is ahead of the NTP server.", *m.ClockOffsetMs)
+		g("clock_drift_milliseconds", "How far the clock is off the NTP server, either way.", math.Abs(*m.ClockOffsetMs))
+	}
+
+	g("network_sent_bytes_per_second", "Upload over all interfaces.", m.NetSentBps)
+	g("network_received_bytes_per_second", "Download over all interfaces.", m.NetRecvBps)
+
+	for _, i := range m.Interfaces {
+		g("interface_errors", "Interface errors since the previous sample.", float64(i.ErrIn), "interface", i.Name, "direction", "in")
+		g("interface_errors", "Interface errors since the previous sample.", float64(i.ErrOut), "interface", i.Name, "direction", "out")
+	}
+	for _, i := range m.Interfaces {
+		g("interface_drops", "Interface drops since the previous sample.", float64(i.DropIn), "interface", i.Name, "direction", "in")
+		g("interface_drops", "Interface drops since the previous sample.", float64(i.DropOut), "interface", i.Name, "direction", "out")
+	}
+
+	if m.DataUsage != nil {
+		for _, i := range m.DataUsage.Interfaces {
+			g("data_usage_bytes", "Traffic since the billing period started.", float64(i.SentBytes), "interface", i.Name, "direction", "sent")
+			g("data_usage_bytes", "Traffic since the billing period started.", float64(i.RecvBytes), "interface", i.Name, "direction", "received")
+		}
+		for _, i := range m.DataUsage.Interfaces {
+			if i.CapBytes > 0 {
+				g("data_usage_cap_percent", "Share of the interface's data cap used this period.", i.CapPercent, "interface", i.Name)
+			}
+		}
+	}
+
+	for _, n := range m.TopNetwork {
+		g("process_network_sent_bytes_per_second", "Top network consumers, upload.", n.SentBps, "process", n.Name, "pid", strconv.Itoa(int(n.PID)))
+	}
+	for _, n := range m.TopNetwork {
+		g("process_network_received_bytes_per_second", "Top network consumers, download.", n.RecvBps, "process", n.Name, "pid", strconv.Itoa(int(n.PID)))
+	}
+
+	for _, r := range m.Pings {
+		g.optional("ping_rtt_milliseconds", "Average ICMP round-trip time.", r.RTTMs, "host", r.Host)
+	}
+	for _, r := range m.Pings {
+		g("ping_loss_percent", "ICMP packet loss.", r.Loss, "host", r.Host)
+	}
+
+	for _, f := range m.Fans {
+		g.optional("fan_rpm", "Fan speed.", f.RPM, "fan", f.Name)
+	}
+	for _, f := range m.Fans {
+		g.optional("fan_percent", "Fan speed.", f.Percent, "fan", f.Name)
+	}
+	for _, s := range m.Sensors {
+		g("hardware_sensor_value", "A LibreHardwareMonitor sensor.", s.Value, "hardware", s.Hardware, "sensor", s.Name, "type", s.Type)
+	}
+	for _, t := range m.DiskTemps {
+		g("disk_temperature_celsius", "Drive temperature.", float64(t.TempC), "disk", t.Name)
+	}
+	for _, v := range m.Volumes {
+		g("volume_size_bytes", "Volume size.", v.TotalGB*gb, "volume", v.Name)
+	}
+	for _, v := range m.Volumes {
+		g("volume_free_bytes", "Volume free space.", v.FreeGB*gb, "volume", v.Name)
+	}
+	for _, v := range m.Volumes {
+		g("volume_free_percent", "Volume free space.", 100-v.UsedPercent, "volume", v.Name)
+	}
+
+	for _, w := range m.Watched {
+		g("watched_process_running", "Running instances of a watched process.", float64(w.Count), "process", w.Name)
+	}
+	for _, w := range m.Watched {
+		g("watched_process_cpu_percent", "CPU used by a watched process.", w.CPU, "process", w.Name)
+	}
+	for _, w := range m.Watched {
+		g("watched_process_memory_bytes", "Memory used by a watched process.", float64(w.RAMMB*mb), "process", w.Name)
+	}
+	for _, s := range m.Services {
+		g("service_running", "Whether a watched service is running.", boolFloat(s.State == "running"), "service", s.Name, "state", s.State)
+	}
+
+	for _, c := range m.Containers {
+		g("container_cpu_percent", "Container CPU usage.", c.CPU, "container", c.Name)
+	}
+	for _, c := range m.Containers {
+		g("container_memory_bytes", "Container memory usage.", float64(c.MemMB*mb), "container", c.Name)
+	}
+	for _, v := range m.VMs {
+		g("vm_cpu_percent", "Hyper-V guest CPU load.", float64(v.CPU), "vm", v.Name)
+	}
+	for _, v := range m.VMs {
+		g("vm_memory_bytes", "Hyper-V guest memory.", float64(v.MemMB*mb), "vm", v.Name)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.Counters)) {
+		g("perf_counter_value", "A Windows performance counter from M_COUNTERS.", m.Counters[name], "counter", name)
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.WMI)) {
+		rows := m.WMI[name]
+		for _, instance := range slices.Sorted(maps.Keys(rows)) {
+			row := rows[instance]
+			for _, f := range slices.Sorted(maps.Keys(row)) {
+				switch v := row[f].(type) {
+				case float64:
+					g("wmi_value", "A field of a configured WMI query.", v, "query", name, "instance", instance, "field", f)
+				case bool:
+					g("wmi_value", "A field of a configured WMI query.", boolFloat(v), "query", name, "instance", instance, "field", f)
+				}
+			}
+		}
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.Scripts)) {
+		g("script_"+name, "Computed by a script.", m.Scripts[name])
+	}
+
+	for _, name := range slices.Sorted(maps.Keys(m.Plugins)) {
+		values := m.Plugins[name]
+		for _, k := range slices.Sorted(maps.Keys(values)) {
+			switch v := values[k].(type) {
+			case float64:
+				g("plugin_value", "A number reported by an exec plugin.", v, "plugin", name, "key", k)
+			case bool:
+				g("plugin_value", "A number reported by an exec plugin.", boolFloat(v), "plugin", name, "key", k)
+			}
+		}
+	}
+}
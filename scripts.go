@@ -0,0 +1,228 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"time"
+
+	starlarkmath "go.starlark.net/lib/math"
+	"go.starlark.net/starlark"
+	"go.starlark.net/syntax"
+)
+
+// Scripts are the Starlark files (*.star) in M_SCRIPT_DIR, by default the
+// scripts folder of the data directory. They run after every collect in a
+// sandbox with no file, network or clock access and a step limit. A script
+// may define either function or both:
+//
+//	def metrics(m):
+//	    return {"pressure_score": (m["cpu"] + m["ram"] + m["pagefile"]) / 3}
+//
+//	def alerts(m):
+//	    return {"memory_pressure": m["ram"] > 90 and m["pagefile"] > 50}
+//
+// m is the sample as sent, a dict keyed like the JSON payload. metrics
+// returns numbers, sent under scripts and as script_<name> samples that
+// alert rules can use. alerts returns conditions: a true value, or a
+// message string, fires the alert of that name and a false one resolves it.
+func scriptDir() string {
+	return getEnv("M_SCRIPT_DIR", filepath.Join(defaultDataDir(), "scripts"))
+}
+
+const (
+	scriptMaxSteps = 1_000_000
+	scriptTimeout  = time.Second
+)
+
+var scriptMetricName = regexp.MustCompile(`^[a-z_][a-z0-9_]*$`)
+
+type script struct {
+	name    string
+	metrics *starlark.Function
+	alerts  *starlark.Function
+	failing bool
+}
+
+// scriptCondition is one entry of what an alerts function returned.
+type scriptCondition struct {
+	script  string
+	name    string
+	firing  bool
+	message string
+}
+
+// scripts are loaded by startServices and again on every config reload.
+// They are only used from the collection loop.
+var scripts []*script
+
+func loadScripts(dir string) []*script {
+	paths, _ := filepath.Glob(filepath.Join(dir, "*.star"))
+	sort.Strings(paths)
+	var loaded []*script
+	for _, path := range paths {
+		name := strings.TrimSuffix(filepath.Base(path), ".star")
+		thread := newScriptThread(name)
+		globals, err := starlark.ExecFileOptions(&syntax.FileOptions{}, thread, path, nil, starlark.StringDict{"math": starlarkmath.Module})
+		if err != nil {
+			slog.Warn("Script not loaded", "script", path, "err", err)
+			continue
+		}
+		s := &script{name: name}
+		s.metrics, _ = globals["metrics"].(*starlark.Function)
+		s.alerts, _ = globals["alerts"].(*starlark.Function)
+		if s.metrics == nil && s.alerts == nil {
+			slog.Warn("Script defines neither metrics nor alerts", "script", path)
+			continue
+		}
+		loaded = append(loaded, s)
+	}
+	if len(loaded) > 0 {
+		slog.Info("Scripts loaded", "dir", dir, "count", len(loaded))
+	}
+	return loaded
+}
+
+func newScriptThread(name string) *starlark.Thread {
+	thread := &starlark.Thread{Name: name, Print: func(t *starlark.Thread, msg string) {
+		slog.Info("Script", "script", t.Name, "message", msg)
+	}}
+	thread.SetMaxExecutionSteps(scriptMaxSteps)
+	return thread
+}
+
+// runScripts adds the scripts' metrics to m and returns their alert
+// conditions. A script that fails is logged once, until it works again.
+func runScripts(m *Metrics) []scriptCondition {
+	if len(scripts) == 0 {
+		return nil
+	}
+	sample, err := sampleValue(*m)
+	if err != nil {
+		slog.Warn("Scripts skipped", "err", err)
+		return nil
+	}
+	var conds []scriptCondition
+	for _, s := range scripts {
+		err := s.run(m, sample, &conds)
+		switch {
+		case err != nil && !s.failing:
+			s.failing = true
+			slog.Warn("Script failed", "script", s.name, "err", err)
+		case err == nil && s.failing:
+			s.failing = false
+			slog.Info("Script works again", "script", s.name)
+		}
+	}
+	return conds
+}
+
+func (s *script) run(m *Metrics, sample starlark.Value, conds *[]scriptCondition) error {
+	if s.metrics != nil {
+		items, err := s.call(s.metrics, sample)
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			name, ok := starlark.AsString(it[0])
+			if !ok || !scriptMetricName.MatchString(name) {
+				return fmt.Errorf("metrics: %s is not a metric name such as pressure_score", it[0])
+			}
+			v, ok := starlark.AsFloat(it[1])
+			if !ok {
+				if b, isBool := it[1].(starlark.Bool); isBool {
+					v, ok = boolFloat(bool(b)), true
+				}
+			}
+			if !ok {
+				return fmt.Errorf("metrics: %s is %s, not a number", name, it[1].Type())
+			}
+			if m.Scripts == nil {
+				m.Scripts = map[string]float64{}
+			}
+			m.Scripts[name] = v
+		}
+	}
+	if s.alerts != nil {
+		items, err := s.call(s.alerts, sample)
+		if err != nil {
+			return err
+		}
+		for _, it := range items {
+			name, ok := starlark.AsString(it[0])
+			if !ok || name == "" {
+				return fmt.Errorf("alerts: %s is not an alert name", it[0])
+			}
+			c := scriptCondition{script: s.name, name: name, firing: bool(it[1].Truth())}
+			c.message, _ = starlark.AsString(it[1])
+			*conds = append(*conds, c)
+		}
+	}
+	return nil
+}
+
+// call runs f(sample), which must return a dict, on a fresh thread so the
+// step limit and the timeout apply to each call.
+func (s *script) call(f *starlark.Function, sample starlark.Value) ([]starlark.Tuple, error) {
+	thread := newScriptThread(s.name)
+	timer := time.AfterFunc(scriptTimeout, func() { thread.Cancel("took longer than " + scriptTimeout.String()) })
+	defer timer.Stop()
+	v, err := starlark.Call(thread, f, starlark.Tuple{sample}, nil)
+	if err != nil {
+		return nil, err
+	}
+	d, ok := v.(*starlark.Dict)
+	if !ok {
+		return nil, fmt.Errorf("%s returned %s, not a dict", f.Name(), v.Type())
+	}
+	return d.Items(), nil
+}
+
+// sampleValue gives scripts the sample as its JSON payload, frozen so one
+// script cannot change what the next one sees.
+func sampleValue(m Metrics) (starlark.Value, error) {
+	data, err := json.Marshal(m)
+	if err != nil {
+		return nil, err
+	}
+	var raw any
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return nil, err
+	}
+	v := toStarlark(raw)
+	v.Freeze()
+	return v, nil
+}
+
+func toStarlark(v any) starlark.Value {
+	switch v := v.(type) {
+	case map[string]any:
+		keys := make([]string, 0, len(v))
+		for k := range v {
+			keys = append(keys, k)
+		}
+		sort.Strings(keys)
+		d := starlark.NewDict(len(v))
+		for _, k := range keys {
+			d.SetKey(starlark.String(k), toStarlark(v[k]))
+		}
+		return d
+	case []any:
+		items := make([]starlark.Value, len(v))
+		for i, item := range v {
+			items[i] = toStarlark(item)
+		}
+		return starlark.NewList(items)
+	case string:
+		return starlark.String(v)
+	case float64:
+		return starlark.Float(v)
+	case bool:
+		return starlark.Bool(v)
+	}
+	return starlark.None
+}
@@ -0,0 +1,58 @@
+package main
+
+import (
+	"runtime"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// AgentStats is what the agent itself costs, reported with every sample so
+// its footprint can be shown next to the machine's.
+type AgentStats struct {
+	CPU        float64 `json:"cpu"`
+	RSSBytes   uint64  `json:"rssBytes"`
+	Goroutines int     `json:"goroutines"`
+	Reconnects uint64  `json:"reconnects"`
+	SendErrors uint64  `json:"sendErrors"`
+
+	LowResource bool `json:"lowResource"`
+}
+
+// Counted by the reporters since the agent started: failed sends, and sends
+// that succeeded after a failure.
+var (
+	sendErrors atomic.Uint64
+	reconnects atomic.Uint64
+)
+
+// selfCPU remembers the previous reading, as the agent's CPU use is the
+// share of all cores it used since then.
+var selfCPU struct {
+	sync.Mutex
+	at   time.Time
+	busy time.Duration
+}
+
+func getAgentStats() AgentStats {
+	s := AgentStats{
+		Goroutines: runtime.NumGoroutine(),
+		Reconnects: reconnects.Load(),
+		SendErrors: sendErrors.Load(),
+
+		LowResource: lowResource.Load(),
+	}
+	if busy, ok := selfCPUTime(); ok {
+		now := time.Now()
+		selfCPU.Lock()
+		if !selfCPU.at.IsZero() {
+			if wall := now.Sub(selfCPU.at); wall > 0 {
+				s.CPU = 100 * float64(busy-selfCPU.busy) / float64(wall) / float64(runtime.NumCPU())
+			}
+		}
+		selfCPU.at, selfCPU.busy = now, busy
+		selfCPU.Unlock()
+	}
+	s.RSSBytes = selfWorkingSet()
+	return s
+}
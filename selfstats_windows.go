@@ -0,0 +1,47 @@
+package main
+
+import (
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type processMemoryCounters struct {
+	cb                         uint32
+	PageFaultCount             uint32
+	PeakWorkingSetSize         uintptr
+	WorkingSetSize             uintptr
+	QuotaPeakPagedPoolUsage    uintptr
+	QuotaPagedPoolUsage        uintptr
+	QuotaPeakNonPagedPoolUsage uintptr
+	QuotaNonPagedPoolUsage     uintptr
+	PagefileUsage              uintptr
+	PeakPagefileUsage          uintptr
+}
+
+var procGetProcessMemoryInfo = psapi.NewProc("GetProcessMemoryInfo")
+
+// selfCPUTime returns the CPU time the agent has used, kernel and user.
+func selfCPUTime() (time.Duration, bool) {
+	var created, exited, kernel, user windows.Filetime
+	if windows.GetProcessTimes(windows.CurrentProcess(), &created, &exited, &kernel, &user) != nil {
+		return 0, false
+	}
+	return filetimeDuration(kernel) + filetimeDuration(user), true
+}
+
+func selfWorkingSet() uint64 {
+	var mc processMemoryCounters
+	mc.cb = uint32(unsafe.Sizeof(mc))
+	if r, _, _ := procGetProcessMemoryInfo.Call(uintptr(windows.CurrentProcess()), uintptr(unsafe.Pointer(&mc)), uintptr(mc.cb)); r == 0 {
+		return 0
+	}
+	return uint64(mc.WorkingSetSize)
+}
+
+// filetimeDuration converts a FILETIME holding an amount of time, in 100 ns
+// units, rather than a date.
+func filetimeDuration(ft windows.Filetime) time.Duration {
+	return time.Duration(uint64(ft.HighDateTime)<<32|uint64(ft.LowDateTime)) * 100
+}
@@ -0,0 +1,258 @@
+package main
+
+import (
+	"crypto/ed25519"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// updatePublicKey is the base64 Ed25519 key release manifests are signed
+// with, set with -ldflags "-X main.updatePublicKey=...". Builds without it
+// never install updates.
+var updatePublicKey = ""
+
+// updateManifest is the <asset>.manifest of a release, which <asset>.sig
+// signs. Signing the version along with the binary's hash keeps an older
+// signed binary from being offered as a newer release.
+type updateManifest struct {
+	Asset   string `json:"asset"`
+	Version string `json:"version"`
+	SHA256  string `json:"sha256"`
+}
+
+var (
+	updateRepo    = getEnv("M_UPDATE_REPO", "coelhomarcus/go-win-monitor")
+	updateChannel = getEnv("M_UPDATE_CHANNEL", "stable")
+	autoUpdate    = getEnv("M_AUTO_UPDATE", "") == "1"
+)
+
+const updateCheckInterval = 6 * time.Hour
+
+// downloadClient allows for a slow link; the binary is a few MB.
+var downloadClient = &http.Client{Timeout: 5 * time.Minute, Transport: httpTransport}
+
+type githubRelease struct {
+	TagName    string `json:"tag_name"`
+	Prerelease bool   `json:"prerelease"`
+	Draft      bool   `json:"draft"`
+	Assets     []struct {
+		Name string `json:"name"`
+		URL  string `json:"browser_download_url"`
+	} `json:"assets"`
+}
+
+func (r githubRelease) asset(name string) string {
+	for _, a := range r.Assets {
+		if a.Name == name {
+			return a.URL
+		}
+	}
+	return ""
+}
+
+// runSelfUpdater checks the M_UPDATE_CHANNEL releases of M_UPDATE_REPO:
+// "stable" for full releases, "beta" to include pre-releases, "off" to never
+// check. A newer version shows the tray item, or is installed right away
+// with M_AUTO_UPDATE=1.
+func runSelfUpdater() {
+	if exe, err := os.Executable(); err == nil {
+		os.Remove(exe + ".old")
+	}
+	if updateChannel == "off" || version == "dev" {
+		return
+	}
+	if updateChannel != "stable" && updateChannel != "beta" {
+		slog.Warn("Unknown M_UPDATE_CHANNEL, using stable", "channel", updateChannel)
+		updateChannel = "stable"
+	}
+	for {
+		rel, err := latestRelease()
+		switch {
+		case err != nil:
+			slog.Warn("Update check failed", "err", err)
+		case rel == nil:
+		case autoUpdate:
+			slog.Info("Installing update", "version", rel.TagName)
+			if err := installUpdate(*rel); err != nil {
+				slog.Error("Update failed", "version", rel.TagName, "err", err)
+			}
+		default:
+			offerUpdate(rel)
+		}
+		time.Sleep(updateCheckInterval)
+	}
+}
+
+// latestRelease returns the newest release on the channel when it is newer
+// than the running version, or nil.
+func latestRelease() (*githubRelease, error) {
+	req, err := http.NewRequest("GET", "https://api.github.com/repos/"+updateRepo+"/releases?per_page=20", nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("Accept", "application/vnd.github+json")
+	req.Header.Set("User-Agent", "go-win-monitor/"+version)
+	resp, err := httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Code: resp.StatusCode}
+	}
+	var releases []githubRelease
+	if err := json.NewDecoder(resp.Body).Decode(&releases); err != nil {
+		return nil, fmt.Errorf("decode releases: %w", err)
+	}
+
+	// Releases come newest first.
+	for _, r := range releases {
+		if r.Draft || r.Prerelease && updateChannel != "beta" {
+			continue
+		}
+		if r.asset(updateAsset) == "" || r.asset(updateAsset+".manifest") == "" || r.asset(updateAsset+".sig") == "" {
+			continue
+		}
+		if newerVersion(r.TagName, version) {
+			return &r, nil
+		}
+		return nil, nil
+	}
+	return nil, nil
+}
+
+// newerVersion compares the numeric parts of tags such as v1.4.2; a
+// git describe suffix like -3-gabc1234 on the running version is ignored.
+func newerVersion(tag, current string) bool {
+	a, b := versionParts(tag), versionParts(current)
+	for i := range max(len(a), len(b)) {
+		var x, y int
+		if i < len(a) {
+			x = a[i]
+		}
+		if i < len(b) {
+			y = b[i]
+		}
+		if x != y {
+			return x > y
+		}
+	}
+	return false
+}
+
+func versionParts(v string) []int {
+	v, _, _ = strings.Cut(strings.TrimPrefix(v, "v"), "-")
+	var parts []int
+	for _, p := range strings.Split(v, ".") {
+		n, err := strconv.Atoi(p)
+		if err != nil {
+			break
+		}
+		parts = append(parts, n)
+	}
+	return parts
+}
+
+// installUpdate downloads the release manifest and its signature, checks
+// the signature against updatePublicKey and that the manifest names a
+// version newer than the running one, then downloads the binary, checks it
+// against the manifest's hash, swaps the executable and restarts.
+// Windows lets a running executable be renamed but not overwritten, so the
+// current one is moved aside and removed on the next start.
+func installUpdate(rel githubRelease) error {
+	manifest, err := verifiedManifest(rel)
+	if err != nil {
+		return err
+	}
+	bin, err := download(rel.asset(updateAsset))
+	if err != nil {
+		return fmt.Errorf("download %s: %w", updateAsset, err)
+	}
+	if sum := sha256.Sum256(bin); !strings.EqualFold(hex.EncodeToString(sum[:]), manifest.SHA256) {
+		return fmt.Errorf("%s does not match the signed manifest", updateAsset)
+	}
+
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(exe+".new", bin, 0o755); err != nil {
+		return err
+	}
+	os.Remove(exe + ".old")
+	if err := os.Rename(exe, exe+".old"); err != nil {
+		os.Remove(exe + ".new")
+		return err
+	}
+	if err := os.Rename(exe+".new", exe); err != nil {
+		os.Rename(exe+".old", exe)
+		return err
+	}
+	slog.Info("Updated, restarting", "version", rel.TagName)
+	restart(exe)
+	return nil
+}
+
+// verifiedManifest returns the release's manifest once its signature checks
+// out and it is for this platform's asset, at the release's version, and
+// newer than the running one.
+func verifiedManifest(rel githubRelease) (updateManifest, error) {
+	var manifest updateManifest
+	key, err := base64.StdEncoding.DecodeString(updatePublicKey)
+	if err != nil || len(key) != ed25519.PublicKeySize {
+		return manifest, fmt.Errorf("this build has no update signing key")
+	}
+	data, err := download(rel.asset(updateAsset + ".manifest"))
+	if err != nil {
+		return manifest, fmt.Errorf("download manifest: %w", err)
+	}
+	sig, err := download(rel.asset(updateAsset + ".sig"))
+	if err != nil {
+		return manifest, fmt.Errorf("download signature: %w", err)
+	}
+	if s, err := base64.StdEncoding.DecodeString(strings.TrimSpace(string(sig))); err == nil {
+		sig = s
+	}
+	if !ed25519.Verify(key, data, sig) {
+		return manifest, fmt.Errorf("signature check failed")
+	}
+	if err := json.Unmarshal(data, &manifest); err != nil {
+		return manifest, fmt.Errorf("decode manifest: %w", err)
+	}
+	switch {
+	case manifest.Asset != updateAsset:
+		return manifest, fmt.Errorf("manifest is for %q, not %q", manifest.Asset, updateAsset)
+	case manifest.Version != rel.TagName:
+		return manifest, fmt.Errorf("manifest is for %s, not %s", manifest.Version, rel.TagName)
+	case !newerVersion(manifest.Version, version):
+		return manifest, fmt.Errorf("%s is not newer than %s", manifest.Version, version)
+	}
+	return manifest, nil
+}
+
+func download(url string) ([]byte, error) {
+	req, err := http.NewRequest("GET", url, nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "go-win-monitor/"+version)
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Code: resp.StatusCode}
+	}
+	return io.ReadAll(io.LimitReader(resp.Body, 200*mb))
+}
@@ -0,0 +1,38 @@
+package main
+
+import "context"
+
+// SensorReading is one sensor of LibreHardwareMonitor or
+// OpenHardwareMonitor: voltages, temperatures, fans, clocks, loads and
+// the rest, as that tool names them.
+type SensorReading struct {
+	Hardware string  `json:"hardware"`
+	Name     string  `json:"name"`
+	Type     string  `json:"type"`
+	Value    float64 `json:"value"`
+}
+
+// collectSensors reads the sensors of LibreHardwareMonitor or
+// OpenHardwareMonitor while one of them runs. Besides being reported as
+// they are, they fill in the CPU temperature when the ACPI zones have none
+// and, on Windows, are where fan speeds come from.
+func collectSensors(context.Context) (fields, error) {
+	sensors, cpuTemp := getHardwareSensors()
+	if len(sensors) == 0 {
+		return nil, nil
+	}
+	return func(m *Metrics) {
+		m.Sensors = sensors
+		if m.CPUTempC < 0 {
+			m.CPUTempC = cpuTemp
+		}
+		if !wants("fans") {
+			return
+		}
+		for _, s := range sensors {
+			if s.Type == "Fan" {
+				m.Fans = append(m.Fans, FanReading{Name: s.Hardware + " " + s.Name, RPM: s.Value, Percent: -1})
+			}
+		}
+	}, nil
+}
@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// getHardwareSensors reads LibreHardwareMonitor, a Windows program.
+func getHardwareSensors() ([]SensorReading, float64) { return nil, -1 }
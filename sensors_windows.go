@@ -0,0 +1,62 @@
+package main
+
+import (
+	"strings"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+// hardwareMonitorNamespaces are where LibreHardwareMonitor and its
+// predecessor OpenHardwareMonitor publish their sensors while running.
+var hardwareMonitorNamespaces = []string{`root\LibreHardwareMonitor`, `root\OpenHardwareMonitor`}
+
+type hwmonHardware struct {
+	Identifier   string
+	Name         string
+	HardwareType string
+}
+
+type hwmonSensor struct {
+	Name       string
+	SensorType string
+	Parent     string
+	Value      float32
+}
+
+// getHardwareSensors returns the sensors of whichever tool is running and
+// the hottest CPU temperature among them, or -1.
+func getHardwareSensors() ([]SensorReading, float64) {
+	for _, ns := range hardwareMonitorNamespaces {
+		var hardware []hwmonHardware
+		if err := wmi.QueryNamespace("SELECT Identifier, Name, HardwareType FROM Hardware", &hardware, ns); err != nil {
+			continue
+		}
+		var sensors []hwmonSensor
+		if err := wmi.QueryNamespace("SELECT Name, SensorType, Parent, Value FROM Sensor", &sensors, ns); err != nil {
+			continue
+		}
+
+		names := map[string]string{}
+		cpus := map[string]bool{}
+		for _, h := range hardware {
+			names[h.Identifier] = h.Name
+			// "Cpu" in LibreHardwareMonitor, "CPU" in OpenHardwareMonitor.
+			cpus[h.Identifier] = strings.EqualFold(h.HardwareType, "cpu")
+		}
+		readings := make([]SensorReading, 0, len(sensors))
+		cpuTemp := -1.0
+		for _, s := range sensors {
+			r := SensorReading{Hardware: names[s.Parent], Name: s.Name, Type: s.SensorType, Value: float64(s.Value)}
+			if r.Hardware == "" {
+				r.Hardware = s.Parent
+			}
+			readings = append(readings, r)
+			// Intel CPUs also report the distance to TjMax as a temperature.
+			if cpus[s.Parent] && s.SensorType == "Temperature" && !strings.Contains(s.Name, "Distance") && s.Value > 0 {
+				cpuTemp = max(cpuTemp, r.Value)
+			}
+		}
+		return readings, cpuTemp
+	}
+	return nil, -1
+}
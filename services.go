@@ -0,0 +1,85 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"time"
+)
+
+type ServiceStatus struct {
+	Name  string `json:"name"`
+	State string `json:"state"`
+}
+
+// serviceStopAlertAfter is M_SERVICE_ALERT_AFTER, how long a watched service
+// that was running must stay down before the service-stopped alert fires,
+// so a restart or a slow start-pending does not raise it.
+var serviceStopAlertAfter = getInterval("M_SERVICE_ALERT_AFTER", time.Minute)
+
+// serviceWatch follows one watched service. downSince is set while a
+// service seen running is not, and alerted once the alert fired for it.
+type serviceWatch struct {
+	state     string
+	seenUp    bool
+	downSince time.Time
+	alerted   bool
+}
+
+// serviceWatches is only used by the services collector, which never runs
+// twice at once.
+var serviceWatches = map[string]*serviceWatch{}
+
+// recordServiceState raises the service-stopped alert when a service that
+// was running has been down for serviceStopAlertAfter, and resolves it once
+// the service runs again.
+func recordServiceState(name, state string) ServiceStatus {
+	w := serviceWatches[name]
+	if w == nil {
+		w = &serviceWatch{}
+		serviceWatches[name] = w
+	}
+	now := time.Now()
+	switch {
+	case state == "running":
+		if w.alerted {
+			raiseServiceAlert(name, state, "resolved")
+		}
+		w.seenUp, w.downSince, w.alerted = true, time.Time{}, false
+	case w.seenUp:
+		if w.state == "running" {
+			slog.Warn("Service stopped running", "service", name, "state", state)
+		}
+		if w.downSince.IsZero() {
+			w.downSince = now
+		}
+		if !w.alerted && now.Sub(w.downSince) >= serviceStopAlertAfter {
+			w.alerted = true
+			raiseServiceAlert(name, state, "fired")
+		}
+	}
+	w.state = state
+	return ServiceStatus{Name: name, State: state}
+}
+
+func raiseServiceAlert(name, state, alertState string) {
+	ev := AlertEvent{
+		Timestamp: time.Now().UTC(), MachineID: machineID(), Hostname: hostname(),
+		Rule: "service-stopped", Series: seriesKey("service_running", []string{"service", name}),
+		Labels: map[string]string{"service": name, "state": state}, State: alertState,
+		Message: fmt.Sprintf("Service %s is running again", name), cooldown: alertCooldown,
+	}
+	if alertState == "fired" {
+		ev.Message = fmt.Sprintf("Service %s stopped (%s)", name, state)
+	}
+	raiseAlert(ev)
+}
+
+func stoppedServices(services []ServiceStatus) []string {
+	var stopped []string
+	for _, s := range services {
+		if s.State != "running" {
+			stopped = append(stopped, s.Name)
+		}
+	}
+	return stopped
+}
@@ -0,0 +1,27 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// getServiceStatuses looks each name up as a launchd job label; a loaded
+// job with a PID is running.
+func getServiceStatuses(names []string) []ServiceStatus {
+	results := make([]ServiceStatus, len(names))
+	for i, name := range names {
+		results[i] = recordServiceState(name, queryJobState(name))
+	}
+	return results
+}
+
+func queryJobState(label string) string {
+	out, err := exec.Command("launchctl", "list", label).Output()
+	if err != nil {
+		return "not-found"
+	}
+	if strings.Contains(string(out), `"PID" = `) {
+		return "running"
+	}
+	return "stopped"
+}
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"os/exec"
+	"strings"
+)
+
+// getServiceStatuses asks systemd for each unit, mapping its active state
+// onto the names the Windows service manager uses.
+func getServiceStatuses(names []string) []ServiceStatus {
+	results := make([]ServiceStatus, len(names))
+	for i, name := range names {
+		results[i] = recordServiceState(name, queryUnitState(name))
+	}
+	return results
+}
+
+func queryUnitState(name string) string {
+	out, err := exec.Command("systemctl", "show", "--property=LoadState,ActiveState", "--value", name).Output()
+	if err != nil {
+		return "unknown"
+	}
+	fields := strings.Fields(string(out))
+	if len(fields) < 2 {
+		return "unknown"
+	}
+	if fields[0] == "not-found" {
+		return "not-found"
+	}
+	switch fields[1] {
+	case "active", "reloading":
+		return "running"
+	case "inactive", "failed":
+		return "stopped"
+	case "activating":
+		return "start-pending"
+	case "deactivating":
+		return "stop-pending"
+	}
+	return "unknown"
+}
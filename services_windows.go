@@ -0,0 +1,56 @@
+package main
+
+import "golang.org/x/sys/windows"
+
+func getServiceStatuses(names []string) []ServiceStatus {
+	scm, err := windows.OpenSCManager(nil, nil, windows.SC_MANAGER_CONNECT)
+	if err != nil {
+		return nil
+	}
+	defer windows.CloseServiceHandle(scm)
+
+	results := make([]ServiceStatus, len(names))
+	for i, name := range names {
+		results[i] = recordServiceState(name, queryServiceState(scm, name))
+	}
+	return results
+}
+
+func queryServiceState(scm windows.Handle, name string) string {
+	namePtr, err := windows.UTF16PtrFromString(name)
+	if err != nil {
+		return "not-found"
+	}
+
+	svc, err := windows.OpenService(scm, namePtr, windows.SERVICE_QUERY_STATUS)
+	if err != nil {
+		return "not-found"
+	}
+	defer windows.CloseServiceHandle(svc)
+
+	var status windows.SERVICE_STATUS
+	if err := windows.QueryServiceStatus(svc, &status); err != nil {
+		return "unknown"
+	}
+	return serviceStateName(status.CurrentState)
+}
+
+func serviceStateName(state uint32) string {
+	switch state {
+	case windows.SERVICE_STOPPED:
+		return "stopped"
+	case windows.SERVICE_START_PENDING:
+		return "start-pending"
+	case windows.SERVICE_STOP_PENDING:
+		return "stop-pending"
+	case windows.SERVICE_RUNNING:
+		return "running"
+	case windows.SERVICE_CONTINUE_PENDING:
+		return "continue-pending"
+	case windows.SERVICE_PAUSE_PENDING:
+		return "pause-pending"
+	case windows.SERVICE_PAUSED:
+		return "paused"
+	}
+	return "unknown"
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+// Session events come from the Windows terminal services; logind and the
+// macOS login window are not watched.
+var reportSessions = false
+
+func watchSessions() {}
+
+// getUserSessions reports no one; utmp and the macOS login window are not
+// read.
+func getUserSessions() ([]UserSession, error) { return nil, nil }
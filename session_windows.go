@@ -0,0 +1,140 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+var (
+	wtsapi32                           = windows.NewLazySystemDLL("wtsapi32.dll")
+	procWTSRegisterSessionNotification = wtsapi32.NewProc("WTSRegisterSessionNotification")
+	procWTSQuerySessionInformation     = wtsapi32.NewProc("WTSQuerySessionInformationW")
+)
+
+var reportSessions = getEnv("M_SESSION_EVENTS", "1") == "1"
+
+const (
+	wmWTSSessionChange   = 0x02b1
+	notifyForThisSession = 0
+	wtsUserName          = 5
+	wtsDomainName        = 7
+	hwndMessage          = ^uintptr(2) // HWND_MESSAGE, (HWND)-3
+)
+
+// SessionEvent is sent to the server's session endpoint when a user logs
+// on or off, or locks or unlocks their session.
+type SessionEvent struct {
+	Timestamp time.Time `json:"timestamp"`
+	MachineID string    `json:"machineId"`
+	Hostname  string    `json:"hostname"`
+	SessionID uint32    `json:"sessionId"`
+	User      string    `json:"user,omitempty"`
+	State     string    `json:"state"`
+	IdleSec   int64     `json:"idleSec"`
+}
+
+var sessionStates = map[uint32]string{
+	windows.WTS_SESSION_LOGON:  "logon",
+	windows.WTS_SESSION_LOGOFF: "logoff",
+	windows.WTS_SESSION_LOCK:   "locked",
+	windows.WTS_SESSION_UNLOCK: "unlocked",
+}
+
+// sessionChanged reports one WTS session change. Lock and unlock also take
+// a fresh sample, so session_locked changes on the server right away rather
+// than at the next tick.
+func sessionChanged(event, sessionID uint32) {
+	state, ok := sessionStates[event]
+	if !ok || !reportSessions {
+		return
+	}
+	ev := SessionEvent{
+		Timestamp: time.Now().UTC(),
+		MachineID: machineID(),
+		Hostname:  hostname(),
+		SessionID: sessionID,
+		User:      sessionUser(sessionID),
+		State:     state,
+		IdleSec:   -1,
+	}
+	// The service runs in session 0, whose last input says nothing about
+	// the user's.
+	if !runningAsService {
+		if idle, ok := getIdleSeconds(); ok {
+			ev.IdleSec = int64(idle)
+		}
+	}
+	slog.Info("Session change", "state", state, "session", sessionID, "user", ev.User)
+	go func() {
+		if err := publish("session", ev); err != nil {
+			slog.Warn("Session event report failed", "err", err)
+		}
+	}()
+	if event == windows.WTS_SESSION_LOCK || event == windows.WTS_SESSION_UNLOCK {
+		queueLocal("snapshot")
+	}
+}
+
+func sessionUser(sessionID uint32) string {
+	return sessionInfo(sessionID, wtsUserName)
+}
+
+func sessionInfo(sessionID uint32, class uintptr) string {
+	var buf *uint16
+	var size uint32
+	r, _, _ := procWTSQuerySessionInformation.Call(0, uintptr(sessionID), class, uintptr(unsafe.Pointer(&buf)), uintptr(unsafe.Pointer(&size)))
+	if r == 0 || buf == nil {
+		return ""
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(buf)))
+	return windows.UTF16PtrToString(buf)
+}
+
+// watchSessions receives the session changes of the tray agent's own
+// session on a hidden message-only window. The service gets them from the
+// service control manager instead.
+func watchSessions() {
+	runtime.LockOSThread()
+	if err := createSessionWindow(); err != nil {
+		slog.Warn("Session changes will not be reported", "err", err)
+		return
+	}
+	pumpMessages()
+}
+
+func createSessionWindow() error {
+	className, _ := windows.UTF16PtrFromString("GoWinMonitorSession")
+	var instance windows.Handle
+	windows.GetModuleHandleEx(0, nil, &instance)
+	wc := wndClassEx{
+		lpfnWndProc:   windows.NewCallback(sessionWndProc),
+		hInstance:     uintptr(instance),
+		lpszClassName: className,
+	}
+	wc.cbSize = uint32(unsafe.Sizeof(wc))
+	if r, _, err := procRegisterClassEx.Call(uintptr(unsafe.Pointer(&wc))); r == 0 {
+		return fmt.Errorf("RegisterClassEx: %w", err)
+	}
+	hwnd, _, err := procCreateWindowEx.Call(0, uintptr(unsafe.Pointer(className)), 0, 0, 0, 0, 0, 0, hwndMessage, 0, uintptr(instance), 0)
+	if hwnd == 0 {
+		return fmt.Errorf("CreateWindowEx: %w", err)
+	}
+	if r, _, err := procWTSRegisterSessionNotification.Call(hwnd, notifyForThisSession); r == 0 {
+		return fmt.Errorf("WTSRegisterSessionNotification: %w", err)
+	}
+	return nil
+}
+
+func sessionWndProc(hwnd, msg, wParam, lParam uintptr) uintptr {
+	if msg == wmWTSSessionChange {
+		sessionChanged(uint32(wParam), uint32(lParam))
+		return 0
+	}
+	r, _, _ := procDefWindowProc.Call(hwnd, msg, wParam, lParam)
+	return r
+}
@@ -0,0 +1,191 @@
+package main
+
+import (
+	"fmt"
+	"html/template"
+	"log/slog"
+	"net/http"
+	"os"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	_ "embed"
+)
+
+//go:embed web/settings.html
+var settingsHTML string
+
+var settingsPage = template.Must(template.New("settings").Parse(settingsHTML))
+
+func openSettings() {
+	openUI("settings")
+}
+
+// setupDone is closed once the first-run setup has been saved.
+var (
+	setupDone = make(chan struct{})
+	setupOnce sync.Once
+)
+
+// needsSetup reports whether there is nowhere to send metrics to, which is
+// the case on a fresh install.
+func needsSetup() bool {
+	return transportName == "http" && apiURL == "" && len(extraAPIURLs) == 0 &&
+		influxURL == "" && statsdAddr == "" && graphiteAddr == "" && otlpEndpoint == ""
+}
+
+// waitForSetup blocks until the settings page has been saved with a server
+// URL, opening it first unless running without a tray. With --no-tray there
+// is nobody to fill it in, so the agent exits instead.
+func waitForSetup() {
+	if noTray && !runningAsService {
+		slog.Error("No server configured; pass --url or --config, or set M_API_URL")
+		os.Exit(1)
+	}
+	slog.Info("No server configured, waiting for setup")
+	setStatus("Not configured")
+	if !headless {
+		openSettings()
+	}
+	<-setupDone
+}
+
+type settingsView struct {
+	Setup      bool
+	Token      string
+	URL        string
+	Interval   string
+	Collectors []struct {
+		Name string
+		On   bool
+	}
+	Message string
+	Error   bool
+}
+
+func handleSettings(w http.ResponseWriter, r *http.Request) {
+	v := settingsView{Token: uiServer.token, Setup: needsSetup()}
+	switch {
+	case r.Method != http.MethodPost && authRejected.Load():
+		v.Message, v.Error = "The server rejected the secret. Enter the new one and save to retry.", true
+	case r.Method != http.MethodPost:
+	case r.PostFormValue("action") == "test":
+		if err := testConnection(r.PostFormValue("url"), r.PostFormValue("secret")); err != nil {
+			v.Message, v.Error = "Connection failed: "+err.Error(), true
+		} else {
+			v.Message = "Connected."
+		}
+	default:
+		if err := saveSettings(r); err != nil {
+			v.Message, v.Error = err.Error(), true
+		} else {
+			v.Message = "Saved."
+		}
+	}
+
+	v.URL = apiURL
+	if v.Setup {
+		v.URL = r.PostFormValue("url")
+	}
+	v.Interval = collectInterval.String()
+	for _, n := range collectorNames {
+		v.Collectors = append(v.Collectors, struct {
+			Name string
+			On   bool
+		}{n, localCollector(n)})
+	}
+	w.Header().Set("Content-Type", "text/html; charset=utf-8")
+	settingsPage.Execute(w, v)
+}
+
+// saveSettings writes the form into the config file, keeping every other
+// key, and asks the collection loop to reload it.
+func saveSettings(r *http.Request) error {
+	setup := needsSetup()
+	if setup && r.PostFormValue("url") == "" {
+		return fmt.Errorf("enter the server URL")
+	}
+	interval := r.PostFormValue("interval")
+	if d, err := time.ParseDuration(interval); err != nil || d < minInterval || d > maxInterval {
+		return fmt.Errorf("interval must be between %s and %s", minInterval, maxInterval)
+	}
+
+	err := updateConfigFile(func(cfg map[string]any) error {
+		cfg["url"] = r.PostFormValue("url")
+		cfg["interval"] = interval
+		delete(cfg, "collect_interval")
+		delete(cfg, "send_interval")
+
+		selected := slices.DeleteFunc(r.PostForm["collector"], func(n string) bool { return !slices.Contains(collectorNames, n) })
+		switch len(selected) {
+		case 0:
+			return fmt.Errorf("select at least one collector")
+		case len(collectorNames):
+			delete(cfg, "collectors")
+		default:
+			cfg["collectors"] = selected
+		}
+
+		if s := r.PostFormValue("secret"); s != "" {
+			if err := writeStoredSecret(s); err != nil {
+				return err
+			}
+			// A secret in the file would shadow the stored one.
+			delete(cfg, "secret")
+			delete(cfg, "agent_secret")
+			delete(cfg, "secret_file")
+			delete(cfg, "agent_secret_file")
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	if setup {
+		// Nothing has been sent yet, so the new server can be used without
+		// a restart.
+		setupOnce.Do(func() {
+			apiURL = r.PostFormValue("url")
+			if s := r.PostFormValue("secret"); s != "" {
+				secret = s
+			}
+			close(setupDone)
+		})
+	} else if r.PostFormValue("secret") != "" {
+		queueLocal("newSecret")
+	}
+	queueReload()
+	return nil
+}
+
+// applySecret switches to the secret just saved from the settings page and
+// retries right away, so a rejected secret is fixed without a restart. The
+// hello goes out again since the first one may have been rejected too. It
+// runs on the collection loop's goroutine.
+func applySecret() {
+	secret = loadSecret()
+	if stored, _ := readStoredSecret(); secret != stored {
+		slog.Warn("M_AGENT_SECRET or M_AGENT_SECRET_FILE overrides the secret saved from the settings page")
+	}
+	sessions.Clear()
+	resetTransport()
+	reconnectPending = true
+	slog.Info("Secret changed, retrying")
+	hellos.Schedule()
+}
+
+// testConnection sends a hello to url, with the current secret when none is
+// given.
+func testConnection(url, token string) error {
+	if url == "" {
+		return fmt.Errorf("enter the server URL")
+	}
+	if token == "" {
+		token = secret
+	}
+	_, err := postJSON(strings.TrimRight(url, "/")+"/pc-stats/hello", token, newHello())
+	return err
+}
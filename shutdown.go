@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"log/slog"
+	"sync"
+	"time"
+)
+
+// agentCtx is cancelled when the agent starts shutting down; the collection
+// loop and the dashboard's live feed stop on it.
+var agentCtx, stopAgent = context.WithCancel(context.Background())
+
+// shutdownTimeout bounds the whole shutdown. Windows gives a console
+// program 5 seconds after CTRL_CLOSE_EVENT before it is killed.
+const shutdownTimeout = 4 * time.Second
+
+// collecting is held while the collection loop runs, so shutdown can let
+// the report in flight finish.
+var collecting sync.WaitGroup
+
+// AgentStopping tells the server the agent is going away on purpose, so it
+// can mark the machine offline right away instead of waiting for reports to
+// stop arriving.
+type AgentStopping struct {
+	Timestamp time.Time `json:"timestamp"`
+	MachineID string    `json:"machineId"`
+	RunID     string    `json:"runId"`
+	Reason    string    `json:"reason"`
+}
+
+var shutdownOnce sync.Once
+
+// shutdown stops the collection loop, says goodbye to the server, closes the
+// transport and dashboard connections and flushes what is on disk. Only the
+// first call does anything; the process should exit once it returns.
+func shutdown(reason string) {
+	shutdownOnce.Do(func() {
+		slog.Info("Agent stopping", "reason", reason, "event", evtStopped)
+		deadline := time.Now().Add(shutdownTimeout)
+		stopAgent()
+		if !waitUntil(deadline, collecting.Wait) {
+			slog.Warn("Collection loop did not stop in time")
+		}
+
+		if transportName != "none" || len(apiTargets) > 0 {
+			ok := waitUntil(deadline, func() {
+				err := publish("stopping", AgentStopping{
+					Timestamp: time.Now().UTC(),
+					MachineID: machineID(),
+					RunID:     runID,
+					Reason:    reason,
+				})
+				if err != nil {
+					slog.Warn("Stopping message failed", "err", err)
+				}
+			})
+			if !ok {
+				slog.Warn("Stopping message not sent in time")
+			}
+		}
+		waitUntil(deadline, func() {
+			if sink != nil {
+				sink.Close()
+			}
+		})
+
+		if etwNet {
+			stopNetworkTrace()
+		}
+		if dataUsageEnabled {
+			if err := dataUsage.Save(); err != nil {
+				slog.Warn("Save data usage failed", "err", err)
+			}
+		}
+		if historyDB != nil {
+			if err := historyDB.Close(); err != nil {
+				slog.Warn("History store close failed", "err", err)
+			}
+		}
+		slog.Info("Agent stopped")
+		if agentLogFile != nil {
+			agentLogFile.Close()
+		}
+	})
+}
+
+// waitUntil runs f and waits for it until deadline, reporting whether it
+// finished.
+func waitUntil(deadline time.Time, f func()) bool {
+	done := make(chan struct{})
+	go func() {
+		defer close(done)
+		f()
+	}()
+	select {
+	case <-done:
+		return true
+	case <-time.After(time.Until(deadline)):
+		return false
+	}
+}
@@ -0,0 +1,31 @@
+package main
+
+type SmartReport struct {
+	Disks []DiskHealth      `json:"disks"`
+	ATA   []SmartPrediction `json:"ata"`
+}
+
+type DiskHealth struct {
+	Name        string `json:"name"`
+	Serial      string `json:"serial"`
+	MediaType   string `json:"mediaType"`
+	Health      string `json:"health"`
+	WearPercent int    `json:"wearPercent"`
+	TempC       int    `json:"tempC"`
+	TempMaxC    int    `json:"tempMaxC"`
+	ReadErrors  uint64 `json:"readErrors"`
+	WriteErrors uint64 `json:"writeErrors"`
+	PowerOnHrs  uint32 `json:"powerOnHours"`
+}
+
+type SmartPrediction struct {
+	Instance           string `json:"instance"`
+	PredictFailure     bool   `json:"predictFailure"`
+	ReallocatedSectors int64  `json:"reallocatedSectors"`
+	WearLevel          int    `json:"wearLevel"`
+}
+
+type DiskTemperature struct {
+	Name  string `json:"name"`
+	TempC int    `json:"tempC"`
+}
@@ -0,0 +1,13 @@
+//go:build !windows
+
+package main
+
+import "log/slog"
+
+// runSmartCollector reads the Windows storage WMI classes; there is no
+// SMART report elsewhere.
+func runSmartCollector() {
+	slog.Info("SMART collection is only available on Windows")
+}
+
+func getDiskTemperatures() []DiskTemperature { return nil }
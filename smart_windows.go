@@ -0,0 +1,211 @@
+package main
+
+import (
+	"encoding/binary"
+	"log/slog"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/yusufpapurcu/wmi"
+)
+
+type msftPhysicalDisk struct {
+	DeviceId     string
+	FriendlyName string
+	SerialNumber string
+	MediaType    uint16
+	HealthStatus uint16
+}
+
+type msftStorageReliabilityCounter struct {
+	DeviceId         string
+	Wear             uint8
+	Temperature      uint8
+	TemperatureMax   uint8
+	ReadErrorsTotal  uint64
+	WriteErrorsTotal uint64
+	PowerOnHours     uint32
+}
+
+type msStorageDriverFailurePredictStatus struct {
+	InstanceName   string
+	PredictFailure bool
+}
+
+type msStorageDriverFailurePredictData struct {
+	InstanceName   string
+	VendorSpecific []uint8
+}
+
+const storageNamespace = `root\Microsoft\Windows\Storage`
+
+// smartInterval is how often the Storage classes are queried. They are slow,
+// so the disk temperatures sent with every report are the last ones read.
+const smartInterval = time.Hour
+
+var diskTemps struct {
+	sync.Mutex
+	temps     []DiskTemperature
+	checkedAt time.Time
+}
+
+func runSmartCollector() {
+	for {
+		waitOutLowResource()
+		report := collectSmart()
+		if err := publish("smart", report); err != nil {
+			slog.Warn("SMART report failed", "err", err)
+		}
+		time.Sleep(smartInterval)
+	}
+}
+
+func collectSmart() SmartReport {
+	var report SmartReport
+
+	disks, err := queryPhysicalDisks()
+	if err != nil {
+		slog.Warn("SMART physical disk query failed", "err", err)
+	}
+	counters := queryReliabilityCounters()
+
+	for _, d := range disks {
+		h := DiskHealth{
+			Name:        d.FriendlyName,
+			Serial:      strings.TrimSpace(d.SerialNumber),
+			MediaType:   mediaTypeName(d.MediaType),
+			Health:      healthStatusName(d.HealthStatus),
+			WearPercent: -1,
+			TempC:       -1,
+			TempMaxC:    -1,
+		}
+		for _, c := range counters {
+			if c.DeviceId == d.DeviceId {
+				h.WearPercent = int(c.Wear)
+				h.ReadErrors = c.ReadErrorsTotal
+				h.WriteErrors = c.WriteErrorsTotal
+				h.PowerOnHrs = c.PowerOnHours
+				if c.Temperature > 0 {
+					h.TempC = int(c.Temperature)
+					h.TempMaxC = int(c.TemperatureMax)
+				}
+			}
+		}
+		report.Disks = append(report.Disks, h)
+	}
+	if err == nil {
+		diskTemps.Lock()
+		diskTemps.temps, diskTemps.checkedAt = diskTemperatures(disks, counters), time.Now()
+		diskTemps.Unlock()
+	}
+
+	var status []msStorageDriverFailurePredictStatus
+	var data []msStorageDriverFailurePredictData
+	_ = wmi.QueryNamespace("SELECT InstanceName, PredictFailure FROM MSStorageDriver_FailurePredictStatus", &status, `root\wmi`)
+	_ = wmi.QueryNamespace("SELECT InstanceName, VendorSpecific FROM MSStorageDriver_FailurePredictData", &data, `root\wmi`)
+
+	for _, s := range status {
+		p := SmartPrediction{Instance: s.InstanceName, PredictFailure: s.PredictFailure, ReallocatedSectors: -1, WearLevel: -1}
+		for _, d := range data {
+			if d.InstanceName == s.InstanceName {
+				p.ReallocatedSectors, p.WearLevel = parseSmartAttributes(d.VendorSpecific)
+			}
+		}
+		report.ATA = append(report.ATA, p)
+	}
+
+	return report
+}
+
+func queryPhysicalDisks() ([]msftPhysicalDisk, error) {
+	var disks []msftPhysicalDisk
+	err := wmi.QueryNamespace("SELECT DeviceId, FriendlyName, SerialNumber, MediaType, HealthStatus FROM MSFT_PhysicalDisk", &disks, storageNamespace)
+	return disks, err
+}
+
+// queryReliabilityCounters requires elevation; without it only disk health is
+// available.
+func queryReliabilityCounters() []msftStorageReliabilityCounter {
+	var counters []msftStorageReliabilityCounter
+	_ = wmi.QueryNamespace("SELECT DeviceId, Wear, Temperature, TemperatureMax, ReadErrorsTotal, WriteErrorsTotal, PowerOnHours FROM MSFT_StorageReliabilityCounter", &counters, storageNamespace)
+	return counters
+}
+
+func getDiskTemperatures() []DiskTemperature {
+	diskTemps.Lock()
+	defer diskTemps.Unlock()
+
+	if time.Since(diskTemps.checkedAt) < smartInterval {
+		return diskTemps.temps
+	}
+	diskTemps.checkedAt = time.Now()
+	diskTemps.temps = nil
+	counters := queryReliabilityCounters()
+	if len(counters) == 0 {
+		return nil
+	}
+	disks, err := queryPhysicalDisks()
+	if err != nil {
+		return nil
+	}
+	diskTemps.temps = diskTemperatures(disks, counters)
+	return diskTemps.temps
+}
+
+func diskTemperatures(disks []msftPhysicalDisk, counters []msftStorageReliabilityCounter) []DiskTemperature {
+	var temps []DiskTemperature
+	for _, d := range disks {
+		for _, c := range counters {
+			if c.DeviceId == d.DeviceId && c.Temperature > 0 {
+				temps = append(temps, DiskTemperature{Name: d.FriendlyName, TempC: int(c.Temperature)})
+			}
+		}
+	}
+	return temps
+}
+
+// parseSmartAttributes walks the ATA SMART attribute table: a 2-byte revision
+// followed by 30 entries of 12 bytes (id, flags, value, worst, 6-byte raw, reserved).
+func parseSmartAttributes(b []uint8) (reallocated int64, wear int) {
+	reallocated, wear = -1, -1
+	for off := 2; off+12 <= len(b) && off < 2+30*12; off += 12 {
+		id := b[off]
+		value := b[off+3]
+		raw := make([]byte, 8)
+		copy(raw, b[off+5:off+11])
+		switch id {
+		case 5:
+			reallocated = int64(binary.LittleEndian.Uint64(raw))
+		case 177, 231, 233:
+			if wear < 0 {
+				wear = int(value)
+			}
+		}
+	}
+	return reallocated, wear
+}
+
+func mediaTypeName(t uint16) string {
+	switch t {
+	case 3:
+		return "HDD"
+	case 4:
+		return "SSD"
+	case 5:
+		return "SCM"
+	}
+	return "Unknown"
+}
+
+func healthStatusName(s uint16) string {
+	switch s {
+	case 0:
+		return "Healthy"
+	case 1:
+		return "Warning"
+	case 2:
+		return "Unhealthy"
+	}
+	return "Unknown"
+}
@@ -0,0 +1,160 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"log/slog"
+	"net/http"
+	"slices"
+	"sync/atomic"
+	"time"
+)
+
+// The speed test downloads from M_SPEEDTEST_DOWNLOAD_URL and uploads to
+// M_SPEEDTEST_UPLOAD_URL, Cloudflare's speed test endpoints by default.
+// The latency is the median time to the headers of an empty download.
+var (
+	speedTestDownloadURL = getEnv("M_SPEEDTEST_DOWNLOAD_URL", "https://speed.cloudflare.com/__down?bytes=25000000")
+	speedTestUploadURL   = getEnv("M_SPEEDTEST_UPLOAD_URL", "https://speed.cloudflare.com/__up")
+	speedTestLatencyURL  = getEnv("M_SPEEDTEST_LATENCY_URL", "https://speed.cloudflare.com/__down?bytes=0")
+)
+
+const (
+	speedTestDuration    = 10 * time.Second
+	speedTestUploadBytes = 10 * 1024 * 1024
+	speedTestPings       = 5
+)
+
+// SpeedTestResult is the /pc-stats/speedtest message. ID is the command's,
+// or "local" when started from the tray.
+type SpeedTestResult struct {
+	ID           string    `json:"id"`
+	MachineID    string    `json:"machineId"`
+	Timestamp    time.Time `json:"timestamp"`
+	DownloadMbps float64   `json:"downloadMbps"`
+	UploadMbps   float64   `json:"uploadMbps"`
+	LatencyMs    float64   `json:"latencyMs"`
+	Error        string    `json:"error,omitempty"`
+}
+
+var speedTestRunning atomic.Bool
+
+// runSpeedTest measures the link and publishes the result. Only one test
+// runs at a time, as two would share the bandwidth.
+func runSpeedTest(id string) {
+	if !speedTestRunning.CompareAndSwap(false, true) {
+		slog.Info("Speed test already running", "id", id)
+		return
+	}
+	defer speedTestRunning.Store(false)
+
+	slog.Info("Speed test started", "id", id)
+	r := measureSpeed(agentCtx)
+	r.ID, r.MachineID = id, machineID()
+	if r.Error != "" {
+		slog.Warn("Speed test failed", "err", r.Error)
+	} else {
+		slog.Info("Speed test done", "download", r.DownloadMbps, "upload", r.UploadMbps, "latencyMs", r.LatencyMs)
+	}
+	if err := publish("speedtest", r); err != nil {
+		slog.Warn("Speed test report failed", "err", err)
+	}
+	if id == "local" {
+		if r.Error != "" {
+			showToast(tr("Speed test failed"), r.Error)
+		} else {
+			showToast(tr("Speed test"), tr("↓ %.1f Mbps  ↑ %.1f Mbps  %.0f ms", r.DownloadMbps, r.UploadMbps, r.LatencyMs))
+		}
+	}
+}
+
+func measureSpeed(ctx context.Context) SpeedTestResult {
+	r := SpeedTestResult{Timestamp: time.Now().UTC()}
+	latency, err := measureLatency(ctx)
+	if err == nil {
+		r.LatencyMs = latency
+		r.DownloadMbps, err = measureDownload(ctx)
+	}
+	if err == nil {
+		r.UploadMbps, err = measureUpload(ctx)
+	}
+	if err != nil {
+		r.Error = err.Error()
+	}
+	return r
+}
+
+func measureLatency(ctx context.Context) (float64, error) {
+	var times []float64
+	for range speedTestPings {
+		req, err := http.NewRequestWithContext(ctx, "GET", speedTestLatencyURL, nil)
+		if err != nil {
+			return 0, err
+		}
+		start := time.Now()
+		resp, err := downloadClient.Do(req)
+		if err != nil {
+			return 0, fmt.Errorf("latency: %w", err)
+		}
+		times = append(times, float64(time.Since(start))/float64(time.Millisecond))
+		io.Copy(io.Discard, resp.Body)
+		resp.Body.Close()
+	}
+	slices.Sort(times)
+	return times[len(times)/2], nil
+}
+
+// measureDownload reads for at most speedTestDuration.
+func measureDownload(ctx context.Context) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, speedTestDuration)
+	defer cancel()
+	req, err := http.NewRequestWithContext(ctx, "GET", speedTestDownloadURL, nil)
+	if err != nil {
+		return 0, err
+	}
+	start := time.Now()
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("download: %w", err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		return 0, fmt.Errorf("download: %w", &statusError{Code: resp.StatusCode})
+	}
+	n, err := io.Copy(io.Discard, resp.Body)
+	if err != nil && ctx.Err() == nil {
+		return 0, fmt.Errorf("download: %w", err)
+	}
+	return mbps(n, time.Since(start)), nil
+}
+
+func measureUpload(ctx context.Context) (float64, error) {
+	ctx, cancel := context.WithTimeout(ctx, 2*speedTestDuration)
+	defer cancel()
+	body := bytes.NewReader(make([]byte, speedTestUploadBytes))
+	req, err := http.NewRequestWithContext(ctx, "POST", speedTestUploadURL, body)
+	if err != nil {
+		return 0, err
+	}
+	req.Header.Set("Content-Type", "application/octet-stream")
+	start := time.Now()
+	resp, err := downloadClient.Do(req)
+	if err != nil {
+		return 0, fmt.Errorf("upload: %w", err)
+	}
+	io.Copy(io.Discard, resp.Body)
+	resp.Body.Close()
+	if resp.StatusCode/100 != 2 {
+		return 0, fmt.Errorf("upload: %w", &statusError{Code: resp.StatusCode})
+	}
+	return mbps(speedTestUploadBytes, time.Since(start)), nil
+}
+
+func mbps(n int64, d time.Duration) float64 {
+	if d <= 0 {
+		return 0
+	}
+	return float64(n) * 8 / d.Seconds() / 1e6
+}
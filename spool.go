@@ -0,0 +1,136 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sync"
+)
+
+// spool is an append-only JSONL file of metrics waiting to be sent. When it
+// grows past maxBytes the oldest entries are pruned.
+type spool struct {
+	mu       sync.Mutex
+	path     string
+	maxBytes int64
+}
+
+func newSpool(path string, maxBytes int64) (*spool, error) {
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return nil, fmt.Errorf("create spool dir: %w", err)
+	}
+	return &spool{path: path, maxBytes: maxBytes}, nil
+}
+
+func defaultDataDir() string {
+	dir, err := os.UserCacheDir()
+	if err != nil {
+		dir = os.TempDir()
+	}
+	return filepath.Join(dir, "go-win-monitor")
+}
+
+func (s *spool) Append(m Metrics) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	line, err := json.Marshal(m)
+	if err != nil {
+		return fmt.Errorf("marshal: %w", err)
+	}
+
+	f, err := os.OpenFile(s.path, os.O_CREATE|os.O_APPEND|os.O_WRONLY, 0o600)
+	if err != nil {
+		return fmt.Errorf("open spool: %w", err)
+	}
+	_, err = f.Write(append(line, '\n'))
+	info, statErr := f.Stat()
+	f.Close()
+	if err != nil {
+		return fmt.Errorf("write spool: %w", err)
+	}
+
+	if statErr == nil && info.Size() > s.maxBytes {
+		return s.prune()
+	}
+	return nil
+}
+
+// prune keeps the newest entries that fit in three quarters of the limit, so
+// pruning doesn't happen again on the very next append.
+func (s *spool) prune() error {
+	lines, err := s.readLines()
+	if err != nil {
+		return err
+	}
+
+	keep := s.maxBytes * 3 / 4
+	var size int64
+	start := len(lines)
+	for start > 0 && size+int64(len(lines[start-1]))+1 <= keep {
+		start--
+		size += int64(len(lines[start])) + 1
+	}
+	return s.writeLines(lines[start:])
+}
+
+// Drain sends spooled metrics oldest first. Entries that could not be sent are
+// kept for the next attempt.
+func (s *spool) Drain(send func(Metrics) error) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	lines, err := s.readLines()
+	if err != nil || len(lines) == 0 {
+		return err
+	}
+
+	for i, line := range lines {
+		var m Metrics
+		if err := json.Unmarshal(line, &m); err != nil {
+			continue
+		}
+		if err := send(m); err != nil {
+			if werr := s.writeLines(lines[i:]); werr != nil {
+				return werr
+			}
+			return err
+		}
+	}
+	return os.Remove(s.path)
+}
+
+func (s *spool) readLines() ([][]byte, error) {
+	data, err := os.ReadFile(s.path)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("read spool: %w", err)
+	}
+
+	var lines [][]byte
+	sc := bufio.NewScanner(bytes.NewReader(data))
+	sc.Buffer(make([]byte, 64*1024), 16*1024*1024)
+	for sc.Scan() {
+		if len(sc.Bytes()) > 0 {
+			lines = append(lines, bytes.Clone(sc.Bytes()))
+		}
+	}
+	return lines, sc.Err()
+}
+
+func (s *spool) writeLines(lines [][]byte) error {
+	tmp := s.path + ".tmp"
+	data := append(bytes.Join(lines, []byte{'\n'}), '\n')
+	if len(lines) == 0 {
+		data = nil
+	}
+	if err := os.WriteFile(tmp, data, 0o600); err != nil {
+		return fmt.Errorf("write spool: %w", err)
+	}
+	return os.Rename(tmp, s.path)
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strconv"
+	"time"
+)
+
+// maxDatagram keeps StatsD packets under a typical Ethernet MTU.
+const maxDatagram = 1432
+
+var metricPathUnsafe = regexp.MustCompile(`[^a-zA-Z0-9_-]+`)
+
+// metricPath builds a dotted Graphite/StatsD path: winmon.<host>.<name>
+// followed by the label values.
+func metricPath(host, name string, labels []string) string {
+	p := "winmon." + metricPathUnsafe.ReplaceAllString(host, "_") + "." + name
+	for i := 1; i < len(labels); i += 2 {
+		if labels[i] != "" {
+			p += "." + metricPathUnsafe.ReplaceAllString(labels[i], "_")
+		}
+	}
+	return p
+}
+
+type statsdEmitter struct {
+	addr string
+	host string
+}
+
+func newStatsdEmitter(addr string) *statsdEmitter {
+	host, _ := os.Hostname()
+	return &statsdEmitter{addr: addr, host: host}
+}
+
+func (s *statsdEmitter) Write(m Metrics) error {
+	conn, err := net.Dial("udp", s.addr)
+	if err != nil {
+		return fmt.Errorf("dial statsd: %w", err)
+	}
+	defer conn.Close()
+
+	var pkt bytes.Buffer
+	var werr error
+	flush := func() {
+		if pkt.Len() > 0 && werr == nil {
+			_, werr = conn.Write(pkt.Bytes())
+		}
+		pkt.Reset()
+	}
+
+	visitSamples(m, func(name, help string, value float64, labels ...string) {
+		line := metricPath(s.host, name, labels) + ":" + strconv.FormatFloat(value, 'g', -1, 64) + "|g"
+		if pkt.Len()+len(line)+1 > maxDatagram {
+			flush()
+		}
+		if pkt.Len() > 0 {
+			pkt.WriteByte('\n')
+		}
+		pkt.WriteString(line)
+	})
+	flush()
+
+	if werr != nil {
+		return fmt.Errorf("write statsd: %w", werr)
+	}
+	return nil
+}
+
+type graphiteEmitter struct {
+	addr string
+	host string
+}
+
+func newGraphiteEmitter(addr string) *graphiteEmitter {
+	host, _ := os.Hostname()
+	return &graphiteEmitter{addr: addr, host: host}
+}
+
+func (g *graphiteEmitter) Write(m Metrics) error {
+	var b bytes.Buffer
+	ts := strconv.FormatInt(m.Timestamp.Unix(), 10)
+	visitSamples(m, func(name, help string, value float64, labels ...string) {
+		fmt.Fprintf(&b, "%s %s %s\n", metricPath(g.host, name, labels), strconv.FormatFloat(value, 'g', -1, 64), ts)
+	})
+
+	conn, err := net.DialTimeout("tcp", g.addr, 5*time.Second)
+	if err != nil {
+		return fmt.Errorf("dial graphite: %w", err)
+	}
+	defer conn.Close()
+
+	conn.SetWriteDeadline(time.Now().Add(requestTimeout))
+	if _, err := conn.Write(b.Bytes()); err != nil {
+		return fmt.Errorf("write graphite: %w", err)
+	}
+	return nil
+}
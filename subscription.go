@@ -0,0 +1,104 @@
+package main
+
+import (
+	"log/slog"
+	"slices"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+// subscription holds the collectors the server asked for in its hello
+// response; nil means all of them. Either way only the ones enabled locally
+// with M_COLLECTORS run.
+var subscription []string
+
+func wants(name string) bool {
+	return localCollector(name) && (subscription == nil || slices.Contains(subscription, name))
+}
+
+// collectorOverrides holds the collectors switched with the enable and
+// disable commands, which win over the settings until the next start.
+// Collectors' goroutines read it.
+var collectorOverrides = struct {
+	sync.RWMutex
+	on map[string]bool
+}{on: map[string]bool{}}
+
+// docker, hyperV and gpuProcesses switch the off-by-default collectors,
+// read by the collectors themselves.
+var docker, hyperV, gpuProcesses atomic.Bool
+
+func loadOptInCollectors() {
+	docker.Store(getEnv("M_DOCKER", "") == "1" || collectorOptIn("docker"))
+	hyperV.Store(getEnv("M_HYPERV", "") == "1" || collectorOptIn("hyperv"))
+	gpuProcesses.Store(getEnv("M_GPU_PROCESSES", "") == "1" || collectorOptIn("gpu-processes"))
+}
+
+func localCollector(name string) bool {
+	collectorOverrides.RLock()
+	on, set := collectorOverrides.on[name]
+	collectorOverrides.RUnlock()
+	if set {
+		return on
+	}
+	if on, set := collectorToggle(name); set {
+		return on
+	}
+	configMu.RLock()
+	defer configMu.RUnlock()
+	return len(collectors) == 0 || slices.Contains(collectors, name)
+}
+
+// collectorToggle reads M_COLLECTORS_<NAME>, e.g. M_COLLECTORS_DISK_TEMPS,
+// normally coming from a [collectors] table in the config file. It takes
+// precedence over the M_COLLECTORS list.
+func collectorToggle(name string) (on, set bool) {
+	key := "M_COLLECTORS_" + strings.ToUpper(strings.ReplaceAll(name, "-", "_"))
+	switch getEnv(key, "") {
+	case "1":
+		return true, true
+	case "0":
+		return false, true
+	}
+	return false, false
+}
+
+// collectorOptIn reports whether an off-by-default collector was turned on
+// with its toggle.
+func collectorOptIn(name string) bool {
+	on, set := collectorToggle(name)
+	return on && set
+}
+
+func availableCollectors() []string {
+	var names []string
+	for _, n := range collectorNames {
+		if localCollector(n) {
+			names = append(names, n)
+		}
+	}
+	return names
+}
+
+func applySubscription(names []string, collectSec int) {
+	var unknown []string
+	for _, n := range names {
+		if !slices.Contains(collectorNames, n) {
+			unknown = append(unknown, n)
+		}
+	}
+	if len(unknown) > 0 {
+		slog.Warn("Server subscribed to unknown collectors", "collectors", unknown)
+	}
+	subscription = names
+	slog.Info("Server subscribed", "collectors", names)
+
+	if collectSec > 0 {
+		collectInterval = min(max(time.Duration(collectSec)*time.Second, minInterval), maxInterval)
+		sendInterval = max(sendInterval, collectInterval)
+		slog.Info("Server set the collect interval", "interval", collectInterval)
+		updateIntervalMenu()
+	}
+}
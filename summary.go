@@ -0,0 +1,214 @@
+package main
+
+import (
+	"log/slog"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// M_DAILY_SUMMARY sends a summary of the previous day shortly after
+// midnight, computed from local history: the SQLite store with
+// M_HISTORY_DB, which also covers a day the agent was restarted or the
+// machine was off at midnight, or else the in-memory samples, which only
+// hold the last M_HISTORY_SIZE.
+// M_DAILY_SUMMARY_TOAST also shows it as a notification.
+var (
+	dailySummary      = getEnv("M_DAILY_SUMMARY", "1") == "1"
+	dailySummaryToast = getEnv("M_DAILY_SUMMARY_TOAST", "") == "1"
+)
+
+const (
+	// summaryMaxGap is the longest time between two samples still counted as
+	// the machine being on; it is above the store's downsampleStep.
+	summaryMaxGap = 10 * time.Minute
+	summaryRetry  = 15 * time.Minute
+)
+
+// DailySummary is sent to the server's summary endpoint once a day.
+// OnSec is how long the agent was sampling that day and UptimeSec the
+// system uptime at its last sample. GPU is nil when no GPU was read.
+type DailySummary struct {
+	Timestamp    time.Time    `json:"timestamp"`
+	MachineID    string       `json:"machineId"`
+	Hostname     string       `json:"hostname"`
+	Date         string       `json:"date"`
+	Samples      int          `json:"samples"`
+	CPU          SummaryStat  `json:"cpu"`
+	RAM          SummaryStat  `json:"ram"`
+	GPU          *SummaryStat `json:"gpu,omitempty"`
+	NetSentBytes uint64       `json:"netSentBytes"`
+	NetRecvBytes uint64       `json:"netRecvBytes"`
+	Alerts       int          `json:"alerts"`
+	OnSec        uint64       `json:"onSec"`
+	UptimeSec    uint64       `json:"uptimeSec"`
+}
+
+type SummaryStat struct {
+	Avg  float64 `json:"avg"`
+	Peak float64 `json:"peak"`
+}
+
+func (s *SummaryStat) add(v float64, n int) {
+	s.Avg += (v - s.Avg) / float64(n)
+	s.Peak = max(s.Peak, v)
+}
+
+// firedAlerts holds when alerts fired over the last two days, for the
+// summary's count. Only alerts raised while the agent ran are counted.
+var firedAlerts struct {
+	sync.Mutex
+	at []time.Time
+}
+
+func noteAlertFired(t time.Time) {
+	firedAlerts.Lock()
+	defer firedAlerts.Unlock()
+	cutoff := t.Add(-48 * time.Hour)
+	for len(firedAlerts.at) > 0 && firedAlerts.at[0].Before(cutoff) {
+		firedAlerts.at = firedAlerts.at[1:]
+	}
+	firedAlerts.at = append(firedAlerts.at, t)
+}
+
+func alertsBetween(from, to time.Time) int {
+	firedAlerts.Lock()
+	defer firedAlerts.Unlock()
+	n := 0
+	for _, t := range firedAlerts.at {
+		if !t.Before(from) && t.Before(to) {
+			n++
+		}
+	}
+	return n
+}
+
+func summaryStatePath() string {
+	return filepath.Join(defaultDataDir(), "summary-day")
+}
+
+// runDailySummary sends yesterday's summary unless summary-day in the data
+// directory says it went out already, then waits for the next midnight. A
+// day without samples is marked done without sending anything.
+func runDailySummary() {
+	for {
+		now := time.Now()
+		today := startOfDay(now)
+		day := today.AddDate(0, 0, -1)
+		if last, err := time.ParseInLocation(time.DateOnly, lastSummaryDay(), time.Local); err == nil && !last.Before(day) {
+			time.Sleep(time.Until(today.AddDate(0, 0, 1).Add(time.Minute)))
+			continue
+		}
+		if err := sendDailySummary(day); err != nil {
+			slog.Warn("Daily summary failed", "day", day.Format(time.DateOnly), "err", err)
+			time.Sleep(summaryRetry)
+			continue
+		}
+		if err := os.WriteFile(summaryStatePath(), []byte(day.Format(time.DateOnly)+"\n"), 0o600); err != nil {
+			slog.Warn("Save daily summary state failed", "err", err)
+		}
+	}
+}
+
+func lastSummaryDay() string {
+	data, _ := os.ReadFile(summaryStatePath())
+	return strings.TrimSpace(string(data))
+}
+
+func startOfDay(t time.Time) time.Time {
+	y, m, d := t.Date()
+	return time.Date(y, m, d, 0, 0, 0, 0, t.Location())
+}
+
+func sendDailySummary(day time.Time) error {
+	samples, err := summarySamples(day, day.AddDate(0, 0, 1))
+	if err != nil {
+		return err
+	}
+	if len(samples) == 0 {
+		return nil
+	}
+	s := summarize(samples)
+	s.Date = day.Format(time.DateOnly)
+	s.Alerts = alertsBetween(day, day.AddDate(0, 0, 1))
+	slog.Info("Daily summary", "day", s.Date, "samples", s.Samples, "cpuAvg", s.CPU.Avg, "ramAvg", s.RAM.Avg)
+	if err := publish("summary", s); err != nil {
+		return err
+	}
+	if dailySummaryToast {
+		showToast(tr("Summary of %s", s.Date), summaryText(s))
+	}
+	return nil
+}
+
+// summarySamples reads [from, to) from the history store when there is one
+// and from the in-memory samples otherwise.
+func summarySamples(from, to time.Time) ([]Metrics, error) {
+	if historyDB != nil {
+		return historyDB.Between(from, to)
+	}
+	samples := history.Since(from)
+	end := len(samples)
+	for end > 0 && !samples[end-1].Timestamp.Before(to) {
+		end--
+	}
+	return samples[:end], nil
+}
+
+// summarize averages the samples and adds the traffic up from the rates,
+// leaving out gaps longer than summaryMaxGap and the readings of collectors
+// that failed.
+func summarize(samples []Metrics) DailySummary {
+	s := DailySummary{Timestamp: time.Now().UTC(), MachineID: machineID(), Hostname: hostname(), Samples: len(samples)}
+	var gpu SummaryStat
+	var cpuSamples, ramSamples, gpuSamples int
+	var sent, recv, on float64
+	for i, m := range samples {
+		if _, failed := m.Errors["cpu"]; !failed {
+			cpuSamples++
+			s.CPU.add(m.CPU, cpuSamples)
+		}
+		if _, failed := m.Errors["memory"]; !failed {
+			ramSamples++
+			s.RAM.add(m.RAM, ramSamples)
+		}
+		if m.UptimeSec > 0 {
+			s.UptimeSec = m.UptimeSec
+		}
+		if m.GPU >= 0 {
+			gpuSamples++
+			gpu.add(m.GPU, gpuSamples)
+		}
+		if i == 0 {
+			continue
+		}
+		if dt := m.Timestamp.Sub(samples[i-1].Timestamp); dt > 0 && dt <= summaryMaxGap {
+			sec := dt.Seconds()
+			if _, failed := m.Errors["network"]; !failed {
+				sent += m.NetSentBps * sec
+				recv += m.NetRecvBps * sec
+			}
+			on += sec
+		}
+	}
+	if gpuSamples > 0 {
+		s.GPU = &gpu
+	}
+	s.NetSentBytes, s.NetRecvBytes, s.OnSec = uint64(sent), uint64(recv), uint64(on)
+	return s
+}
+
+func summaryText(s DailySummary) string {
+	text := tr("CPU %.0f%% avg, %.0f%% peak · RAM %.0f%% avg, %.0f%% peak", s.CPU.Avg, s.CPU.Peak, s.RAM.Avg, s.RAM.Peak)
+	if s.GPU != nil {
+		text += "\n" + tr("GPU %.0f%% avg, %.0f%% peak", s.GPU.Avg, s.GPU.Peak)
+	}
+	text += "\n" + tr("↓ %s ↑ %s · %d alerts · on for %s", formatBytes(s.NetRecvBytes), formatBytes(s.NetSentBytes), s.Alerts, formatUptime(s.OnSec))
+	return text
+}
+
+func formatBytes(n uint64) string {
+	return strings.TrimSuffix(formatRate(float64(n)), "/s")
+}
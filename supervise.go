@@ -0,0 +1,119 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"os"
+	"path/filepath"
+	"runtime/debug"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+)
+
+var (
+	crashReports   = getEnv("M_CRASH_REPORTS", "1") == "1"
+	crashReportDir = filepath.Join(defaultDataDir(), "crashes")
+)
+
+const keepCrashReports = 10
+
+// supervise runs f and, when it panics, starts it again after a delay that
+// doubles up to a minute, so one failing component does not take the tray
+// down with it. It returns once f returns normally.
+func supervise(name string, f func()) {
+	delay := time.Second
+	for {
+		start := time.Now()
+		if !runRecovered(name, f) {
+			return
+		}
+		if time.Since(start) > time.Minute {
+			delay = time.Second
+		}
+		slog.Warn("Restarting after a panic", "component", name, "in", delay)
+		time.Sleep(delay)
+		delay = min(2*delay, time.Minute)
+	}
+}
+
+func runRecovered(name string, f func()) (panicked bool) {
+	defer func() {
+		if r := recover(); r != nil {
+			panicked = true
+			reportPanic(name, r, debug.Stack())
+		}
+	}()
+	f()
+	return false
+}
+
+// runCollector runs part of one collector of collectMetrics. A panic only
+// loses that collector's readings for this tick and is returned as an error.
+func runCollector(name string, f func()) (err error) {
+	defer func() {
+		if r := recover(); r != nil {
+			reportPanic("collector "+name, r, debug.Stack())
+			err = fmt.Errorf("panic: %v", r)
+		}
+	}()
+	f()
+	return nil
+}
+
+// panicked counts the panics of each component. The stack is logged and a
+// crash report written only for the first, as a collector that keeps failing
+// would otherwise do so on every tick.
+var panicked = struct {
+	sync.Mutex
+	counts map[string]int
+}{counts: map[string]int{}}
+
+func reportPanic(component string, r any, stack []byte) {
+	panicked.Lock()
+	panicked.counts[component]++
+	n := panicked.counts[component]
+	panicked.Unlock()
+	if n > 1 {
+		slog.Error("Panic", "component", component, "panic", r, "count", n, "event", evtCrash)
+		return
+	}
+	slog.Error("Panic", "component", component, "panic", r, "stack", string(stack), "event", evtCrash)
+	if !crashReports {
+		return
+	}
+	path, err := writeCrashReport(component, r, stack)
+	if err != nil {
+		slog.Error("Crash report failed", "err", err)
+		return
+	}
+	slog.Info("Crash report written", "path", path)
+}
+
+// writeCrashReport saves what a bug report needs: the version, the system
+// and the stack. Only the newest keepCrashReports files are kept.
+func writeCrashReport(component string, r any, stack []byte) (string, error) {
+	if err := os.MkdirAll(crashReportDir, 0o700); err != nil {
+		return "", err
+	}
+	now := time.Now()
+	path := filepath.Join(crashReportDir, "crash-"+now.Format("20060102-150405")+".txt")
+	var b strings.Builder
+	fmt.Fprintf(&b, "Time: %s\r\nComponent: %s\r\nPanic: %v\r\n", now.Format(time.RFC3339), component, r)
+	for _, f := range aboutFields() {
+		fmt.Fprintf(&b, "%s: %s\r\n", f.Name, f.Value)
+	}
+	fmt.Fprintf(&b, "\r\n%s", stack)
+	if err := os.WriteFile(path, []byte(b.String()), 0o600); err != nil {
+		return "", err
+	}
+
+	old, _ := filepath.Glob(filepath.Join(crashReportDir, "crash-*.txt"))
+	sort.Strings(old)
+	for len(old) > keepCrashReports {
+		os.Remove(old[0])
+		old = old[1:]
+	}
+	return path, nil
+}
@@ -0,0 +1,26 @@
+package main
+
+import (
+	"os"
+	"strconv"
+	"strings"
+)
+
+// readSysfs returns the trimmed contents of a /proc or /sys file, or ""
+// when it cannot be read.
+func readSysfs(path string) string {
+	b, err := os.ReadFile(path)
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(b))
+}
+
+// readSysfsInt reads a file holding a single integer, or -1.
+func readSysfsInt(path string) int64 {
+	n, err := strconv.ParseInt(readSysfs(path), 10, 64)
+	if err != nil {
+		return -1
+	}
+	return n
+}
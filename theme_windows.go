@@ -0,0 +1,59 @@
+package main
+
+import (
+	_ "embed"
+	"log/slog"
+
+	"github.com/getlantern/systray"
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/registry"
+)
+
+// iconLightData is app.ico with its lightness flipped, for light taskbars.
+//
+//go:embed app-light.ico
+var iconLightData []byte
+
+const personalizeKey = `Software\Microsoft\Windows\CurrentVersion\Themes\Personalize`
+
+// lightTaskbar reports whether the taskbar uses the light theme. Windows
+// versions without the setting always have a dark taskbar.
+func lightTaskbar() bool {
+	k, err := registry.OpenKey(registry.CURRENT_USER, personalizeKey, registry.QUERY_VALUE)
+	if err != nil {
+		return false
+	}
+	defer k.Close()
+	v, _, err := k.GetIntegerValue("SystemUsesLightTheme")
+	return err == nil && v == 1
+}
+
+// setAppIcon shows the static icon matching the taskbar theme.
+func setAppIcon() {
+	if lightTaskbar() {
+		systray.SetIcon(iconLightData)
+	} else {
+		systray.SetIcon(iconData)
+	}
+}
+
+// watchTheme swaps the icon whenever the personalization settings change.
+// Generated icons have their own background and are left alone.
+func watchTheme() {
+	if generatedTrayIcon() {
+		return
+	}
+	k, err := registry.OpenKey(registry.CURRENT_USER, personalizeKey, registry.NOTIFY)
+	if err != nil {
+		slog.Warn("Theme changes will not be followed", "err", err)
+		return
+	}
+	defer k.Close()
+	for {
+		if err := windows.RegNotifyChangeKeyValue(windows.Handle(k), false, windows.REG_NOTIFY_CHANGE_LAST_SET, 0, false); err != nil {
+			slog.Warn("Theme watch failed", "err", err)
+			return
+		}
+		setAppIcon()
+	}
+}
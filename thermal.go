@@ -0,0 +1,67 @@
+package main
+
+import (
+	"fmt"
+	"time"
+)
+
+// The throttling heuristic: under sustained load a healthy CPU runs at or
+// above its base clock and a GPU close to its boost clock, so clocks well
+// below that while busy usually mean the chip is backing off to cool down.
+const (
+	throttleLoadPercent = 80
+	cpuThrottleRatio    = 0.8
+	gpuThrottleRatio    = 0.7
+)
+
+func cpuThrottled(m Metrics) bool {
+	return m.CPU >= throttleLoadPercent && m.CPUBaseFreqMHz > 0 && float64(m.CPUFreqMHz) < cpuThrottleRatio*float64(m.CPUBaseFreqMHz)
+}
+
+func gpuThrottled(util, clock, maxClock float64) bool {
+	return util >= throttleLoadPercent && clock >= 0 && maxClock > 0 && clock < gpuThrottleRatio*maxClock
+}
+
+// thermalRules builds the rules turned on by M_CPU_TEMP_ALERT and
+// M_GPU_TEMP_ALERT (°C) and M_THROTTLE_ALERT.
+func thermalRules() []alertRule {
+	var rules []alertRule
+	for _, t := range []struct{ name, key, metric string }{
+		{"cpu-temperature", "M_CPU_TEMP_ALERT", "cpu_temperature_celsius"},
+		{"gpu-temperature", "M_GPU_TEMP_ALERT", "gpu_temperature_celsius"},
+	} {
+		if limit := getInt(t.key, 0); limit > 0 {
+			rules = append(rules, alertRule{
+				Name: t.name, Kind: "temperature", Metric: t.metric, Op: ">=", Threshold: float64(limit),
+				For: 30 * time.Second, Hysteresis: 5, Cooldown: alertCooldown,
+			})
+		}
+	}
+	if getEnv("M_THROTTLE_ALERT", "") == "1" {
+		for _, name := range []string{"cpu", "gpu"} {
+			rules = append(rules, alertRule{
+				Name: name + "-throttling", Kind: "throttling", Metric: name + "_throttling", Op: ">=", Threshold: 1,
+				For: time.Minute, Cooldown: alertCooldown,
+			})
+		}
+	}
+	return rules
+}
+
+// describeThermal words temperature and throttling alerts around the chip.
+func describeThermal(r alertRule, ev AlertEvent) string {
+	chip := "CPU"
+	if r.Metric == "gpu_temperature_celsius" || r.Metric == "gpu_throttling" {
+		chip = "GPU"
+	}
+	switch {
+	case r.Kind == "throttling" && ev.State == "resolved":
+		return chip + " clocks are back to normal"
+	case r.Kind == "throttling":
+		return fmt.Sprintf("%s is throttling: clocks dropped while under load for %s, check its cooling", chip, r.For)
+	case ev.State == "resolved":
+		return fmt.Sprintf("%s cooled down to %s °C", chip, formatAlertValue(ev.Value))
+	default:
+		return fmt.Sprintf("%s is at %s °C (limit %s °C)", chip, formatAlertValue(ev.Value), formatAlertValue(r.Threshold))
+	}
+}
@@ -0,0 +1,29 @@
+//go:build !windows
+
+package main
+
+import (
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/host"
+)
+
+// cpuSensors are the sensor names CPU temperature drivers use: coretemp and
+// k10temp on x86, cpu_thermal and soc_thermal on ARM boards.
+var cpuSensors = []string{"coretemp", "k10temp", "zenpower", "x86_pkg_temp", "cpu", "soc_thermal", "tctl"}
+
+// getCPUTemperature returns the hottest CPU sensor in °C, or -1.
+func getCPUTemperature() float64 {
+	temps, _ := host.SensorsTemperatures()
+	hottest := -1.0
+	for _, t := range temps {
+		key := strings.ToLower(t.SensorKey)
+		for _, s := range cpuSensors {
+			if strings.Contains(key, s) && t.Temperature > 0 {
+				hottest = max(hottest, t.Temperature)
+				break
+			}
+		}
+	}
+	return hottest
+}
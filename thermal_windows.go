@@ -0,0 +1,25 @@
+package main
+
+import "github.com/yusufpapurcu/wmi"
+
+type msAcpiThermalZone struct {
+	CurrentTemperature uint32
+}
+
+// getCPUTemperature returns the hottest ACPI thermal zone in °C, or -1.
+// The zones need admin rights and many desktop boards do not expose them;
+// laptops usually do.
+func getCPUTemperature() float64 {
+	var dst []msAcpiThermalZone
+	if err := wmi.QueryNamespace("SELECT CurrentTemperature FROM MSAcpi_ThermalZoneTemperature", &dst, `root\wmi`); err != nil {
+		return -1
+	}
+	hottest := -1.0
+	for _, z := range dst {
+		// Tenths of a kelvin; zero means the zone has no reading.
+		if z.CurrentTemperature > 0 {
+			hottest = max(hottest, float64(z.CurrentTemperature)/10-273.15)
+		}
+	}
+	return hottest
+}
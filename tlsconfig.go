@@ -0,0 +1,94 @@
+package main
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"log/slog"
+	"os"
+	"sync/atomic"
+)
+
+// tlsConfig is replaced as a whole when a profile switch changes the
+// settings, never modified in place, since MQTT and gRPC read it from their
+// own goroutines.
+var tlsConfig atomic.Pointer[tls.Config]
+
+// initTLS applies the M_TLS_* settings to the connections to the server:
+// HTTP, MQTT and gRPC. Requests to anywhere else (update checks, webhooks,
+// the speed test) keep the default TLS settings.
+func initTLS() error {
+	cfg, err := loadTLSConfig()
+	if err != nil {
+		return err
+	}
+	tlsConfig.Store(cfg)
+	if old := serverClient.Swap(newServerClient(cfg)); old != nil {
+		old.CloseIdleConnections()
+	}
+	return nil
+}
+
+func loadTLSConfig() (*tls.Config, error) {
+	cfg := &tls.Config{}
+	if tlsCAFile != "" {
+		pool, err := x509.SystemCertPool()
+		if err != nil {
+			pool = x509.NewCertPool()
+		}
+		pem, err := os.ReadFile(tlsCAFile)
+		if err != nil {
+			return nil, fmt.Errorf("read CA bundle: %w", err)
+		}
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("no certificates found in %s", tlsCAFile)
+		}
+		cfg.RootCAs = pool
+	}
+
+	switch tlsMinVersion {
+	case "", "1.2":
+		cfg.MinVersion = tls.VersionTLS12
+	case "1.3":
+		cfg.MinVersion = tls.VersionTLS13
+	default:
+		return nil, fmt.Errorf("unsupported M_TLS_MIN_VERSION %q", tlsMinVersion)
+	}
+
+	cfg.ServerName = tlsServerName
+
+	// The client certificate identifies this machine, so it must only reach
+	// the server: tlsConfig is used by serverClient, MQTT and gRPC alone.
+	switch {
+	case tlsCertFile != "":
+		cert, err := tls.LoadX509KeyPair(tlsCertFile, tlsKeyFile)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		cfg.Certificates = []tls.Certificate{cert}
+	case tlsCertSubject != "":
+		cert, err := loadStoreCertificate(tlsCertSubject)
+		if err != nil {
+			return nil, fmt.Errorf("load client certificate: %w", err)
+		}
+		slog.Info("Using client certificate from the certificate store", "subject", cert.Leaf.Subject.CommonName)
+		cfg.Certificates = []tls.Certificate{cert}
+	}
+
+	if tlsInsecure {
+		slog.Warn("M_TLS_INSECURE is set, server certificates are NOT verified. Use only for lab servers.")
+		cfg.InsecureSkipVerify = true
+	}
+
+	return cfg, nil
+}
+
+// clientTLS returns the shared settings for a connection to host, keeping
+// an explicit SNI override if one was configured.
+func clientTLS(host string) *tls.Config {
+	c := tlsConfig.Load().Clone()
+	if c.ServerName == "" {
+		c.ServerName = host
+	}
+	return c
+}
@@ -0,0 +1,42 @@
+package main
+
+import (
+	"log/slog"
+	"os/exec"
+	"strings"
+)
+
+// powershellAppID is PowerShell's registered AppUserModelID; toasts need one
+// and an unpackaged tray app has none of its own.
+const powershellAppID = `{1AC14E77-02E7-4E5D-B744-2EB1AE5198B7}\WindowsPowerShell\v1.0\powershell.exe`
+
+const toastScript = `
+[Windows.UI.Notifications.ToastNotificationManager, Windows.UI.Notifications, ContentType = WindowsRuntime] | Out-Null
+$xml = [Windows.UI.Notifications.ToastNotificationManager]::GetTemplateContent([Windows.UI.Notifications.ToastTemplateType]::ToastText02)
+$text = $xml.GetElementsByTagName('text')
+$text.Item(0).AppendChild($xml.CreateTextNode($env:TOAST_TITLE)) | Out-Null
+$text.Item(1).AppendChild($xml.CreateTextNode($env:TOAST_MESSAGE)) | Out-Null
+[Windows.UI.Notifications.ToastNotificationManager]::CreateToastNotifier($env:TOAST_APP).Show([Windows.UI.Notifications.ToastNotification]::new($xml))
+`
+
+// showToast pops a Windows notification without blocking the caller. The
+// text is passed through the environment so it needs no escaping. Without a
+// tray there may be no desktop to show it on, so it is only logged.
+func showToast(title, message string) {
+	if headless {
+		slog.Info("Notification", "title", title, "message", message)
+		return
+	}
+	cmd := exec.Command("powershell.exe", "-NoProfile", "-NonInteractive", "-Command", toastScript)
+	hideWindow(cmd)
+	cmd.Env = append(cmd.Environ(),
+		"TOAST_TITLE="+title,
+		"TOAST_MESSAGE="+strings.ReplaceAll(message, "\n", " "),
+		"TOAST_APP="+powershellAppID,
+	)
+	go func() {
+		if out, err := cmd.CombinedOutput(); err != nil {
+			slog.Warn("Toast failed", "err", err, "output", string(out))
+		}
+	}()
+}
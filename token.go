@@ -0,0 +1,83 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"log/slog"
+	"net/url"
+	"strings"
+	"sync"
+	"time"
+)
+
+// sessionTokenEndpoint is exchanged for a short-lived token, authenticated
+// with the agent secret. Its response is {"token": "...", "expiresIn": 900}.
+const sessionTokenEndpoint = "/pc-stats/token"
+
+type session struct {
+	mu        sync.Mutex
+	token     string
+	refreshAt time.Time
+}
+
+// sessions holds one token per API base URL.
+var sessions sync.Map
+
+func apiBase(u *url.URL) string {
+	p := u.Path
+	if i := strings.LastIndex(p, "/pc-stats/"); i >= 0 {
+		p = p[:i]
+	}
+	return u.Scheme + "://" + u.Host + p
+}
+
+func isTokenEndpoint(u *url.URL) bool {
+	return strings.HasSuffix(u.Path, sessionTokenEndpoint)
+}
+
+// sessionToken returns the cached token for u's API, exchanging secret for
+// a new one once 80% of the previous one's lifetime has passed.
+func sessionToken(u *url.URL, secret string) (string, error) {
+	base := apiBase(u)
+	v, _ := sessions.LoadOrStore(base, &session{})
+	s := v.(*session)
+
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.token != "" && time.Now().Before(s.refreshAt) {
+		return s.token, nil
+	}
+
+	data, err := exchange(base+sessionTokenEndpoint, secret, "application/json", "", []byte("{}"))
+	if err != nil {
+		return "", err
+	}
+	var resp struct {
+		Token     string `json:"token"`
+		ExpiresIn int    `json:"expiresIn"`
+	}
+	if err := json.Unmarshal(data, &resp); err != nil {
+		return "", fmt.Errorf("decode: %w", err)
+	}
+	if resp.Token == "" || resp.ExpiresIn <= 0 {
+		return "", fmt.Errorf("server returned no token")
+	}
+
+	lifetime := time.Duration(resp.ExpiresIn) * time.Second
+	if s.token == "" {
+		slog.Info("Got session token", "host", u.Host, "validFor", lifetime)
+	}
+	s.token = resp.Token
+	s.refreshAt = time.Now().Add(lifetime * 4 / 5)
+	return s.token, nil
+}
+
+func dropSessionToken(u *url.URL) {
+	if v, ok := sessions.Load(apiBase(u)); ok {
+		s := v.(*session)
+		s.mu.Lock()
+		s.token = ""
+		s.mu.Unlock()
+	}
+}
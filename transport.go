@@ -0,0 +1,455 @@
+package main
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/tls"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log/slog"
+	"net"
+	"net/http"
+	"net/url"
+	"strings"
+	"sync"
+	"sync/atomic"
+	"time"
+)
+
+const requestTimeout = 15 * time.Second
+
+var httpTransport = &http.Transport{
+	Proxy: http.ProxyFromEnvironment,
+	DialContext: (&net.Dialer{
+		Timeout:   5 * time.Second,
+		KeepAlive: 10 * time.Second,
+	}).DialContext,
+	TLSHandshakeTimeout:   5 * time.Second,
+	ResponseHeaderTimeout: 10 * time.Second,
+	IdleConnTimeout:       30 * time.Second,
+	MaxIdleConns:          4,
+}
+
+var httpClient = &http.Client{
+	Timeout:   requestTimeout,
+	Transport: httpTransport,
+}
+
+// serverClient talks to the primary server with the M_TLS_* settings, over
+// its own copy of httpTransport, which carries the proxy settings. initTLS
+// swaps in a new one when the settings change.
+var serverClient atomic.Pointer[http.Client]
+
+func newServerClient(cfg *tls.Config) *http.Client {
+	t := httpTransport.Clone()
+	t.TLSClientConfig = cfg
+	return &http.Client{Timeout: requestTimeout, Transport: t}
+}
+
+// clientFor picks serverClient for the primary server's host and httpClient
+// for everything else, the extra APIs included.
+func clientFor(host string) *http.Client {
+	if u, err := url.Parse(apiURL); err == nil && u.Host == host {
+		if c := serverClient.Load(); c != nil {
+			return c
+		}
+	}
+	return httpClient
+}
+
+type statusError struct {
+	Code int
+}
+
+func (e *statusError) Error() string {
+	return fmt.Sprintf("unexpected status: %d", e.Code)
+}
+
+func apiEndpoint(name string) string {
+	return strings.TrimRight(apiURL, "/") + "/pc-stats/" + name
+}
+
+// apiTarget is an additional HTTP API that receives everything the primary
+// transport does. Its secret may be given as the URL's user info.
+type apiTarget struct {
+	name   string
+	base   string
+	secret string
+}
+
+func parseAPITargets(urls []string) ([]apiTarget, error) {
+	var targets []apiTarget
+	for _, raw := range urls {
+		u, err := url.Parse(raw)
+		if err != nil {
+			return nil, fmt.Errorf("parse %q: %w", raw, err)
+		}
+		t := apiTarget{name: strings.ReplaceAll(u.Host, ":", "_"), secret: secret}
+		if u.User != nil {
+			t.secret = u.User.Username()
+			u.User = nil
+		}
+		t.base = strings.TrimRight(u.String(), "/")
+		targets = append(targets, t)
+	}
+	return targets, nil
+}
+
+func (t apiTarget) endpoint(name string) string {
+	return t.base + "/pc-stats/" + name
+}
+
+func (t apiTarget) sendMetrics(m Metrics) error {
+	_, err := postMetrics(t.endpoint("report"), t.secret, m)
+	return err
+}
+
+// Sink is a transport to the primary server, picked with M_TRANSPORT.
+type Sink interface {
+	// Send delivers one message of the given kind ("report", "smart", ...)
+	// and returns the server's reply. Only HTTP has one; the others return
+	// nil.
+	Send(kind string, v any) ([]byte, error)
+	// Reset drops the open connections so the next Send dials afresh.
+	Reset()
+	// Close ends the session when the agent stops.
+	Close()
+	// Status describes where messages are going, for the log.
+	Status() string
+}
+
+// httpSink posts to M_API_URL.
+type httpSink struct{}
+
+func (httpSink) Send(kind string, v any) ([]byte, error) {
+	if m, ok := v.(Metrics); ok {
+		return postMetrics(apiEndpoint(kind), secret, m)
+	}
+	return postJSON(apiEndpoint(kind), secret, v)
+}
+
+// Reset also drops the extra APIs' connections, which share httpClient.
+func (httpSink) Reset() {
+	if c := serverClient.Load(); c != nil {
+		c.CloseIdleConnections()
+	}
+	httpClient.CloseIdleConnections()
+}
+
+func (s httpSink) Close() { s.Reset() }
+
+func (httpSink) Status() string { return apiEndpoint("report") }
+
+// sink is nil with M_TRANSPORT=none. mqttSink is also kept for the Home
+// Assistant discovery, which only MQTT has.
+var (
+	sink       Sink = httpSink{}
+	mqttSink   *mqttClient
+	apiTargets []apiTarget
+)
+
+func initTransport() error {
+	if err := initProxy(); err != nil {
+		return err
+	}
+	if err := initTLS(); err != nil {
+		return err
+	}
+	if err := initPayloadKey(); err != nil {
+		return err
+	}
+
+	targets, err := parseAPITargets(extraAPIURLs)
+	if err != nil {
+		return err
+	}
+	apiTargets = targets
+
+	switch transportName {
+	case "http":
+		sink = httpSink{}
+		return nil
+	case "none":
+		sink = nil
+		return nil
+	case "mqtt":
+		c, err := newMQTTClient(mqttURL, mqttTopic, mqttQoS, mqttUser, mqttPassword)
+		if err != nil {
+			return err
+		}
+		mqttSink, sink = c, c
+		return nil
+	case "grpc":
+		c, err := newGRPCClient(grpcAddr, grpcTLS)
+		if err != nil {
+			return err
+		}
+		sink = c
+		return nil
+	}
+	return fmt.Errorf("unknown transport %q", transportName)
+}
+
+const (
+	fallbackAfter = 3
+	fallbackProbe = 5 * time.Minute
+)
+
+// httpFallback tracks consecutive MQTT failures. Once fallbackAfter is
+// reached, and M_API_URL is set, messages go over HTTPS instead, with MQTT
+// retried every fallbackProbe.
+var httpFallback struct {
+	sync.Mutex
+	failures int
+	until    time.Time
+}
+
+func fallbackActive() bool {
+	httpFallback.Lock()
+	defer httpFallback.Unlock()
+	return time.Now().Before(httpFallback.until)
+}
+
+// recordMQTT notes the outcome of an MQTT publish and reports whether the
+// message should be retried over HTTPS.
+func recordMQTT(err error) bool {
+	httpFallback.Lock()
+	defer httpFallback.Unlock()
+
+	if err == nil {
+		if httpFallback.failures >= fallbackAfter {
+			slog.Info("MQTT is back, leaving HTTPS fallback")
+		}
+		httpFallback.failures = 0
+		return false
+	}
+	if apiURL == "" {
+		return false
+	}
+
+	httpFallback.failures++
+	if httpFallback.failures < fallbackAfter {
+		return false
+	}
+	if httpFallback.failures == fallbackAfter {
+		slog.Warn("MQTT failing, falling back to HTTPS", "err", err, "to", apiEndpoint("report"))
+	}
+	httpFallback.until = time.Now().Add(fallbackProbe)
+	return true
+}
+
+func resetFallback() {
+	httpFallback.Lock()
+	httpFallback.failures = 0
+	httpFallback.until = time.Time{}
+	httpFallback.Unlock()
+}
+
+// resetTransport drops every open connection so the next message dials
+// afresh, and gives up an active MQTT to HTTPS fallback.
+func resetTransport() {
+	if sink != nil {
+		sink.Reset()
+	}
+	if _, ok := sink.(httpSink); !ok {
+		// The extra APIs and the MQTT fallback still pool HTTPS connections.
+		httpSink{}.Reset()
+	}
+}
+
+// publish sends one message of the given kind ("report", "smart", ...) over
+// the configured transport, and in the background to every extra API.
+func publish(kind string, v any) error {
+	if paused.Load() || inQuietHours(time.Now()) {
+		return nil
+	}
+	fanOut(kind, v)
+	return publishPrimary(kind, v)
+}
+
+func fanOut(kind string, v any) {
+	for _, t := range apiTargets {
+		go func() {
+			if _, err := postJSON(t.endpoint(kind), t.secret, v); err != nil {
+				slog.Warn("Publish failed", "kind", kind, "to", t.base, "err", err)
+			}
+		}()
+	}
+}
+
+func publishPrimary(kind string, v any) error {
+	_, err := request(kind, v)
+	return err
+}
+
+// request sends one message over the primary transport and returns the
+// server's reply. Only the HTTP transport has one; the others return nil.
+func request(kind string, v any) ([]byte, error) {
+	if sink == nil {
+		return nil, nil
+	}
+	if !transmitLimiter.Allow() {
+		return nil, errRateLimited
+	}
+	return sink.Send(kind, v)
+}
+
+// lastLatency holds the round trip of the last answered report, in
+// nanoseconds, or 0 before the first one.
+var lastLatency atomic.Int64
+
+func latencyMs() float64 {
+	d := lastLatency.Load()
+	if d == 0 {
+		return -1
+	}
+	return float64(d) / float64(time.Millisecond)
+}
+
+// timedReport sends a report over the primary transport and records how long
+// the server took to take it.
+func timedReport(v any) ([]byte, error) {
+	start := time.Now()
+	reply, err := request("report", v)
+	if err == nil && transportName != "none" {
+		lastLatency.Store(max(int64(time.Since(start)), 1))
+	}
+	return reply, err
+}
+
+// sendMetrics leaves out the extra APIs, which report through their own
+// reporters.
+func sendMetrics(metrics Metrics) error {
+	if deltaMode {
+		return sendDelta(metrics)
+	}
+	reply, err := timedReport(metrics)
+	if err == nil {
+		queueCommands(reply)
+	}
+	return err
+}
+
+func sendDelta(metrics Metrics) error {
+	payload, full := deltas.Encode(metrics)
+	reply, err := timedReport(payload)
+	if err == nil {
+		deltas.Commit(metrics, payload, full)
+		queueCommands(reply)
+	}
+	return err
+}
+
+// noGzip holds the hosts that answered a compressed body with 415.
+var noGzip sync.Map
+
+func postJSON(endpoint, token string, v any) ([]byte, error) {
+	body, err := json.Marshal(v)
+	if err != nil {
+		return nil, fmt.Errorf("marshal: %w", err)
+	}
+
+	return postBody(endpoint, token, "application/json", body)
+}
+
+func isUnsupportedMedia(err error) bool {
+	var se *statusError
+	return errors.As(err, &se) && se.Code == http.StatusUnsupportedMediaType
+}
+
+func postBody(endpoint, token, contentType string, body []byte) ([]byte, error) {
+	compress := compressBodies
+	resp, err := post(endpoint, token, contentType, body, compress)
+	if compress && isUnsupportedMedia(err) {
+		u, _ := url.Parse(endpoint)
+		if _, seen := noGzip.LoadOrStore(u.Host, true); !seen {
+			slog.Info("Server does not accept gzip bodies, sending uncompressed", "host", u.Host)
+		}
+		resp, err = post(endpoint, token, contentType, body, false)
+	}
+	return resp, err
+}
+
+// post compresses the body before sealing it, since ciphertext does not
+// compress; a sealed body then names its encoding inside the envelope
+// rather than in Content-Encoding.
+func post(endpoint, token, contentType string, body []byte, compress bool) ([]byte, error) {
+	var encoding string
+	if compress {
+		u, _ := url.Parse(endpoint)
+		if _, skip := noGzip.Load(u.Host); !skip {
+			var buf bytes.Buffer
+			zw := gzip.NewWriter(&buf)
+			zw.Write(body)
+			if err := zw.Close(); err != nil {
+				return nil, fmt.Errorf("compress: %w", err)
+			}
+			body = buf.Bytes()
+			encoding = "gzip"
+		}
+	}
+
+	contentType, body, err := seal(contentType, encoding, body)
+	if err != nil {
+		return nil, err
+	}
+	if payloadKey != nil {
+		encoding = ""
+	}
+	return exchange(endpoint, token, contentType, encoding, body)
+}
+
+// exchange sends one request and returns the response body, repeating it
+// once when the server asked to authenticate again.
+func exchange(endpoint, token, contentType, encoding string, body []byte) ([]byte, error) {
+	defer lockAuth(endpoint, token)()
+	resp, err := send(endpoint, token, contentType, encoding, body)
+	if mustRetryAuth(endpoint, err) {
+		resp, err = send(endpoint, token, contentType, encoding, body)
+	}
+	return resp, err
+}
+
+func send(endpoint, token, contentType, encoding string, body []byte) ([]byte, error) {
+	req, err := http.NewRequest("POST", endpoint, bytes.NewReader(body))
+	if err != nil {
+		return nil, fmt.Errorf("new request: %w", err)
+	}
+
+	req.Header.Set("Content-Type", contentType)
+	if encoding != "" {
+		req.Header.Set("Content-Encoding", encoding)
+	}
+	if err := authorize(req, token); err != nil {
+		return nil, err
+	}
+	req.Header.Set("User-Agent", "go-win-monitor/"+version)
+	for k, v := range extraHeaders {
+		req.Header.Set(k, v)
+	}
+
+	client := clientFor(req.URL.Host)
+	resp, err := client.Do(req)
+	if err != nil {
+		// The pooled connection may be half-open (sleep/resume, NAT timeout);
+		// make sure the next attempt dials a fresh one.
+		client.CloseIdleConnections()
+		return nil, fmt.Errorf("request: %w", err)
+	}
+	defer resp.Body.Close()
+
+	noteAcceptPost(req.URL.Host, resp.Header.Get("Accept-Post"))
+	noteNonce(req.URL.Host, resp.Header)
+
+	if resp.StatusCode != http.StatusOK {
+		return nil, &statusError{Code: resp.StatusCode}
+	}
+
+	data, err := io.ReadAll(io.LimitReader(resp.Body, 1<<20))
+	if err != nil {
+		return nil, fmt.Errorf("read response: %w", err)
+	}
+	return data, nil
+}
@@ -0,0 +1,117 @@
+package main
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"slices"
+	"sync"
+	"testing"
+	"time"
+)
+
+// hmacServer answers like a server in hmac mode: a 401 with a fresh nonce
+// until a request is signed with secret, then 200. It sends what it accepted
+// on got.
+func hmacServer(t *testing.T, secret string, got chan<- string) *httptest.Server {
+	t.Helper()
+	var mu sync.Mutex
+	var nonce string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		mu.Lock()
+		defer mu.Unlock()
+		mac := hmac.New(sha256.New, []byte(secret))
+		mac.Write([]byte(nonce))
+		signed := `HMAC nonce="` + nonce + `", signature="` + hex.EncodeToString(mac.Sum(nil)) + `"`
+		if nonce == "" || r.Header.Get("Authorization") != signed {
+			nonce = fmt.Sprintf("n%d", time.Now().UnixNano())
+			w.Header().Set("WWW-Authenticate", `HMAC nonce="`+nonce+`"`)
+			w.WriteHeader(http.StatusUnauthorized)
+			return
+		}
+		nonce = ""
+		body, _ := io.ReadAll(r.Body)
+		got <- r.URL.Path + " " + string(body)
+	}))
+	t.Cleanup(srv.Close)
+	return srv
+}
+
+func TestFanOutRetriesWithEachTargetsSecret(t *testing.T) {
+	got := make(chan string, 2)
+	a := hmacServer(t, "secret-a", got)
+	b := hmacServer(t, "secret-b", got)
+
+	saved := apiTargets
+	t.Cleanup(func() { apiTargets = saved })
+	apiTargets = []apiTarget{{name: "a", base: a.URL, secret: "secret-a"}, {name: "b", base: b.URL, secret: "secret-b"}}
+
+	fanOut("hello", map[string]int{"n": 1})
+	for range apiTargets {
+		select {
+		case s := <-got:
+			if want := `/pc-stats/hello {"n":1}`; s != want {
+				t.Fatalf("server got %q, want %q", s, want)
+			}
+		case <-time.After(5 * time.Second):
+			t.Fatal("a target never accepted the message")
+		}
+	}
+}
+
+func TestPostBodyRetriesUncompressed(t *testing.T) {
+	var encodings []string
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		encodings = append(encodings, r.Header.Get("Content-Encoding"))
+		if r.Header.Get("Content-Encoding") == "gzip" {
+			w.WriteHeader(http.StatusUnsupportedMediaType)
+			return
+		}
+		if body, _ := io.ReadAll(r.Body); string(body) != `{"n":1}` {
+			t.Errorf("server got %q", body)
+		}
+	}))
+	defer srv.Close()
+	u, _ := url.Parse(srv.URL)
+	t.Cleanup(func() { noGzip.Delete(u.Host) })
+
+	saved := compressBodies
+	t.Cleanup(func() { compressBodies = saved })
+	compressBodies = true
+
+	for range 2 {
+		if _, err := postBody(srv.URL+"/pc-stats/report", "", "application/json", []byte(`{"n":1}`)); err != nil {
+			t.Fatalf("post: %v", err)
+		}
+	}
+	// The 415 is remembered, so the second post goes out uncompressed.
+	if want := []string{"gzip", "", ""}; !slices.Equal(encodings, want) {
+		t.Fatalf("encodings %q, want %q", encodings, want)
+	}
+}
+
+func TestConcurrentPostsTakeTurnsWithTheNonce(t *testing.T) {
+	const posts = 8
+	got := make(chan string, posts)
+	srv := hmacServer(t, "secret", got)
+	u, _ := url.Parse(srv.URL)
+	t.Cleanup(func() { nonces.Delete(u.Host) })
+
+	var wg sync.WaitGroup
+	for i := range posts {
+		wg.Go(func() {
+			if _, err := postJSON(srv.URL+"/pc-stats/report", "secret", map[string]int{"n": i}); err != nil {
+				t.Errorf("post %d: %v", i, err)
+			}
+		})
+	}
+	wg.Wait()
+	if len(got) != posts {
+		t.Fatalf("server accepted %d posts, want %d", len(got), posts)
+	}
+}
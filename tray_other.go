@@ -0,0 +1,30 @@
+//go:build !windows
+
+package main
+
+import "log/slog"
+
+// The tray is Windows-only, as the tray library needs cgo and a desktop
+// session elsewhere. Without it the agent always runs headless and these
+// have nothing to update.
+const trayAvailable = false
+
+func runTray() {}
+
+func setStatus(string) {}
+
+func updateMenuMetrics(Metrics) {}
+
+func recordAlert(AlertEvent) {}
+
+func updateIntervalMenu() {}
+
+func updateProfileMenu() {}
+
+func showSecretMenu(bool) {}
+
+func checkForegroundMenu(bool) {}
+
+func offerUpdate(rel *githubRelease) {
+	slog.Info("Update available", "version", rel.TagName)
+}
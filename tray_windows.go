@@ -0,0 +1,422 @@
+package main
+
+import (
+	_ "embed"
+	"fmt"
+	"log/slog"
+	"maps"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/getlantern/systray"
+)
+
+//go:embed app.ico
+var iconData []byte
+
+var (
+	menuForeground *systray.MenuItem
+	menuCPU        *systray.MenuItem
+	menuRAM        *systray.MenuItem
+	menuGPU        *systray.MenuItem
+	menuGPUEnc     *systray.MenuItem
+	menuGPUDec     *systray.MenuItem
+	menuGPUClk     *systray.MenuItem
+	menuGPUMem     *systray.MenuItem
+	menuSwap       *systray.MenuItem
+	menuNet        *systray.MenuItem
+	menuUptime     *systray.MenuItem
+	menuAgent      *systray.MenuItem
+	menuFans       *deviceMenu
+	menuWatch      *systray.MenuItem
+	menuSvc        *systray.MenuItem
+	menuErrors     *systray.MenuItem
+	menuSecret     *systray.MenuItem
+	menuDisks      *deviceMenu
+	menuIfaces     *deviceMenu
+	menuContainers *deviceMenu
+	menuVMs        *deviceMenu
+	menuStatus     *systray.MenuItem
+	menuMu         sync.Mutex
+)
+
+const trayAvailable = true
+
+// runTray shows the tray icon and runs the agent behind it until Quit.
+func runTray() {
+	systray.Run(onReady, onExit)
+}
+
+func onReady() {
+	setAppIcon()
+	go watchTheme()
+	systray.SetTitle("")
+	systray.SetTooltip(tr("Computer Monitor"))
+
+	menuCPU = systray.AddMenuItem("CPU: ---", "")
+	menuRAM = systray.AddMenuItem("RAM: ---", "")
+	menuGPU = systray.AddMenuItem("GPU: ---", "")
+	menuGPUEnc = menuGPU.AddSubMenuItem(tr("Encoder: ---"), "")
+	menuGPUDec = menuGPU.AddSubMenuItem(tr("Decoder: ---"), "")
+	menuGPUClk = menuGPU.AddSubMenuItem(tr("Core clock: ---"), "")
+	menuGPUMem = menuGPU.AddSubMenuItem(tr("Memory clock: ---"), "")
+	menuSwap = systray.AddMenuItem(tr("Pagefile: ---"), "")
+	menuNet = systray.AddMenuItem(tr("Net: ---"), "")
+	menuUptime = systray.AddMenuItem(tr("Uptime: ---"), "")
+	menuAgent = systray.AddMenuItem(tr("Agent: ---"), tr("CPU and memory used by this monitor"))
+	menuFans = newDeviceMenu(tr("Fans"))
+	menuDisks = newDeviceMenu(tr("Disk temperatures"))
+	menuIfaces = newDeviceMenu(tr("Network interfaces"))
+	menuContainers = newDeviceMenu(tr("Containers"))
+	menuVMs = newDeviceMenu(tr("Virtual machines"))
+	menuWatch = systray.AddMenuItem(tr("Processes: ---"), "")
+	if len(watch) == 0 {
+		menuWatch.Hide()
+	}
+	menuSvc = systray.AddMenuItem(tr("Services: ---"), "")
+	if len(services) == 0 {
+		menuSvc.Hide()
+	}
+	menuErrors = systray.AddMenuItem(tr("Collectors failing: ---"), "")
+	menuErrors.Hide()
+	systray.AddSeparator()
+	menuStatus = systray.AddMenuItem(tr("Status: %s", tr("Starting...")), "")
+	menuSecret = systray.AddMenuItem(tr("Enter new secret…"), tr("The server rejected the secret; enter the new one and retry"))
+	menuSecret.Hide()
+	addAlertMenu()
+	systray.AddSeparator()
+	menuForeground = systray.AddMenuItemCheckbox(tr("Report foreground app"), tr("Include the active application name in reports"), reportForeground.Load())
+	mPause := systray.AddMenuItemCheckbox(tr("Pause sending"), tr("Stop sending metrics; the tray keeps updating"), false)
+	mExport := systray.AddMenuItemCheckbox(tr("Export to file"), tr("Append metrics to %s", exportPath), exportEnabled.Load())
+	mOverlay := systray.AddMenuItemCheckbox(tr("Show overlay"), tr("Always-on-top window with the latest readings"), overlayEnabled)
+	mAutostart := systray.AddMenuItemCheckbox(tr("Start with Windows"), tr("Start the agent when you sign in"), autostartEnabled())
+	var mDashboard, mRemoteDashboard *systray.MenuItem
+	if dashboardURL != "" {
+		mRemoteDashboard = systray.AddMenuItem(tr("Open dashboard"), tr("Open %s", dashboardURL))
+	}
+	if dashboard {
+		title := tr("Open dashboard")
+		if mRemoteDashboard != nil {
+			title = tr("Open local dashboard")
+		}
+		mDashboard = systray.AddMenuItem(title, tr("Show live charts in the browser"))
+	}
+	addProfileMenu()
+	addIntervalMenu()
+	addUpdateMenu()
+	mCopy := systray.AddMenuItem(tr("Copy metrics"), tr("Copy the latest metrics to the clipboard"))
+	mReconnect := systray.AddMenuItem(tr("Reconnect now"), tr("Retry the server right away instead of waiting out the backoff"))
+	mInventory := systray.AddMenuItem(tr("Send inventory"), tr("Send the hardware inventory to the server"))
+	mSpeedTest := systray.AddMenuItem(tr("Run speed test"), tr("Measure download, upload and latency and send the result to the server"))
+	mGraphs := systray.AddMenuItem(tr("Show graphs"), tr("Recent CPU, RAM, GPU and network history"))
+	mSettings := systray.AddMenuItem(tr("Settings…"), tr("Edit the server, secret, interval and collectors"))
+	mReload := systray.AddMenuItem(tr("Reload config"), tr("Apply changes to %s", configFilePath()))
+	mLogs := systray.AddMenuItem(tr("View logs"), tr("Recent log lines, to diagnose connection problems"))
+	mAbout := systray.AddMenuItem(tr("About"), tr("Version and build information"))
+	mQuit := systray.AddMenuItem(tr("Quit"), tr("Exit the application"))
+
+	menuCPU.Disable()
+	menuRAM.Disable()
+	menuGPUEnc.Disable()
+	menuGPUDec.Disable()
+	menuGPUClk.Disable()
+	menuGPUMem.Disable()
+	menuSwap.Disable()
+	menuNet.Disable()
+	menuUptime.Disable()
+	menuAgent.Disable()
+	menuWatch.Disable()
+	menuSvc.Disable()
+	menuErrors.Disable()
+	menuStatus.Disable()
+
+	startServices()
+	go run(agentCtx)
+
+	go func() {
+		for range menuForeground.ClickedCh {
+			if menuForeground.Checked() {
+				menuForeground.Uncheck()
+				reportForeground.Store(false)
+			} else {
+				menuForeground.Check()
+				reportForeground.Store(true)
+			}
+		}
+	}()
+
+	go func() {
+		for range mPause.ClickedCh {
+			if mPause.Checked() {
+				mPause.Uncheck()
+				paused.Store(false)
+				slog.Info("Sending resumed")
+				setStatus("Resuming...")
+			} else {
+				mPause.Check()
+				paused.Store(true)
+				slog.Info("Sending paused")
+				setStatus("Paused")
+			}
+		}
+	}()
+
+	if overlayEnabled {
+		setOverlayVisible(true)
+	}
+	go func() {
+		for range mOverlay.ClickedCh {
+			if mOverlay.Checked() {
+				mOverlay.Uncheck()
+				setOverlayVisible(false)
+			} else {
+				mOverlay.Check()
+				setOverlayVisible(true)
+			}
+		}
+	}()
+
+	go func() {
+		for range mAutostart.ClickedCh {
+			on := !mAutostart.Checked()
+			if err := setAutostart(on); err != nil {
+				slog.Error("Start with Windows failed", "err", err)
+				continue
+			}
+			if on {
+				mAutostart.Check()
+			} else {
+				mAutostart.Uncheck()
+			}
+		}
+	}()
+
+	go func() {
+		for range mExport.ClickedCh {
+			if mExport.Checked() {
+				mExport.Uncheck()
+				exportEnabled.Store(false)
+			} else {
+				mExport.Check()
+				exportEnabled.Store(true)
+			}
+		}
+	}()
+
+	if mRemoteDashboard != nil {
+		go func() {
+			for range mRemoteDashboard.ClickedCh {
+				openBrowser(dashboardURL)
+			}
+		}()
+	}
+	if mDashboard != nil {
+		go func() {
+			for range mDashboard.ClickedCh {
+				openDashboard()
+			}
+		}()
+	}
+
+	go func() {
+		for range mCopy.ClickedCh {
+			copyMetrics()
+		}
+	}()
+
+	go func() {
+		for range mReconnect.ClickedCh {
+			select {
+			case commands <- Command{ID: "local", Type: "reconnect"}:
+			default:
+			}
+		}
+	}()
+
+	go func() {
+		for range mInventory.ClickedCh {
+			sendInventory()
+		}
+	}()
+
+	go func() {
+		for range mSpeedTest.ClickedCh {
+			go runSpeedTest("local")
+		}
+	}()
+
+	go func() {
+		for range mGraphs.ClickedCh {
+			openGraphs()
+		}
+	}()
+
+	go func() {
+		for range mSettings.ClickedCh {
+			openSettings()
+		}
+	}()
+
+	go func() {
+		for range menuSecret.ClickedCh {
+			openSettings()
+		}
+	}()
+
+	go func() {
+		for range mLogs.ClickedCh {
+			openLogs()
+		}
+	}()
+
+	go func() {
+		for range mAbout.ClickedCh {
+			openAbout()
+		}
+	}()
+
+	go func() {
+		for range mReload.ClickedCh {
+			queueReload()
+		}
+	}()
+
+	go func() {
+		<-mQuit.ClickedCh
+		shutdown("quit")
+		systray.Quit()
+	}()
+}
+
+func setStatus(status string) {
+	if headless {
+		return
+	}
+	menuMu.Lock()
+	defer menuMu.Unlock()
+	menuStatus.SetTitle(tr("Status: %s", tr(status)))
+	setTrayOffline(status == "Error" || status == "Auth failed" || status == "Update required" || status == "Not configured")
+}
+
+func showSecretMenu(show bool) {
+	if headless {
+		return
+	}
+	if show {
+		menuSecret.Show()
+	} else {
+		menuSecret.Hide()
+	}
+}
+
+func updateMenuMetrics(m Metrics) {
+	if headless {
+		return
+	}
+	menuMu.Lock()
+	defer menuMu.Unlock()
+	if m.CPUFreqMHz > 0 {
+		menuCPU.SetTitle(tr("CPU: %.1f%% @ %.2f GHz", m.CPU, float64(m.CPUFreqMHz)/1000))
+	} else {
+		menuCPU.SetTitle(fmt.Sprintf("CPU: %.1f%%", m.CPU))
+	}
+	menuRAM.SetTitle(fmt.Sprintf("RAM: %.1f%% (%d MB / %d MB)", m.RAM, m.RAMUsedMB, m.RAMTotalMB))
+	if m.GPU >= 0 {
+		menuGPU.SetTitle(fmt.Sprintf("GPU: %.0f%%", m.GPU))
+		menuGPUEnc.SetTitle(tr("Encoder: %.0f%%", m.GPUEncoder))
+		menuGPUDec.SetTitle(tr("Decoder: %.0f%%", m.GPUDecoder))
+		menuGPUClk.SetTitle(tr("Core clock: %.0f MHz", m.GPUCoreMHz))
+		menuGPUMem.SetTitle(tr("Memory clock: %.0f MHz", m.GPUMemMHz))
+	} else {
+		menuGPU.SetTitle(tr("GPU: N/A"))
+		menuGPUEnc.SetTitle(tr("Encoder: N/A"))
+		menuGPUDec.SetTitle(tr("Decoder: N/A"))
+		menuGPUClk.SetTitle(tr("Core clock: N/A"))
+		menuGPUMem.SetTitle(tr("Memory clock: N/A"))
+	}
+	if m.PagefileTotalMB > 0 {
+		menuSwap.SetTitle(tr("Pagefile: %.1f%% (%d MB / %d MB)", m.Pagefile, m.PagefileUsedMB, m.PagefileTotalMB))
+	} else {
+		menuSwap.SetTitle(tr("Pagefile: N/A"))
+	}
+	menuNet.SetTitle(tr("Net: ↓ %s ↑ %s", formatRate(m.NetRecvBps), formatRate(m.NetSentBps)))
+	menuUptime.SetTitle(tr("Uptime: %s", formatUptime(m.UptimeSec)))
+	if a := m.Agent; a != nil {
+		menuAgent.SetTitle(tr("Agent: %.1f%% CPU · %d MB · %d goroutines", a.CPU, a.RSSBytes/mb, a.Goroutines))
+		menuAgent.SetTooltip(tr("Reconnects: %d, send errors: %d", a.Reconnects, a.SendErrors))
+	}
+	updateTrayIcon(m)
+	updateOverlay(m)
+	systray.SetTooltip(trayTooltip(m))
+	recent := history.Stats(statsWindow)
+	menuCPU.SetTooltip(statsTooltip(recent["cpu_usage_percent"], "%"))
+	menuRAM.SetTooltip(statsTooltip(recent["memory_used_percent"], "%"))
+	menuGPU.SetTooltip(statsTooltip(recent["gpu_usage_percent"], "%"))
+	menuFans.Update(fanLines(m.Fans))
+	menuDisks.Update(diskTempLines(m.DiskTemps))
+	menuIfaces.Update(interfaceLines(m.Interfaces))
+	menuContainers.Update(containerLines(m.Containers))
+	menuVMs.Update(vmLines(m.VMs))
+	if len(watch) > 0 {
+		if missing := missingProcesses(m.Watched); len(missing) > 0 {
+			menuWatch.SetTitle(tr("Processes: %s missing", strings.Join(missing, ", ")))
+		} else {
+			menuWatch.SetTitle(tr("Processes: all running"))
+		}
+	}
+	if len(services) > 0 {
+		if stopped := stoppedServices(m.Services); len(stopped) > 0 {
+			menuSvc.SetTitle(tr("Services: %s not running", strings.Join(stopped, ", ")))
+		} else {
+			menuSvc.SetTitle(tr("Services: all running"))
+		}
+	}
+	if len(m.Errors) > 0 {
+		names := slices.Sorted(maps.Keys(m.Errors))
+		lines := make([]string, len(names))
+		for i, n := range names {
+			lines[i] = n + ": " + m.Errors[n]
+		}
+		menuErrors.SetTitle(tr("Collectors failing: %s", strings.Join(names, ", ")))
+		menuErrors.SetTooltip(strings.Join(lines, "\n"))
+		menuErrors.Show()
+	} else {
+		menuErrors.Hide()
+	}
+}
+
+const statsWindow = 5 * time.Minute
+
+// trayTooltip is the one-line summary shown when hovering the icon.
+func trayTooltip(m Metrics) string {
+	parts := []string{fmt.Sprintf("CPU %.0f%%", m.CPU), fmt.Sprintf("RAM %.0f%%", m.RAM)}
+	if m.GPU >= 0 {
+		parts = append(parts, fmt.Sprintf("GPU %.0f%%", m.GPU))
+	}
+	if ms := latencyMs(); ms >= 0 {
+		parts = append(parts, fmt.Sprintf("%.0f ms", ms))
+	}
+	return strings.Join(parts, " · ")
+}
+
+func statsTooltip(s metricStats, unit string) string {
+	if s.Count == 0 {
+		return ""
+	}
+	return tr("Last %.0f min: min %.1f%s, avg %.1f%s, max %.1f%s", statsWindow.Minutes(), s.Min, unit, s.Avg, unit, s.Max, unit)
+}
+
+// checkForegroundMenu matches the "Report foreground app" checkbox to a
+// change made by the server.
+func checkForegroundMenu(on bool) {
+	if headless {
+		return
+	}
+	if on {
+		menuForeground.Check()
+	} else {
+		menuForeground.Uncheck()
+	}
+}
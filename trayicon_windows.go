@@ -0,0 +1,203 @@
+package main
+
+import (
+	"bytes"
+	"encoding/binary"
+	"image"
+	"image/color"
+	"image/png"
+	"strconv"
+
+	"github.com/getlantern/systray"
+)
+
+const trayIconSize = 32
+
+// digitFont is a 3x5 bitmap font, one row per byte with the leftmost pixel
+// in bit 2.
+var digitFont = [10][5]uint8{
+	{7, 5, 5, 5, 7}, {2, 6, 2, 2, 7}, {7, 1, 7, 4, 7}, {7, 1, 7, 1, 7}, {5, 5, 7, 1, 1},
+	{7, 4, 7, 1, 7}, {7, 4, 7, 5, 7}, {7, 1, 1, 1, 1}, {7, 5, 7, 5, 7}, {7, 5, 7, 1, 7},
+}
+
+var (
+	iconBackground = color.RGBA{0x20, 0x20, 0x20, 0xff}
+	iconForeground = color.RGBA{0xff, 0xff, 0xff, 0xff}
+	iconOffline    = color.RGBA{0x80, 0x80, 0x80, 0xff}
+
+	// iconLevels are the ok, warning and critical colors.
+	iconLevels = [3]color.RGBA{{0x4c, 0xaf, 0x50, 0xff}, {0xff, 0xc1, 0x07, 0xff}, {0xf4, 0x43, 0x36, 0xff}}
+)
+
+type iconState struct {
+	pct, level int
+	offline    bool
+}
+
+// trayIcon is what the generated icon should show; it is redrawn only when
+// this differs from drawnIcon. Both are guarded by menuMu.
+var (
+	trayIcon  iconState
+	drawnIcon *iconState
+)
+
+func generatedTrayIcon() bool {
+	return trayIconMode == "cpu-text" || trayIconMode == "cpu-bar" || trayIconMode == "load"
+}
+
+// updateTrayIcon records the load shown by a generated icon. The caller
+// holds menuMu.
+func updateTrayIcon(m Metrics) {
+	trayIcon.pct = min(max(int(m.CPU+0.5), 0), 100)
+	trayIcon.level = loadLevel(m)
+	redrawTrayIcon()
+}
+
+// setTrayOffline grays out a generated icon while reports fail. The caller
+// holds menuMu.
+func setTrayOffline(offline bool) {
+	trayIcon.offline = offline
+	redrawTrayIcon()
+}
+
+// loadLevel compares CPU, RAM and the hottest disk against the
+// M_ICON_* thresholds: 0 is fine, 1 warning, 2 critical.
+func loadLevel(m Metrics) int {
+	hottest := -1
+	for _, t := range m.DiskTemps {
+		hottest = max(hottest, t.TempC)
+	}
+	level := 0
+	for _, c := range []struct{ v, warn, crit float64 }{
+		{m.CPU, iconWarn, iconCrit},
+		{m.RAM, iconWarn, iconCrit},
+		{float64(hottest), iconTempWarn, iconTempCrit},
+	} {
+		switch {
+		case c.v >= c.crit:
+			level = 2
+		case c.v >= c.warn:
+			level = max(level, 1)
+		}
+	}
+	return level
+}
+
+func redrawTrayIcon() {
+	if !generatedTrayIcon() {
+		return
+	}
+	state := trayIcon
+	if drawnIcon != nil && *drawnIcon == state {
+		return
+	}
+	drawnIcon = &state
+
+	fg := iconLevels[state.level]
+	if trayIconMode != "load" {
+		fg = iconForeground
+		if state.level > 0 {
+			fg = iconLevels[state.level]
+		}
+	}
+	if state.offline {
+		fg = iconOffline
+	}
+
+	img := image.NewRGBA(image.Rect(0, 0, trayIconSize, trayIconSize))
+	switch trayIconMode {
+	case "load":
+		drawDisc(img, fg)
+		if state.offline {
+			drawCross(img, iconBackground)
+		}
+	case "cpu-bar":
+		fill(img, img.Bounds(), iconBackground)
+		h := state.pct * (trayIconSize - 4) / 100
+		fill(img, image.Rect(6, trayIconSize-2-h, trayIconSize-6, trayIconSize-2), fg)
+	default:
+		fill(img, img.Bounds(), iconBackground)
+		drawNumber(img, state.pct, fg)
+	}
+
+	if ico, err := encodeICO(img); err == nil {
+		systray.SetIcon(ico)
+	}
+}
+
+func drawDisc(img *image.RGBA, c color.RGBA) {
+	r := trayIconSize/2 - 1
+	for y := 0; y < trayIconSize; y++ {
+		for x := 0; x < trayIconSize; x++ {
+			dx, dy := x-trayIconSize/2, y-trayIconSize/2
+			if dx*dx+dy*dy <= r*r {
+				img.SetRGBA(x, y, c)
+			}
+		}
+	}
+}
+
+func drawCross(img *image.RGBA, c color.RGBA) {
+	for i := 8; i < trayIconSize-8; i++ {
+		for w := -1; w <= 1; w++ {
+			img.SetRGBA(i+w, i, c)
+			img.SetRGBA(trayIconSize-1-i+w, i, c)
+		}
+	}
+}
+
+func fill(img *image.RGBA, r image.Rectangle, c color.RGBA) {
+	for y := r.Min.Y; y < r.Max.Y; y++ {
+		for x := r.Min.X; x < r.Max.X; x++ {
+			img.SetRGBA(x, y, c)
+		}
+	}
+}
+
+// drawNumber centers n in the icon, as large as three digits allow.
+func drawNumber(img *image.RGBA, n int, c color.RGBA) {
+	s := strconv.Itoa(n)
+	scale := 3
+	if len(s) > 2 {
+		scale = 2
+	}
+	width := (len(s)*4 - 1) * scale
+	x0 := (trayIconSize - width) / 2
+	y0 := (trayIconSize - 5*scale) / 2
+
+	for i, ch := range s {
+		glyph := digitFont[ch-'0']
+		for row, bits := range glyph {
+			for col := 0; col < 3; col++ {
+				if bits&(4>>col) == 0 {
+					continue
+				}
+				x := x0 + (i*4+col)*scale
+				y := y0 + row*scale
+				fill(img, image.Rect(x, y, x+scale, y+scale), c)
+			}
+		}
+	}
+}
+
+// encodeICO wraps img as a single PNG entry, which Windows accepts since
+// Vista.
+func encodeICO(img image.Image) ([]byte, error) {
+	var pngData bytes.Buffer
+	if err := png.Encode(&pngData, img); err != nil {
+		return nil, err
+	}
+
+	var buf bytes.Buffer
+	b := img.Bounds()
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Reserved, Type, Count uint16
+	}{0, 1, 1})
+	binary.Write(&buf, binary.LittleEndian, struct {
+		Width, Height, Colors, Reserved uint8
+		Planes, BitCount                uint16
+		Size, Offset                    uint32
+	}{uint8(b.Dx()), uint8(b.Dy()), 0, 0, 1, 32, uint32(pngData.Len()), 6 + 16})
+	buf.Write(pngData.Bytes())
+	return buf.Bytes(), nil
+}
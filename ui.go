@@ -0,0 +1,64 @@
+package main
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"net"
+	"net/http"
+	"sync"
+)
+
+// uiServer serves the pages opened from the tray (settings, graphs, logs, about) on a
+// random loopback port, started the first time one is used. Every request
+// must carry the per-process token, which keeps other local pages from
+// reading or posting to it.
+var uiServer struct {
+	once  sync.Once
+	base  string
+	token string
+	err   error
+}
+
+func openUI(page string) {
+	uiServer.once.Do(func() {
+		ln, err := net.Listen("tcp", "127.0.0.1:0")
+		if err != nil {
+			uiServer.err = err
+			return
+		}
+		b := make([]byte, 16)
+		rand.Read(b)
+		uiServer.token = hex.EncodeToString(b)
+		uiServer.base = "http://" + ln.Addr().String()
+
+		mux := http.NewServeMux()
+		mux.HandleFunc("/settings", withUIToken(handleSettings))
+		mux.HandleFunc("GET /graphs", withUIToken(handleGraphs))
+		mux.HandleFunc("GET /graphs/data", withUIToken(handleGraphData))
+		mux.HandleFunc("/about", withUIToken(handleAbout))
+		mux.HandleFunc("GET /logs", withUIToken(handleLogs))
+		mux.HandleFunc("GET /logs/text", withUIToken(handleLogText))
+		go http.Serve(ln, mux)
+	})
+	if uiServer.err != nil {
+		slog.Error("Local UI unavailable", "err", uiServer.err)
+		return
+	}
+	openBrowser(fmt.Sprintf("%s/%s?token=%s", uiServer.base, page, uiServer.token))
+}
+
+func withUIToken(h http.HandlerFunc) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		token := r.URL.Query().Get("token")
+		if r.Method == http.MethodPost {
+			token = r.PostFormValue("token")
+		}
+		if token != uiServer.token {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
+		h(w, r)
+	}
+}
@@ -0,0 +1,49 @@
+package main
+
+import (
+	"log/slog"
+	"sync"
+
+	"github.com/getlantern/systray"
+)
+
+var updateMenu struct {
+	sync.Mutex
+	item    *systray.MenuItem
+	release *githubRelease
+}
+
+func addUpdateMenu() {
+	updateMenu.item = systray.AddMenuItem("", tr("Download, verify and install the new version, then restart"))
+	updateMenu.item.Hide()
+	go func() {
+		for range updateMenu.item.ClickedCh {
+			updateMenu.Lock()
+			rel := updateMenu.release
+			updateMenu.Unlock()
+			if rel != nil {
+				updateMenu.item.Disable()
+				if err := installUpdate(*rel); err != nil {
+					slog.Error("Update failed", "version", rel.TagName, "err", err)
+					showToast(tr("Update failed"), err.Error())
+				}
+				updateMenu.item.Enable()
+			}
+		}
+	}()
+}
+
+func offerUpdate(rel *githubRelease) {
+	if headless {
+		slog.Info("Update available", "version", rel.TagName)
+		return
+	}
+	updateMenu.Lock()
+	defer updateMenu.Unlock()
+	if updateMenu.release == nil || updateMenu.release.TagName != rel.TagName {
+		showToast(tr("Update available"), tr("Version %s is ready to install from the tray menu", rel.TagName))
+	}
+	updateMenu.release = rel
+	updateMenu.item.SetTitle(tr("Update available: %s", rel.TagName))
+	updateMenu.item.Show()
+}
@@ -0,0 +1,7 @@
+package main
+
+type UpdateReport struct {
+	Pending        int      `json:"pending"`
+	Titles         []string `json:"titles"`
+	RebootRequired bool     `json:"rebootRequired"`
+}
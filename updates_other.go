@@ -0,0 +1,11 @@
+//go:build !windows
+
+package main
+
+import "log/slog"
+
+// runUpdateCollector asks the Windows Update agent; package managers are
+// not queried, so no updates report is sent.
+func runUpdateCollector() {
+	slog.Info("Update checks are only available on Windows")
+}
@@ -0,0 +1,107 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"runtime"
+	"time"
+
+	ole "github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+func runUpdateCollector() {
+	for {
+		waitOutLowResource()
+		report, err := checkWindowsUpdates()
+		if err != nil {
+			slog.Warn("Windows Update check failed", "err", err)
+		} else if err := publish("updates", report); err != nil {
+			slog.Warn("Windows Update report failed", "err", err)
+		}
+		time.Sleep(4 * time.Hour)
+	}
+}
+
+func checkWindowsUpdates() (UpdateReport, error) {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil {
+		if oleErr, ok := err.(*ole.OleError); !ok || oleErr.Code() != 1 { // S_FALSE: already initialized
+			return UpdateReport{}, fmt.Errorf("coinitialize: %w", err)
+		}
+	}
+	defer ole.CoUninitialize()
+
+	var report UpdateReport
+
+	session, err := createDispatch("Microsoft.Update.Session")
+	if err != nil {
+		return report, err
+	}
+	defer session.Release()
+
+	searcherV, err := oleutil.CallMethod(session, "CreateUpdateSearcher")
+	if err != nil {
+		return report, fmt.Errorf("create searcher: %w", err)
+	}
+	searcher := searcherV.ToIDispatch()
+	defer searcher.Release()
+
+	resultV, err := oleutil.CallMethod(searcher, "Search", "IsInstalled=0 and IsHidden=0")
+	if err != nil {
+		return report, fmt.Errorf("search: %w", err)
+	}
+	result := resultV.ToIDispatch()
+	defer result.Release()
+
+	updatesV, err := oleutil.GetProperty(result, "Updates")
+	if err != nil {
+		return report, fmt.Errorf("updates: %w", err)
+	}
+	updates := updatesV.ToIDispatch()
+	defer updates.Release()
+
+	countV, err := oleutil.GetProperty(updates, "Count")
+	if err != nil {
+		return report, fmt.Errorf("count: %w", err)
+	}
+	count := int(countV.Val)
+	report.Pending = count
+	for i := 0; i < count; i++ {
+		itemV, err := oleutil.GetProperty(updates, "Item", i)
+		if err != nil {
+			continue
+		}
+		item := itemV.ToIDispatch()
+		if title, err := oleutil.GetProperty(item, "Title"); err == nil {
+			report.Titles = append(report.Titles, title.ToString())
+		}
+		item.Release()
+	}
+
+	sysInfo, err := createDispatch("Microsoft.Update.SystemInfo")
+	if err == nil {
+		defer sysInfo.Release()
+		if v, err := oleutil.GetProperty(sysInfo, "RebootRequired"); err == nil {
+			report.RebootRequired, _ = v.Value().(bool)
+		}
+	}
+
+	return report, nil
+}
+
+func createDispatch(progID string) (*ole.IDispatch, error) {
+	unknown, err := oleutil.CreateObject(progID)
+	if err != nil {
+		return nil, fmt.Errorf("create %s: %w", progID, err)
+	}
+	defer unknown.Release()
+
+	disp, err := unknown.QueryInterface(ole.IID_IDispatch)
+	if err != nil {
+		return nil, fmt.Errorf("query %s: %w", progID, err)
+	}
+	return disp, nil
+}
@@ -0,0 +1,25 @@
+package main
+
+import "context"
+
+// UserSession is a user account with a session on the machine: State is
+// "active" for the one at the console or connected over RDP and
+// "disconnected" for one left logged on after switching users or closing
+// the remote desktop. Station is the window station, such as Console or
+// RDP-Tcp#3, empty once disconnected.
+type UserSession struct {
+	User      string `json:"user"`
+	SessionID uint32 `json:"sessionId"`
+	State     string `json:"state"`
+	Station   string `json:"station,omitempty"`
+}
+
+// collectUsers lists who is logged on, so a shared machine shows who is
+// using it and who left a session open.
+func collectUsers(context.Context) (fields, error) {
+	users, err := getUserSessions()
+	if err != nil {
+		return nil, err
+	}
+	return func(m *Metrics) { m.Users = users }, nil
+}
@@ -0,0 +1,46 @@
+package main
+
+import (
+	"fmt"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+// getUserSessions enumerates the terminal services sessions and keeps the
+// active and disconnected ones that have a user, which leaves out session 0
+// and the idle RDP listeners.
+func getUserSessions() ([]UserSession, error) {
+	var info *windows.WTS_SESSION_INFO
+	var count uint32
+	if err := windows.WTSEnumerateSessions(0, 0, 1, &info, &count); err != nil {
+		return nil, fmt.Errorf("WTSEnumerateSessions: %w", err)
+	}
+	defer windows.WTSFreeMemory(uintptr(unsafe.Pointer(info)))
+
+	var users []UserSession
+	for _, s := range unsafe.Slice(info, count) {
+		var state string
+		switch s.State {
+		case windows.WTSActive:
+			state = "active"
+		case windows.WTSDisconnected:
+			state = "disconnected"
+		default:
+			continue
+		}
+		user := sessionUser(s.SessionID)
+		if user == "" {
+			continue
+		}
+		if domain := sessionInfo(s.SessionID, wtsDomainName); domain != "" {
+			user = domain + `\` + user
+		}
+		u := UserSession{User: user, SessionID: s.SessionID, State: state}
+		if s.WindowStationName != nil {
+			u.Station = windows.UTF16PtrToString(s.WindowStationName)
+		}
+		users = append(users, u)
+	}
+	return users, nil
+}
@@ -0,0 +1,88 @@
+package main
+
+import (
+	"fmt"
+	"log/slog"
+	"strconv"
+	"strings"
+
+	"github.com/shirou/gopsutil/v3/disk"
+)
+
+type VolumeUsage struct {
+	Name        string  `json:"name"`
+	TotalGB     float64 `json:"totalGb"`
+	FreeGB      float64 `json:"freeGb"`
+	UsedPercent float64 `json:"usedPercent"`
+}
+
+const gb = 1024 * 1024 * 1024
+
+// getVolumes reports the fixed and removable volumes that have a drive
+// letter. Empty card readers and optical drives fail Usage and are skipped.
+func getVolumes() []VolumeUsage {
+	parts, err := disk.Partitions(false)
+	if err != nil {
+		return nil
+	}
+	var vols []VolumeUsage
+	for _, p := range parts {
+		u, err := disk.Usage(p.Mountpoint)
+		if err != nil || u.Total == 0 {
+			continue
+		}
+		vols = append(vols, VolumeUsage{
+			Name:        p.Mountpoint,
+			TotalGB:     float64(u.Total) / gb,
+			FreeGB:      float64(u.Free) / gb,
+			UsedPercent: u.UsedPercent,
+		})
+	}
+	return vols
+}
+
+// diskFreeRules turns M_DISK_FREE_ALERT, e.g. "10%" or "20GB" or both
+// separated by a comma, into rules on every volume's free space.
+func diskFreeRules(s string) []alertRule {
+	var rules []alertRule
+	for _, limit := range parseList(s) {
+		r := alertRule{Op: "<", Cooldown: alertCooldown}
+		var num string
+		switch l := strings.ToUpper(limit); {
+		case strings.HasSuffix(l, "%"):
+			num = strings.TrimSuffix(l, "%")
+			r.Name, r.Kind, r.Metric, r.Hysteresis = "disk-free", "disk-free-percent", "volume_free_percent", 1
+		case strings.HasSuffix(l, "GB"):
+			num = strings.TrimSuffix(l, "GB")
+			r.Name, r.Kind, r.Metric, r.Hysteresis = "disk-free-gb", "disk-free-gb", "volume_free_bytes", gb
+		default:
+			slog.Warn("Invalid M_DISK_FREE_ALERT, expected e.g. 10% or 20GB", "limit", limit)
+			continue
+		}
+		v, err := strconv.ParseFloat(strings.TrimSpace(num), 64)
+		if err != nil || v <= 0 {
+			slog.Warn("Invalid M_DISK_FREE_ALERT, expected e.g. 10% or 20GB", "limit", limit)
+			continue
+		}
+		r.Threshold = v
+		if r.Kind == "disk-free-gb" {
+			r.Threshold *= gb
+		}
+		rules = append(rules, r)
+	}
+	return rules
+}
+
+// describeDiskFree words disk free space alerts around the volume rather
+// than the metric.
+func describeDiskFree(r alertRule, ev AlertEvent) string {
+	unit, value, limit := "%", ev.Value, r.Threshold
+	if r.Kind == "disk-free-gb" {
+		unit, value, limit = " GB", value/gb, limit/gb
+	}
+	vol := ev.Labels["volume"]
+	if ev.State == "resolved" {
+		return fmt.Sprintf("%s has %s%s free again", vol, formatAlertValue(value), unit)
+	}
+	return fmt.Sprintf("%s has only %s%s free (below %s%s)", vol, formatAlertValue(value), unit, formatAlertValue(limit), unit)
+}
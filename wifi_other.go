@@ -0,0 +1,6 @@
+//go:build !windows
+
+package main
+
+// getWiFi reads the WLAN service; elsewhere the Wi-Fi fields are left out.
+func getWiFi() (string, int, bool) { return "", 0, false }
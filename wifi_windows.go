@@ -0,0 +1,101 @@
+package main
+
+import (
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+)
+
+type wlanInterfaceInfo struct {
+	InterfaceGuid windows.GUID
+	Description   [256]uint16
+	State         uint32
+}
+
+type wlanInterfaceInfoList struct {
+	NumberOfItems uint32
+	Index         uint32
+	InterfaceInfo [1]wlanInterfaceInfo
+}
+
+type wlanAssociationAttributes struct {
+	SSIDLength    uint32
+	SSID          [32]byte
+	BSSType       uint32
+	BSSID         [6]byte
+	PhyType       uint32
+	PhyIndex      uint32
+	SignalQuality uint32
+	RxRate        uint32
+	TxRate        uint32
+}
+
+type wlanConnectionAttributes struct {
+	State          uint32
+	ConnectionMode uint32
+	ProfileName    [256]uint16
+	Association    wlanAssociationAttributes
+}
+
+const (
+	wlanInterfaceStateConnected     = 1
+	wlanIntfOpcodeCurrentConnection = 7
+)
+
+var (
+	wlanapi                = windows.NewLazySystemDLL("wlanapi.dll")
+	procWlanOpenHandle     = wlanapi.NewProc("WlanOpenHandle")
+	procWlanCloseHandle    = wlanapi.NewProc("WlanCloseHandle")
+	procWlanEnumInterfaces = wlanapi.NewProc("WlanEnumInterfaces")
+	procWlanQueryInterface = wlanapi.NewProc("WlanQueryInterface")
+	procWlanFreeMemory     = wlanapi.NewProc("WlanFreeMemory")
+)
+
+func getWiFi() (string, int, bool) {
+	if procWlanOpenHandle.Find() != nil {
+		return "", 0, false
+	}
+
+	var version uint32
+	var handle windows.Handle
+	if r, _, _ := procWlanOpenHandle.Call(2, 0, uintptr(unsafe.Pointer(&version)), uintptr(unsafe.Pointer(&handle))); r != 0 {
+		return "", 0, false
+	}
+	defer procWlanCloseHandle.Call(uintptr(handle), 0)
+
+	var list *wlanInterfaceInfoList
+	if r, _, _ := procWlanEnumInterfaces.Call(uintptr(handle), 0, uintptr(unsafe.Pointer(&list))); r != 0 {
+		return "", 0, false
+	}
+	defer procWlanFreeMemory.Call(uintptr(unsafe.Pointer(list)))
+
+	ifaces := unsafe.Slice(&list.InterfaceInfo[0], list.NumberOfItems)
+	for i := range ifaces {
+		if ifaces[i].State != wlanInterfaceStateConnected {
+			continue
+		}
+
+		var size uint32
+		var attrs *wlanConnectionAttributes
+		r, _, _ := procWlanQueryInterface.Call(
+			uintptr(handle),
+			uintptr(unsafe.Pointer(&ifaces[i].InterfaceGuid)),
+			wlanIntfOpcodeCurrentConnection,
+			0,
+			uintptr(unsafe.Pointer(&size)),
+			uintptr(unsafe.Pointer(&attrs)),
+			0,
+		)
+		if r != 0 {
+			continue
+		}
+
+		assoc := attrs.Association
+		ssid := string(assoc.SSID[:min(assoc.SSIDLength, uint32(len(assoc.SSID)))])
+		quality := int(assoc.SignalQuality)
+		procWlanFreeMemory.Call(uintptr(unsafe.Pointer(attrs)))
+		return ssid, quality, true
+	}
+
+	return "", 0, false
+}
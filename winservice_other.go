@@ -0,0 +1,31 @@
+//go:build !windows
+
+package main
+
+import (
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"runtime"
+)
+
+// runningAsService is only ever set by the Windows service manager. Under
+// systemd or launchd the agent runs headless like any other --no-tray
+// start and logs to stderr, which the journal or launchd collects.
+const runningAsService = false
+
+func runServiceCommand([]string) {
+	unit := "a systemd unit running: go-win-monitor --no-tray"
+	if runtime.GOOS == "darwin" {
+		unit = "a launchd job running: go-win-monitor --no-tray"
+	}
+	fmt.Fprintln(os.Stderr, "The service command is Windows only; install "+unit)
+	os.Exit(2)
+}
+
+func runService() {}
+
+func serviceLogHandler() (slog.Handler, error) {
+	return nil, errors.New("no service log")
+}
@@ -0,0 +1,268 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"os"
+	"slices"
+	"strings"
+	"time"
+	"unsafe"
+
+	"golang.org/x/sys/windows"
+	"golang.org/x/sys/windows/svc"
+	"golang.org/x/sys/windows/svc/eventlog"
+	"golang.org/x/sys/windows/svc/mgr"
+)
+
+const winServiceName = "go-win-monitor"
+
+// runningAsService is set when the service control manager started us; the
+// agent then runs headless and logs to the Application event log.
+var runningAsService, _ = svc.IsWindowsService()
+
+// runServiceCommand handles go-win-monitor service install|uninstall|start|stop.
+// Options after install, e.g. --url, are passed to the service on every
+// start.
+func runServiceCommand(args []string) {
+	var err error
+	switch action, rest := firstArg(args); action {
+	case "install":
+		err = installService(rest)
+	case "uninstall":
+		err = uninstallService()
+	case "start":
+		err = controlService(func(s *mgr.Service) error { return s.Start() })
+	case "stop":
+		err = controlService(stopService)
+	default:
+		fmt.Fprintln(os.Stderr, "usage: go-win-monitor service install|uninstall|start|stop")
+		os.Exit(2)
+	}
+	if errors.Is(err, windows.ERROR_ACCESS_DENIED) {
+		err = fmt.Errorf("%w (run from an elevated prompt)", err)
+	}
+	if err != nil {
+		fmt.Fprintln(os.Stderr, err)
+		os.Exit(1)
+	}
+	os.Exit(0)
+}
+
+func firstArg(args []string) (string, []string) {
+	if len(args) == 0 {
+		return "", nil
+	}
+	return args[0], args[1:]
+}
+
+func installService(args []string) error {
+	exe, err := os.Executable()
+	if err != nil {
+		return err
+	}
+	// The service runs as LocalSystem, whose config directory is not the
+	// installing user's, so it is pointed at the config file in use now.
+	if !hasConfigFlag(args) {
+		if path := configFilePath(); path != "" {
+			args = append([]string{"--config", path}, args...)
+		}
+	}
+
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.CreateService(winServiceName, exe, mgr.Config{
+		DisplayName: "Go Win Monitor",
+		Description: "Collects system metrics and sends them to the monitoring server.",
+		StartType:   mgr.StartAutomatic,
+	}, args...)
+	if err != nil {
+		return fmt.Errorf("create service: %w", err)
+	}
+	defer s.Close()
+
+	restart := mgr.RecoveryAction{Type: mgr.ServiceRestart, Delay: 10 * time.Second}
+	if err := s.SetRecoveryActions([]mgr.RecoveryAction{restart, restart, restart}, uint32((24 * time.Hour).Seconds())); err != nil {
+		slog.Warn("Service recovery actions not set", "err", err)
+	}
+	if err := eventlog.InstallAsEventCreate(winServiceName, eventlog.Error|eventlog.Warning|eventlog.Info); err != nil {
+		slog.Warn("Event log source not installed", "err", err)
+	}
+	fmt.Fprintf(os.Stderr, "Installed the %s service; start it with: go-win-monitor service start\n", winServiceName)
+	return nil
+}
+
+func hasConfigFlag(args []string) bool {
+	for _, a := range args {
+		if a == "-config" || a == "--config" || strings.HasPrefix(a, "-config=") || strings.HasPrefix(a, "--config=") {
+			return true
+		}
+	}
+	return false
+}
+
+func uninstallService() error {
+	err := controlService(func(s *mgr.Service) error {
+		if st, err := s.Query(); err == nil && st.State != svc.Stopped {
+			if err := stopService(s); err != nil {
+				return err
+			}
+		}
+		return s.Delete()
+	})
+	if err != nil {
+		return err
+	}
+	eventlog.Remove(winServiceName)
+	fmt.Fprintf(os.Stderr, "Removed the %s service\n", winServiceName)
+	return nil
+}
+
+func controlService(f func(s *mgr.Service) error) error {
+	m, err := mgr.Connect()
+	if err != nil {
+		return fmt.Errorf("connect to service manager: %w", err)
+	}
+	defer m.Disconnect()
+	s, err := m.OpenService(winServiceName)
+	if err != nil {
+		return fmt.Errorf("open service %s: %w", winServiceName, err)
+	}
+	defer s.Close()
+	return f(s)
+}
+
+// stopService asks the service to stop and waits for it to exit.
+func stopService(s *mgr.Service) error {
+	st, err := s.Control(svc.Stop)
+	if err != nil {
+		return fmt.Errorf("stop service: %w", err)
+	}
+	deadline := time.Now().Add(15 * time.Second)
+	for st.State != svc.Stopped {
+		if time.Now().After(deadline) {
+			return fmt.Errorf("service did not stop within 15s")
+		}
+		time.Sleep(300 * time.Millisecond)
+		if st, err = s.Query(); err != nil {
+			return fmt.Errorf("query service: %w", err)
+		}
+	}
+	return nil
+}
+
+type agentService struct{}
+
+func (agentService) Execute(_ []string, r <-chan svc.ChangeRequest, s chan<- svc.Status) (bool, uint32) {
+	s <- svc.Status{State: svc.StartPending}
+	startServices()
+	go run(agentCtx)
+	s <- svc.Status{State: svc.Running, Accepts: svc.AcceptStop | svc.AcceptShutdown | svc.AcceptSessionChange}
+
+	for c := range r {
+		switch c.Cmd {
+		case svc.Interrogate:
+			s <- c.CurrentStatus
+		case svc.SessionChange:
+			// EventData points at a WTSSESSION_NOTIFICATION owned by the
+			// service manager for the duration of the call.
+			n := (*windows.WTSSESSION_NOTIFICATION)(unsafe.Add(unsafe.Pointer(nil), c.EventData))
+			sessionChanged(c.EventType, n.SessionID)
+		case svc.Stop, svc.Shutdown:
+			s <- svc.Status{State: svc.StopPending, WaitHint: uint32((shutdownTimeout + time.Second).Milliseconds())}
+			reason := "service stop"
+			if c.Cmd == svc.Shutdown {
+				reason = "system shutdown"
+			}
+			shutdown(reason)
+			return false, 0
+		}
+	}
+	return false, 0
+}
+
+// runService hands the process to the service control manager until the
+// service is stopped.
+func runService() {
+	if err := svc.Run(winServiceName, agentService{}); err != nil {
+		slog.Error("Service failed", "err", err)
+	}
+}
+
+func serviceLogHandler() (slog.Handler, error) {
+	elog, err := eventlog.Open(winServiceName)
+	if err != nil {
+		return nil, err
+	}
+	return &eventLogHandler{elog: elog}, nil
+}
+
+// eventLogHandler writes the records with an event ID, and every error, to
+// the Application event log under that ID: the message on the first line,
+// then one key=value per line.
+type eventLogHandler struct {
+	elog   *eventlog.Log
+	attrs  []slog.Attr
+	prefix string
+}
+
+func (h *eventLogHandler) Enabled(_ context.Context, l slog.Level) bool {
+	return l >= logLevel.Level()
+}
+
+func (h *eventLogHandler) Handle(_ context.Context, r slog.Record) error {
+	attrs := slices.Clip(h.attrs)
+	r.Attrs(func(a slog.Attr) bool {
+		attrs = append(attrs, h.qualify(a))
+		return true
+	})
+
+	id := 0
+	if r.Level >= slog.LevelError {
+		id = evtError
+	}
+	var b strings.Builder
+	b.WriteString(r.Message)
+	for _, a := range attrs {
+		if a.Key == "event" && a.Value.Kind() == slog.KindInt64 {
+			id = int(a.Value.Int64())
+			continue
+		}
+		fmt.Fprintf(&b, "\r\n%s=%s", a.Key, a.Value.Resolve())
+	}
+	switch {
+	case id == 0:
+		return nil
+	case r.Level >= slog.LevelError:
+		return h.elog.Error(uint32(id), b.String())
+	case r.Level >= slog.LevelWarn:
+		return h.elog.Warning(uint32(id), b.String())
+	default:
+		return h.elog.Info(uint32(id), b.String())
+	}
+}
+
+func (h *eventLogHandler) qualify(a slog.Attr) slog.Attr {
+	a.Key = h.prefix + a.Key
+	return a
+}
+
+func (h *eventLogHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	c := *h
+	c.attrs = slices.Clip(c.attrs)
+	for _, a := range attrs {
+		c.attrs = append(c.attrs, h.qualify(a))
+	}
+	return &c
+}
+
+func (h *eventLogHandler) WithGroup(name string) slog.Handler {
+	c := *h
+	c.prefix += name + "."
+	return &c
+}
@@ -0,0 +1,203 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log/slog"
+	"regexp"
+	"slices"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+)
+
+// fileWMIQueries holds the [[wmi_queries]] tables of the config file, e.g.
+//
+//	[[wmi_queries]]
+//	name = "printers"
+//	class = "Win32_Printer"
+//	fields = ["PrinterStatus", "WorkOffline"]
+//	key = "Name"
+//	interval = "5m"
+//
+// namespace defaults to root\cimv2, where adds a WQL condition and key names
+// the field telling the instances apart; without it they are numbered.
+var fileWMIQueries []map[string]any
+
+type wmiQuery struct {
+	name      string
+	namespace string
+	class     string
+	fields    []string
+	where     string
+	key       string
+	interval  time.Duration
+}
+
+// selected is the fields with the key in front.
+func (q wmiQuery) selected() []string {
+	if q.key == "" || slices.Contains(q.fields, q.key) {
+		return q.fields
+	}
+	return append([]string{q.key}, q.fields...)
+}
+
+func (q wmiQuery) wql() string {
+	s := "SELECT " + strings.Join(q.selected(), ", ") + " FROM " + q.class
+	if q.where != "" {
+		s += " WHERE " + q.where
+	}
+	return s
+}
+
+// wmiQueries are set by startServices and again on every config reload.
+var wmiQueries struct {
+	sync.Mutex
+	queries []wmiQuery
+}
+
+func setWMIQueries(qs []wmiQuery) {
+	wmiQueries.Lock()
+	wmiQueries.queries = qs
+	wmiQueries.Unlock()
+}
+
+var wmiIdentifier = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+
+func parseWMITables(tables []map[string]any) []wmiQuery {
+	var queries []wmiQuery
+	for i, t := range tables {
+		q, err := parseWMITable(t)
+		if err != nil {
+			name, _ := t["name"].(string)
+			if name == "" {
+				name = fmt.Sprintf("#%d", i+1)
+			}
+			slog.Warn("Invalid WMI query in the config file", "path", configFilePath(), "query", name, "err", err)
+			continue
+		}
+		queries = append(queries, q)
+	}
+	return queries
+}
+
+func parseWMITable(t map[string]any) (wmiQuery, error) {
+	q := wmiQuery{namespace: `root\cimv2`}
+	for k, v := range t {
+		var err error
+		switch k {
+		case "name":
+			q.name, err = tomlString(k, v)
+		case "namespace":
+			q.namespace, err = tomlString(k, v)
+		case "class":
+			q.class, err = tomlString(k, v)
+		case "where":
+			q.where, err = tomlString(k, v)
+		case "key":
+			q.key, err = tomlString(k, v)
+		case "interval":
+			q.interval, err = tomlDuration(k, v)
+		case "fields":
+			items, ok := v.([]any)
+			if !ok {
+				return q, fmt.Errorf("fields should be a list of property names")
+			}
+			for _, item := range items {
+				f, ok := item.(string)
+				if !ok || !wmiIdentifier.MatchString(f) {
+					return q, fmt.Errorf("field %v is not a property name", item)
+				}
+				q.fields = append(q.fields, f)
+			}
+		default:
+			err = fmt.Errorf("unknown key %q", k)
+		}
+		if err != nil {
+			return q, err
+		}
+	}
+
+	switch {
+	case !wmiIdentifier.MatchString(q.class):
+		return q, fmt.Errorf("class %q should be a WMI class such as Win32_Printer", q.class)
+	case len(q.fields) == 0:
+		return q, fmt.Errorf("missing fields")
+	case q.key != "" && !wmiIdentifier.MatchString(q.key):
+		return q, fmt.Errorf("key %q is not a property name", q.key)
+	}
+	if q.name == "" {
+		q.name = strings.ToLower(q.class)
+	}
+	return q, nil
+}
+
+type wmiResult struct {
+	at   time.Time
+	rows map[string]map[string]any
+	err  error
+}
+
+// wmiResults keeps each query's last rows, reported again until its
+// interval is up, by namespace and WQL so an edited query runs afresh. Only
+// the wmi collector uses it, and it never runs twice at once.
+var wmiResults = map[string]*wmiResult{}
+
+func collectWMI(context.Context) (fields, error) {
+	wmiQueries.Lock()
+	queries := wmiQueries.queries
+	wmiQueries.Unlock()
+	if len(queries) == 0 {
+		return nil, nil
+	}
+
+	tables := map[string]map[string]map[string]any{}
+	var errs []error
+	for _, q := range queries {
+		id := q.namespace + "|" + q.wql()
+		r := wmiResults[id]
+		if r == nil {
+			r = &wmiResult{}
+			wmiResults[id] = r
+		}
+		if r.at.IsZero() || time.Since(r.at) >= q.interval {
+			rows, err := queryWMI(q)
+			r.at = time.Now()
+			switch {
+			case err != nil && r.err == nil:
+				slog.Warn("WMI query failed", "query", q.name, "wql", q.wql(), "err", err)
+			case err == nil && r.err != nil:
+				slog.Info("WMI query works again", "query", q.name)
+			}
+			r.err, r.rows = err, nil
+			if err == nil {
+				r.rows = keyWMIRows(q, rows)
+			}
+		}
+		if r.err != nil {
+			errs = append(errs, fmt.Errorf("%s: %w", q.name, r.err))
+		}
+		if r.rows != nil {
+			tables[q.name] = r.rows
+		}
+	}
+	return func(m *Metrics) { m.WMI = tables }, errors.Join(errs...)
+}
+
+// keyWMIRows names each row by its key field, or by its position.
+func keyWMIRows(q wmiQuery, rows []map[string]any) map[string]map[string]any {
+	keyed := make(map[string]map[string]any, len(rows))
+	for i, row := range rows {
+		instance := strconv.Itoa(i)
+		if q.key != "" {
+			instance = fmt.Sprint(row[q.key])
+			if !slices.Contains(q.fields, q.key) {
+				delete(row, q.key)
+			}
+		}
+		keyed[instance] = row
+	}
+	return keyed
+}
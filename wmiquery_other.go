@@ -0,0 +1,9 @@
+//go:build !windows
+
+package main
+
+import "errors"
+
+func queryWMI(wmiQuery) ([]map[string]any, error) {
+	return nil, errors.New("WMI is only available on Windows")
+}
@@ -0,0 +1,128 @@
+package main
+
+import (
+	"fmt"
+	"runtime"
+	"strconv"
+
+	ole "github.com/go-ole/go-ole"
+	"github.com/go-ole/go-ole/oleutil"
+)
+
+// CIM types the scripting API hands over as strings.
+const (
+	wbemCimtypeSint64 = 20
+	wbemCimtypeUint64 = 21
+)
+
+// queryWMI runs q through the scripting API, which unlike the wmi package
+// needs no struct per class. Numbers come back as float64.
+func queryWMI(q wmiQuery) ([]map[string]any, error) {
+	fields := q.selected()
+	var rows []map[string]any
+	err := execWMI(q.namespace, q.wql(), func(item *ole.IDispatch) error {
+		row := map[string]any{}
+		for _, f := range fields {
+			if value, ok := wmiProperty(item, f); ok {
+				row[f] = value
+			}
+		}
+		rows = append(rows, row)
+		return nil
+	})
+	return rows, err
+}
+
+// execWMI connects to namespace, runs the WQL query and calls f with each
+// object, on a locked thread with COM initialized. Objects are released
+// once f returns.
+func execWMI(namespace, wql string, f func(item *ole.IDispatch) error) error {
+	runtime.LockOSThread()
+	defer runtime.UnlockOSThread()
+
+	if err := ole.CoInitializeEx(0, ole.COINIT_MULTITHREADED); err != nil {
+		if oleErr, ok := err.(*ole.OleError); !ok || oleErr.Code() != 1 { // S_FALSE: already initialized
+			return fmt.Errorf("coinitialize: %w", err)
+		}
+	}
+	defer ole.CoUninitialize()
+
+	locator, err := createDispatch("WbemScripting.SWbemLocator")
+	if err != nil {
+		return err
+	}
+	defer locator.Release()
+
+	serviceV, err := oleutil.CallMethod(locator, "ConnectServer", nil, namespace)
+	if err != nil {
+		return fmt.Errorf("connect %s: %w", namespace, err)
+	}
+	service := serviceV.ToIDispatch()
+	defer service.Release()
+
+	resultV, err := oleutil.CallMethod(service, "ExecQuery", wql)
+	if err != nil {
+		return fmt.Errorf("query: %w", err)
+	}
+	result := resultV.ToIDispatch()
+	defer result.Release()
+
+	return oleutil.ForEach(result, func(v *ole.VARIANT) error {
+		item := v.ToIDispatch()
+		defer item.Release()
+		return f(item)
+	})
+}
+
+func wmiProperty(item *ole.IDispatch, name string) (any, bool) {
+	propV, err := oleutil.CallMethod(item, "Properties_")
+	if err != nil {
+		return nil, false
+	}
+	props := propV.ToIDispatch()
+	defer props.Release()
+	itemV, err := oleutil.CallMethod(props, "Item", name)
+	if err != nil {
+		return nil, false
+	}
+	prop := itemV.ToIDispatch()
+	defer prop.Release()
+
+	valueV, err := oleutil.GetProperty(prop, "Value")
+	if err != nil {
+		return nil, false
+	}
+	defer valueV.Clear()
+	switch v := valueV.Value().(type) {
+	case string:
+		if t, err := oleutil.GetProperty(prop, "CIMType"); err == nil && (t.Val == wbemCimtypeSint64 || t.Val == wbemCimtypeUint64) {
+			if n, err := strconv.ParseFloat(v, 64); err == nil {
+				return n, true
+			}
+		}
+		return v, true
+	case bool:
+		return v, true
+	case int8:
+		return float64(v), true
+	case uint8:
+		return float64(v), true
+	case int16:
+		return float64(v), true
+	case uint16:
+		return float64(v), true
+	case int32:
+		return float64(v), true
+	case uint32:
+		return float64(v), true
+	case int64:
+		return float64(v), true
+	case uint64:
+		return float64(v), true
+	case float32:
+		return float64(v), true
+	case float64:
+		return v, true
+	}
+	return nil, false
+}